@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// outputSuffix replaces ".pdf" in the names of the PDFs a batch writes.
+const outputSuffix = ".overlaid.pdf"
+
+// runConfig holds the settings that apply to every PDF of a run.
+type runConfig struct {
+	mode          string
+	password      string              // opens encrypted inputs
+	metadata      *overlay.Metadata   // rewrites the metadata of each result; nil if not
+	encryption    *overlay.Encryption // encrypts each result; nil if not
+	signer        *overlay.Signer     // signs each result; nil if not
+	optimize      bool                // optimizes each result
+	linearize     overlay.Linearizer  // linearizes each result; nil if not
+	flatten       bool                // flatten the form after filling it
+	strict        bool
+	maxOutputSize int64
+	debug         bool
+	quiet         bool     // log failures instead of printing a summary
+	track         tracking // reports progress and keeps the -resume journal
+	stampHash     bool
+	stampStyle    overlay.OverlayRectText
+	overlayJSON   []byte          // hashed into the stamp together with each PDF
+	verify        bool            // check each result for text left under the overlays
+	data          *templateSource // fills in overlay text templates; nil if none
+	truth         bool            // work out the ground truth of each result
+	scan          *scanConfig     // degrades each result like a scan; nil if not
+	images        *imageOutput    // renders each result to images; nil for PDF
+	grid          bool            // draw a coordinate grid over each result
+}
+
+// process applies overlays to originalPDF according to c and returns the
+// result. name identifies the PDF in warnings.
+func (c runConfig) process(name string, originalPDF []byte, overlays []overlay.OverlayRectText) ([]byte, error) {
+	var outBuf bytes.Buffer
+	switch c.mode {
+	case "overlay":
+		if c.stampHash {
+			// Copy so the stamps of different PDFs don't share a backing array.
+			overlays = append(overlays[:len(overlays):len(overlays)],
+				overlay.HashStampOverlay(c.stampStyle, originalPDF, c.overlayJSON))
+		}
+		if c.grid {
+			grid, err := overlay.GridOverlays(originalPDF, overlay.GridMinor, overlay.GridMajor)
+			if err != nil {
+				return nil, fmt.Errorf("grid: %w", err)
+			}
+			overlays = append(overlays[:len(overlays):len(overlays)], grid...)
+		}
+		var boundsErr *overlay.BoundsError
+		if err := overlay.CheckBounds(originalPDF, overlays); errors.As(err, &boundsErr) {
+			if c.strict {
+				return nil, fmt.Errorf("overlays out of bounds:\n%w", err)
+			}
+			slog.Warn("Overlays out of bounds", "input", name, "err", err)
+		}
+		if err := overlay.ApplyOverlays(bytes.NewReader(originalPDF), &outBuf, overlays); err != nil {
+			return nil, fmt.Errorf("applying overlays: %w", err)
+		}
+	case "form":
+		if err := overlay.FillForm(bytes.NewReader(originalPDF), &outBuf, overlays); err != nil {
+			return nil, fmt.Errorf("filling form: %w", err)
+		}
+		if c.flatten {
+			filled := outBuf.Bytes()
+			outBuf = bytes.Buffer{}
+			if err := overlay.FlattenForm(bytes.NewReader(filled), &outBuf); err != nil {
+				return nil, fmt.Errorf("flattening form: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q (valid: overlay, form)", c.mode)
+	}
+
+	scanned, err := c.scan.apply(outBuf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	optimized, err := optimize(name, scanned, c.optimize)
+	if err != nil {
+		return nil, err
+	}
+	result, err := overlay.EnforceMaxSize(optimized, c.maxOutputSize, c.debug)
+	if err != nil {
+		return nil, fmt.Errorf("output size check: %w", err)
+	}
+	if result, err = setMetadata(result, c.metadata); err != nil {
+		return nil, err
+	}
+	if result, err = sign(result, c.signer); err != nil {
+		return nil, err
+	}
+	if result, err = encrypt(result, c.encryption); err != nil {
+		return nil, err
+	}
+	if result, err = linearize(result, c.linearize); err != nil {
+		return nil, err
+	}
+	// Signing, encrypting and linearizing make the PDF larger again.
+	if err := overlay.CheckMaxSize(result, c.maxOutputSize); err != nil {
+		return nil, fmt.Errorf("output size check: %w", err)
+	}
+	return result, nil
+}
+
+// batchInputs returns the PDFs named by a -pdf value that is a directory (its
+// .pdf files) or a glob pattern, and whether it is one at all. Outputs of an
+// earlier batch are skipped so a batch can write next to its inputs.
+func batchInputs(pdfPath string) ([]string, bool, error) {
+	var paths []string
+	if info, err := os.Stat(pdfPath); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(pdfPath)
+		if err != nil {
+			return nil, true, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".pdf") {
+				paths = append(paths, filepath.Join(pdfPath, e.Name()))
+			}
+		}
+	} else if strings.ContainsAny(pdfPath, "*?[") {
+		matches, err := filepath.Glob(pdfPath)
+		if err != nil {
+			return nil, true, err
+		}
+		paths = matches
+	} else {
+		return nil, false, nil
+	}
+
+	inputs := paths[:0]
+	for _, path := range paths {
+		if !strings.HasSuffix(strings.ToLower(path), outputSuffix) {
+			inputs = append(inputs, path)
+		}
+	}
+	sort.Strings(inputs)
+	return inputs, true, nil
+}
+
+// batchOutputPath returns where the batch writes the result for input: in
+// outDir, or next to input when outDir is empty, as name.overlaid.pdf.
+func batchOutputPath(input, outDir string) string {
+	name := filepath.Base(input)
+	name = name[:len(name)-len(filepath.Ext(name))] + outputSuffix
+	if outDir == "" {
+		return filepath.Join(filepath.Dir(input), name)
+	}
+	return filepath.Join(outDir, name)
+}
+
+// fileReport is the -verify report for one PDF.
+type fileReport struct {
+	Input    string                 `json:"input"`
+	Output   string                 `json:"output"`
+	Pass     bool                   `json:"pass"`
+	Overlays []overlay.OverlayCheck `json:"overlays"`
+}
+
+// verifyResult checks result, produced from originalPDF, for original text
+// left under the overlays. The error lists the overlays that failed.
+func verifyResult(input, output string, originalPDF, result []byte, overlays []overlay.OverlayRectText) (*fileReport, error) {
+	checks, err := overlay.VerifyOverlays(originalPDF, result, overlays)
+	if err != nil {
+		return nil, fmt.Errorf("verifying: %w", err)
+	}
+	report := &fileReport{Input: input, Output: output, Pass: true, Overlays: checks}
+	var failed []string
+	for _, c := range checks {
+		if !c.Pass {
+			report.Pass = false
+			failed = append(failed, strconv.Itoa(c.Index))
+		}
+	}
+	if !report.Pass {
+		which := "overlay"
+		if len(failed) > 1 {
+			which = "overlays"
+		}
+		return report, fmt.Errorf("verification failed: text remains under %s %s", which, strings.Join(failed, ", "))
+	}
+	return report, nil
+}
+
+// writeReports writes the -verify reports as JSON to path, or to stdout
+// when path is "-".
+func writeReports(path string, reports []*fileReport) error {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// batchResult is the outcome of one PDF of a batch.
+type batchResult struct {
+	input, output string
+	report        *fileReport          // nil unless verifying
+	truth         []overlay.TruthField // nil unless working out ground truth
+	err           error
+	skipped       bool     // finished by an earlier run, see -resume
+	seed          uint64   // seed of the made-up data of the output; 0 if none
+	data          any      // values drawn on the output, for -corpus-manifest; nil if none
+	evil          []string // -evil kinds of inconsistency put in the output; nil if none
+}
+
+// processBatch applies overlays to every input on up to workers goroutines,
+// writing each result to outDir, and returns one result per input in input
+// order. With -fake text templates, input i gets the stub of seed+i. A
+// failing PDF doesn't stop the others.
+func processBatch(c runConfig, inputs []string, outDir string, overlays []overlay.OverlayRectText, workers int) []batchResult {
+	return runJobs(len(inputs), workers, c.track, c.truth, func(i int) batchResult {
+		r := batchResult{input: inputs[i], output: c.images.name(batchOutputPath(inputs[i], outDir))}
+		c.data.describeResult(&r, i)
+		return r
+	}, func(i int, r *batchResult) {
+		// Each PDF is read, overlaid and written with its own buffers;
+		// workers only share the read-only overlays.
+		if filled, err := c.data.fill(overlays, i); err != nil {
+			r.err = err
+		} else {
+			r.report, r.truth, r.err = processFile(c, r.input, r.output, filled)
+		}
+	})
+}
+
+// tracking follows the jobs of a batch: progress reports how far it has
+// got, journal records the outputs it finishes and skips those an earlier
+// run did, and corpus writes the manifest of the outputs. Any may be nil.
+type tracking struct {
+	progress *progress
+	journal  *journal
+	corpus   *corpusWriter
+}
+
+// finish stops following the batch, whose results are results.
+func (t tracking) finish(results []batchResult) error {
+	t.progress.finish()
+	if err := t.journal.close(); err != nil {
+		return fmt.Errorf("-resume journal: %v", err)
+	}
+	if err := t.corpus.write(results); err != nil {
+		return fmt.Errorf("-corpus-manifest: %v", err)
+	}
+	return nil
+}
+
+// runJobs runs jobs 0 to n-1 on up to workers goroutines and returns their
+// results in order. name returns the result of job i with its input and
+// output set, and run does the job, filling in the rest, unless the journal
+// of t shows an earlier run did it (with its ground truth if truth is set).
+// A failing job doesn't stop the others.
+func runJobs(n, workers int, t tracking, truth bool, name func(i int) batchResult, run func(i int, r *batchResult)) []batchResult {
+	results := make([]batchResult, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := name(i)
+				if !t.journal.skip(&r, truth) {
+					run(i, &r)
+					if err := t.journal.record(r); err != nil {
+						r.err = fmt.Errorf("%w: -resume journal: %v", errWrite, err)
+					}
+				}
+				t.progress.add(r)
+				results[i] = r
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// processFile applies overlays to the PDF at input and writes it to output,
+// verifying the result and working out its ground truth if c asks for them.
+func processFile(c runConfig, input, output string, overlays []overlay.OverlayRectText) (*fileReport, []overlay.TruthField, error) {
+	originalPDF, err := readPDF(input, c.password)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := c.process(input, originalPDF, overlays)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.images.write(output, result); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errWrite, err)
+	}
+	var truth []overlay.TruthField
+	if c.truth {
+		if truth, err = overlay.GroundTruth(originalPDF, overlays); err != nil {
+			return nil, nil, fmt.Errorf("ground truth: %w", err)
+		}
+	}
+	if !c.verify {
+		return nil, truth, nil
+	}
+	report, err := verifyResult(input, output, originalPDF, result, overlays)
+	return report, truth, err
+}
+
+// finishBatch stops tracking the batch of c, writes the -verify reports of
+// results to verifyPath if c verifies and their ground truth to truthPath
+// if c works it out, prints their summary to w, or only logs the failures
+// if c is quiet, and exits if any failed: with the status of their failures
+// if they all failed the same way, or else exitFailure.
+func finishBatch(c runConfig, verifyPath, truthPath string, w io.Writer, results []batchResult) {
+	if err := c.track.finish(results); err != nil {
+		fatalf(exitOutput, "Could not write %v\n", err)
+	}
+	if c.verify {
+		var reports []*fileReport
+		for _, r := range results {
+			if r.report != nil {
+				reports = append(reports, r.report)
+			}
+		}
+		if err := writeReports(verifyPath, reports); err != nil {
+			fatalf(exitOutput, "Could not write verification report: %v\n", err)
+		}
+	}
+	if c.truth {
+		var records []truthRecord
+		for _, r := range results {
+			if r.err == nil {
+				records = append(records, truthRecord{Output: r.output, Evil: r.evil, Fields: r.truth})
+			}
+		}
+		if err := writeTruthLines(truthPath, records); err != nil {
+			fatalf(exitOutput, "Could not write ground truth: %v\n", err)
+		}
+	}
+	if c.quiet {
+		for _, r := range results {
+			if r.err != nil {
+				slog.Error("Processing failed", "input", r.input, "err", r.err)
+			}
+		}
+		w = io.Discard
+	}
+	if printSummary(w, results) > 0 {
+		os.Exit(batchStatus(results))
+	}
+}
+
+// batchStatus returns the exit status of the failures of results.
+func batchStatus(results []batchResult) int {
+	status := 0
+	for _, r := range results {
+		if r.err == nil {
+			continue
+		}
+		if s := exitStatus(r.err); status == 0 {
+			status = s
+		} else if s != status {
+			return exitFailure
+		}
+	}
+	return status
+}
+
+// printSummary prints one line per batch result and a total to w, and
+// returns the number of failures. Results skipped as done by an earlier
+// run count as processed.
+func printSummary(w io.Writer, results []batchResult) int {
+	failed, skipped := 0, 0
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", r.input, r.err)
+		case r.skipped:
+			skipped++
+			fmt.Fprintf(w, "SKIP %s -> %s\n", r.input, r.output)
+		default:
+			fmt.Fprintf(w, "OK   %s -> %s\n", r.input, r.output)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d PDFs processed, %d failed", len(results)-failed, len(results), failed)
+	if skipped > 0 {
+		fmt.Fprintf(w, ", %d already done", skipped)
+	}
+	fmt.Fprintln(w)
+	return failed
+}
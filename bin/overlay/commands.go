@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// command is a subcommand of the binary.
+type command struct {
+	name    string
+	summary string
+	main    func([]string) // runs the command with its arguments
+}
+
+// commands are the subcommands of the binary.
+var commands = []command{
+	{"overlay", overlaySummary, runOverlay},
+	{"generate", generateSummary, runGenerate},
+	{"batch", batchSummary, runBatch},
+	{"inspect", inspectSummary, runInspect},
+	{"validate", validateSummary, runValidate},
+	{"serve", serveSummary, runServe},
+}
+
+// Summaries of the commands, for their help.
+const (
+	overlaySummary  = "Apply overlays to a PDF, or to a batch of PDFs (the default command)"
+	generateSummary = "Build paystubs from scratch from JSON data, made-up data or a roster"
+	batchSummary    = "Apply overlays to every PDF in a directory or matching a glob"
+	inspectSummary  = "List the text of a PDF with the box of each run, to find what to cover"
+	validateSummary = "Check that the overlays' text reads back from an output PDF where they draw it, and that no forbidden text is left"
+	serveSummary    = "Serve overlays and generated paystubs over HTTP"
+)
+
+// lookupCommand returns the command called name.
+func lookupCommand(name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+	return commands[i], true
+}
+
+// printCommands prints the list of subcommands and the exit statuses to w.
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Usage: overlay-rect-text [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit status:")
+	for _, e := range exitStatuses {
+		fmt.Fprintf(w, "  %d  %s\n", e.status, e.means)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run overlay-rect-text <command> -h for the flags of a command.")
+}
+
+// commandFlags is the flag set of a command that makes documents, with
+// the flags every such command takes: those of logging, -config and
+// -profile. The command defines the groups of flags it takes on it.
+type commandFlags struct {
+	*flag.FlagSet
+	logs    logFlags
+	config  *string
+	profile *string
+}
+
+// newCommandFlags returns the flag set of the command called name, or of
+// flags given without a command if name is "", whose help starts with
+// summary.
+func newCommandFlags(name, summary string) commandFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		usage := "overlay-rect-text [flags]"
+		if name != "" {
+			usage = "overlay-rect-text " + name + " [flags]"
+		}
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\n%s.\n\nFlags:\n", usage, summary)
+		fs.PrintDefaults()
+	}
+	return commandFlags{
+		FlagSet: fs,
+		logs:    addLogFlags(fs),
+		config:  fs.String("config", defaultConfigPath, "Path of the YAML config file of default flag values and -profile profiles; flags given on the command line override it"),
+		profile: fs.String("profile", "", "Also apply this profile of the -config file, and those it extends"),
+	}
+}
+
+// parse parses the command-line arguments args, then sets the flags they
+// don't give from the -config file and -profile, and sets up logging,
+// logging debug messages too if *debug is set once it is. It exits with
+// status 2 on bad flags, or if there are arguments left.
+func (f commandFlags) parse(args []string, debug *bool) {
+	f.Parse(args)
+	conf, err := loadConfig(*f.config, f.isSet("config"))
+	if err != nil {
+		fatalf(exitInput, "Could not read config file: %v\n", err)
+	}
+	values, err := conf.values(*f.profile)
+	if err != nil {
+		fatalf(exitUsage, "%s: %v\n", *f.config, err)
+	}
+	if err := applyConfig(f, values); err != nil {
+		fatalf(exitUsage, "%s: %v\n", *f.config, err)
+	}
+	f.logs.setup(*debug)
+	if f.NArg() > 0 {
+		fatalf(exitUsage, "Unexpected arguments %q\n", f.Args())
+	}
+}
+
+// isSet reports whether the flag called name was given.
+func (f commandFlags) isSet(name string) bool {
+	set := false
+	f.Visit(func(fl *flag.Flag) { set = set || fl.Name == name })
+	return set
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultConfigPath is the project config file commands read when -config
+// doesn't name one, if it exists.
+const defaultConfigPath = "paystubgen.yaml"
+
+// config is a project config file: flag values for every run, and named
+// profiles of more, so that a team can share its invocations instead of
+// copying them between scripts. Flags given on the command line override
+// both. For example:
+//
+//	flags:
+//	  units: mm
+//	  fontfile: fonts/Inter.ttf
+//	profiles:
+//	  adp:
+//	    template: adp
+//	    out: corpus/adp/stub_{index}.pdf
+//	  adp-uk:
+//	    extends: adp
+//	    locale: en-GB
+type config struct {
+	Flags    map[string]any            `yaml:"flags"`
+	Profiles map[string]map[string]any `yaml:"profiles"`
+}
+
+// extendsKey names the profile a profile inherits the flag values of.
+const extendsKey = "extends"
+
+// loadConfig reads the config file at path. A missing file gives an empty
+// config unless required is set.
+func loadConfig(path string, required bool) (config, error) {
+	var c config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// values returns the flag values of profile, or of no profile if it is "":
+// those of c.Flags, overridden by those of each profile it extends from the
+// furthest, and then by its own. A flag given a list gets each of its items
+// in turn.
+func (c config) values(profile string) (map[string][]string, error) {
+	var chain []map[string]any
+	var extender string // the profile that extends name
+	for name := profile; name != ""; {
+		p, ok := c.Profiles[name]
+		if !ok {
+			if name == profile {
+				return nil, fmt.Errorf("unknown profile %q (valid: %s)", name, strings.Join(c.profileNames(), ", "))
+			}
+			return nil, fmt.Errorf("profile %q extends unknown profile %q", extender, name)
+		}
+		if len(chain) > len(c.Profiles) {
+			return nil, fmt.Errorf("profile %q extends itself, through the profiles it extends", profile)
+		}
+		chain = append(chain, p)
+		next, ok := p[extendsKey].(string)
+		if _, set := p[extendsKey]; set && !ok {
+			return nil, fmt.Errorf("profile %q: %s must be a profile name", name, extendsKey)
+		}
+		extender, name = name, next
+	}
+	chain = append(chain, c.Flags)
+	slices.Reverse(chain)
+
+	values := map[string][]string{}
+	for _, m := range chain {
+		for name, v := range m {
+			if name == extendsKey {
+				continue
+			}
+			if list, ok := v.([]any); ok {
+				values[name] = nil
+				for _, item := range list {
+					values[name] = append(values[name], fmt.Sprint(item))
+				}
+				continue
+			}
+			values[name] = []string{fmt.Sprint(v)}
+		}
+	}
+	return values, nil
+}
+
+// profileNames returns the names of the profiles of c, sorted.
+func (c config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyConfig sets each flag of f not given on the command line to its
+// value in values. Flags of other commands that f's doesn't take are left
+// out, so one config serves every command.
+func applyConfig(f commandFlags, values map[string][]string) error {
+	given := map[string]bool{}
+	f.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+	known := configFlags()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		switch {
+		case name == "config" || name == "profile":
+			return fmt.Errorf("-%s cannot be set in the config file", name)
+		case given[name]:
+			continue
+		case f.Lookup(name) == nil:
+			if known[name] {
+				continue
+			}
+			return fmt.Errorf("unknown flag %q", name)
+		}
+		for _, v := range values[name] {
+			if err := f.Set(name, v); err != nil {
+				return fmt.Errorf("flag %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corpusManifest is the -corpus-manifest of a batch: what made each of its
+// outputs, so that the corpus can be audited and any of its documents made
+// again.
+type corpusManifest struct {
+	// Args are the command-line arguments of the run, and Parameters the
+	// value of every flag, defaults included.
+	Args       []string          `json:"args"`
+	Parameters map[string]string `json:"parameters"`
+	Documents  []corpusDocument  `json:"documents"`
+}
+
+// corpusDocument is the manifest entry of one output.
+type corpusDocument struct {
+	Output string `json:"output"`
+	Input  string `json:"input"` // what it was made from, as the summary names it
+	// Seed makes its data with -fake -seed, if it is made up.
+	Seed  uint64            `json:"seed,omitempty"`
+	Files map[string]string `json:"files"`          // SHA-256 of each file written, by path
+	Data  any               `json:"data,omitempty"` // values drawn on it, such as the paystub's
+	// Evil are the -evil kinds of inconsistency put in it on purpose, for
+	// negative testing.
+	Evil []string `json:"evil,omitempty"`
+}
+
+// corpusWriter writes the -corpus-manifest of a batch to path, as CSV if it
+// ends in .csv and else as JSON. A nil corpusWriter writes nothing.
+type corpusWriter struct {
+	path       string
+	args       []string
+	parameters map[string]string
+	images     *imageOutput // how the outputs are written, to find their files
+}
+
+// write writes the manifest of the outputs of results that were written.
+func (c *corpusWriter) write(results []batchResult) error {
+	if c == nil {
+		return nil
+	}
+	m := corpusManifest{Args: c.args, Parameters: c.parameters, Documents: []corpusDocument{}}
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		d := corpusDocument{Output: r.output, Input: r.input, Seed: r.seed, Files: map[string]string{}, Data: r.data, Evil: r.evil}
+		for _, path := range c.images.files(r.output) {
+			sum, err := fileSum(path)
+			if err != nil {
+				return err
+			}
+			d.Files[path] = sum
+		}
+		m.Documents = append(m.Documents, d)
+	}
+	if strings.EqualFold(filepath.Ext(c.path), ".csv") {
+		data, err := m.csv()
+		if err != nil {
+			return err
+		}
+		return writeOutput(c.path, data)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(c.path, append(data, '\n'))
+}
+
+// csv returns m as CSV with a row per document: its output, input, seed,
+// files as space-separated path=SHA-256 pairs, data as JSON, -evil kinds
+// separated by commas, and the command-line arguments of the run, which
+// the row is made again with.
+func (m corpusManifest) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"output", "input", "seed", "files", "data", "evil", "args"})
+	args := strings.Join(m.Args, " ")
+	for _, d := range m.Documents {
+		var files []string
+		for path, sum := range d.Files {
+			files = append(files, path+"="+sum)
+		}
+		slices.Sort(files)
+		seed := ""
+		if d.Seed != 0 {
+			seed = strconv.FormatUint(d.Seed, 10)
+		}
+		data := ""
+		if d.Data != nil {
+			b, err := json.Marshal(d.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", d.Output, err)
+			}
+			data = string(b)
+		}
+		w.Write([]string{d.Output, d.Input, seed, strings.Join(files, " "), data, strings.Join(d.Evil, ","), args})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
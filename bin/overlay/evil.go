@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// The kinds of -evil inconsistency, put in generated paystubs on purpose
+// for negative testing.
+const (
+	// evilTotals prints a net pay that isn't the earnings less the
+	// deductions.
+	evilTotals = "totals"
+	// evilAfterSigning changes the net pay of a signed paystub in an
+	// update appended after the signature.
+	evilAfterSigning = "after-signing"
+)
+
+// evilKinds lists the -evil kinds in the order they are applied.
+var evilKinds = []string{evilTotals, evilAfterSigning}
+
+// netField is the overlay and ground truth field of a paystub's net pay.
+const netField = "totals.net.current"
+
+// How much more than it is each -evil kind makes the net pay.
+const (
+	totalsMisstatement  paystub.Money = 100_00
+	signingMisstatement paystub.Money = 1_000_00
+)
+
+// errNoNetPay is the error of -evil on a layout without a net pay total.
+var errNoNetPay = errors.New("the layout shows no net pay total")
+
+// parseEvil returns the kinds of s, a comma-separated -evil list, in the
+// order of evilKinds, or nil if s is empty.
+func parseEvil(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var kinds []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(evilKinds, name) {
+			return nil, fmt.Errorf("unknown kind %q (valid: %s)", name, strings.Join(evilKinds, ", "))
+		}
+		kinds = append(kinds, name)
+	}
+	var out []string
+	for _, kind := range evilKinds {
+		if slices.Contains(kinds, kind) {
+			out = append(out, kind)
+		}
+	}
+	return out, nil
+}
+
+// misstate sets the text of the net pay overlay of overlays to net.
+func misstate(overlays []overlay.OverlayRectText, net string) error {
+	for i := range overlays {
+		if overlays[i].Field == netField {
+			overlays[i].Text = net
+			return nil
+		}
+	}
+	return fmt.Errorf("-evil %s: %w", evilTotals, errNoNetPay)
+}
+
+// amendNet returns signed, a signed paystub whose ground truth is truth,
+// with its net pay changed to net in an update appended to it, and sets
+// the text of the net pay field of truth to match.
+func amendNet(signed []byte, truth []overlay.TruthField, net string) ([]byte, error) {
+	i := slices.IndexFunc(truth, func(f overlay.TruthField) bool { return f.Field == netField })
+	if i < 0 {
+		return nil, fmt.Errorf("-evil %s: %w", evilAfterSigning, errNoNetPay)
+	}
+	f := truth[i]
+	f.Text = net
+	pdf, err := overlay.Amend(signed, f)
+	if err != nil {
+		return nil, fmt.Errorf("-evil %s: %w", evilAfterSigning, err)
+	}
+	truth[i].Text = net
+	return pdf, nil
+}
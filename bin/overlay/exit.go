@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// Exit statuses of the binary by what went wrong, so that scripts can tell
+// failures apart. printCommands lists them.
+const (
+	exitFailure  = 1 // anything else, such as a check that did not pass
+	exitUsage    = 2 // invalid flags or arguments
+	exitInput    = 3 // overlays, paystub data or another input that cannot be read or is invalid
+	exitPDF      = 4 // an input PDF that cannot be read
+	exitOutput   = 5 // an output that cannot be written
+	exitTooLarge = 6 // an output over -max-output-size even after optimizing
+)
+
+// exitStatuses describes the exit statuses for help.
+var exitStatuses = []struct {
+	status int
+	means  string
+}{
+	{0, "success"},
+	{exitFailure, "any other failure, such as a check that did not pass"},
+	{exitUsage, "invalid flags or arguments"},
+	{exitInput, "overlays, paystub data, a layout or another input cannot be read or is invalid"},
+	{exitPDF, "an input PDF cannot be read"},
+	{exitOutput, "an output cannot be written"},
+	{exitTooLarge, "an output is over -max-output-size even after optimizing"},
+}
+
+// errWrite is wrapped by the errors of writing an output.
+var errWrite = errors.New("writing output")
+
+// exitStatus returns the exit status of a failure with err.
+func exitStatus(err error) int {
+	switch {
+	case errors.Is(err, errWrite):
+		return exitOutput
+	case errors.Is(err, overlay.ErrInvalidPDF):
+		return exitPDF
+	case errors.Is(err, overlay.ErrInvalidOverlay), errors.Is(err, paystub.ErrInvalidLayout):
+		return exitInput
+	case errors.Is(err, overlay.ErrTooLarge):
+		return exitTooLarge
+	}
+	return exitFailure
+}
+
+// fatalf logs the message of format and args as an error and exits with
+// status.
+func fatalf(status int, format string, args ...any) {
+	slog.Error(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+	os.Exit(status)
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// runGenerate runs the generate subcommand with its arguments args: it
+// builds paystubs from scratch, and with -series the documents that go
+// with them.
+func runGenerate(args []string) {
+	flags := newCommandFlags("generate", generateSummary)
+	var out outputOptions
+	var data dataOptions
+	var gen generateOptions
+	out.define(flags.FlagSet)
+	data.define(flags.FlagSet)
+	gen.define(flags.FlagSet)
+	// -data reads the paystub data here, as -generate does for overlay.
+	flags.StringVar(&gen.dataPath, "data", "", "Path to the JSON paystub data (- for stdin)")
+	flags.parse(args, &out.debug)
+	if gen.dataPath == "" && !data.fake && data.rosterPath == "" {
+		fmt.Fprintln(os.Stderr, "generate needs -data, -fake or -roster")
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	generateDocuments(flags, &out, &data, &gen, "")
+}
+
+// generateDocuments builds the paystubs and other documents that the flags
+// of flags, out, d and g ask for, writing their amounts and dates in locale,
+// or in their layout's if it is "". It exits with status 2 on bad flags.
+func generateDocuments(flags commandFlags, out *outputOptions, d *dataOptions, g *generateOptions, locale string) {
+	if d.rosterPath != "" && g.dataPath != "" {
+		fatalf(exitUsage, "-roster cannot be combined with -generate or -data\n")
+	}
+	if out.truthPath == "-" && out.outPath == "-" || g.mergePath == "-" && (out.outPath == "-" || out.truthPath == "-") {
+		fatalf(exitUsage, "Only one of -out, -truth and -merge can be written to stdout\n")
+	}
+	if g.layoutTemplate != "" {
+		if _, err := paystub.LayoutNamed(g.layoutTemplate); err != nil {
+			fatalf(exitUsage, "Invalid -template: %v\n", err)
+		}
+	}
+	output := out.check(flags)
+	// Messages go to stderr when stdout carries a report.
+	msgOut := output.messages(out.truthPath == "-" || g.mergePath == "-")
+	evil, err := parseEvil(g.evilSpec)
+	if err != nil {
+		fatalf(exitUsage, "Invalid -evil: %v\n", err)
+	}
+	if slices.Contains(evil, evilAfterSigning) && output.signer == nil {
+		fatalf(exitUsage, "-evil %s needs -sign-cert to sign the paystubs first\n", evilAfterSigning)
+	}
+	if g.taxForm != "" && d.series == 0 {
+		fatalf(exitUsage, "-tax-form needs -series\n")
+	}
+	if (g.bankStatements || g.depositAdvice) && d.series == 0 {
+		fatalf(exitUsage, "-bank-statements and -deposit-advice need -series\n")
+	}
+	data := d.load(out.deterministic)
+
+	var form paystub.TaxForm
+	if g.taxForm != "" {
+		if form, err = paystub.ParseTaxForm(g.taxForm); err != nil {
+			fatalf(exitUsage, "Invalid -tax-form: %v\n", err)
+		}
+	}
+	formPattern := g.taxFormOut
+	if formPattern == "" {
+		formPattern = output.images.name(string(form) + "_" + yearPlaceholder + ".pdf")
+	}
+	if form != "" && len(paystub.TaxYears(data.stubs)) > 1 && !strings.Contains(formPattern, yearPlaceholder) {
+		fatalf(exitUsage, "-tax-form-out %q needs %s to name the form of each year\n", formPattern, yearPlaceholder)
+	}
+	statements, advices := data.statements, data.advices
+	if !g.bankStatements {
+		statements = nil
+	}
+	if !g.depositAdvice {
+		advices = nil
+	}
+	if g.statementOut == "" {
+		g.statementOut = output.images.name("statement_" + monthPlaceholder + ".pdf")
+	}
+	if len(statements) > 1 && !strings.Contains(g.statementOut, monthPlaceholder) {
+		fatalf(exitUsage, "-bank-statement-out %q needs %s to name the statement of each month\n", g.statementOut, monthPlaceholder)
+	}
+	if g.adviceOut == "" {
+		g.adviceOut = output.images.name("advice_" + indexPlaceholder + ".pdf")
+	}
+	if len(advices) > 1 && !strings.Contains(g.adviceOut, indexPlaceholder) {
+		fatalf(exitUsage, "-deposit-advice-out %q needs %s to name each advice\n", g.adviceOut, indexPlaceholder)
+	}
+	countPattern := output.pattern(data.copies)
+
+	gen := generateConfig{dataPath: g.dataPath, layoutPath: g.layoutPath, template: g.layoutTemplate, locale: locale, fake: d.fake, seed: d.seed, rates: data.rates, stubs: data.stubs, truth: out.truthPath != "", scan: output.scan, metadata: output.metadata, encryption: output.encryption, signer: output.signer, optimize: out.optimize, maxSize: out.maxOutputSize, debug: out.debug, linearize: output.linearizeWith, images: output.images, evil: evil}
+	if g.appendTo != "" {
+		if gen.appendTo, err = readInput(g.appendTo); err != nil {
+			fatalf(exitInput, "Could not read -append-to PDF: %v\n", err)
+		}
+	}
+	if g.mergePath != "" && (output.images != nil || output.encryption != nil || output.signer != nil) {
+		fatalf(exitUsage, "-merge cannot be combined with -out-format images, -opw or -sign-cert\n")
+	}
+	if data.copies > 1 || form != "" || statements != nil || advices != nil {
+		if !d.fake && data.stubs == nil {
+			fatalf(exitUsage, "-count needs -fake: a -generate data file gives the same paystub every time\n")
+		}
+		total := data.copies + len(statements) + len(advices)
+		if form != "" {
+			total += len(paystub.TaxYears(data.stubs))
+		}
+		gen.track = output.track(total)
+		results := generateFiles(gen, countPattern, data.copies, out.workers)
+		if form != "" {
+			results = append(results, generateTaxForms(gen, form, formPattern)...)
+		}
+		results = append(results, generateStatements(gen, statements, g.statementOut)...)
+		results = append(results, generateAdvices(gen, advices, g.adviceOut)...)
+		if g.mergePath != "" {
+			if err := mergeOutputs(g.mergePath, results); err != nil {
+				fatalf(exitStatus(err), "Could not merge the documents: %v\n", err)
+			}
+		}
+		finishBatch(runConfig{truth: gen.truth, quiet: *flags.logs.quiet, track: gen.track}, "", out.truthPath, msgOut, results)
+		return
+	}
+	if g.mergePath != "" {
+		fatalf(exitUsage, "-merge needs a batch of -count, -series or -roster\n")
+	}
+	pdf, truth, err := gen.generate(out.outPath)
+	if err != nil {
+		fatalf(exitStatus(err), "Generating paystub failed: %v\n", err)
+	}
+	if err := output.images.write(out.outPath, pdf); err != nil {
+		fatalf(exitOutput, "Could not write output: %v\n", err)
+	}
+	if out.outPath != "-" {
+		fmt.Fprintf(msgOut, "Done! Paystub generated. Result saved to %q\n", out.outPath)
+	}
+	if gen.truth {
+		if err := writeTruth(out.truthPath, truthRecord{Output: out.outPath, Evil: evil, Fields: truth}); err != nil {
+			fatalf(exitOutput, "Could not write ground truth: %v\n", err)
+		}
+	}
+}
+
+// generateConfig says where the paystubs of -generate come from.
+type generateConfig struct {
+	dataPath   string              // JSON paystub data, - for stdin
+	layoutPath string              // JSON layout, over template's
+	template   string              // built-in layout; empty means paystub.DefaultLayout
+	locale     string              // overrides the layout's locale if set
+	fake       bool                // make the data up instead of reading dataPath
+	seed       uint64              // seeds the synthetic data
+	rates      paystub.Rates       // withholds the synthetic data
+	truth      bool                // work out the ground truth of each paystub
+	scan       *scanConfig         // degrades each paystub like a scan; nil if not
+	metadata   *overlay.Metadata   // rewrites the metadata of each paystub; nil if not
+	encryption *overlay.Encryption // encrypts each paystub; nil if not
+	signer     *overlay.Signer     // signs each paystub; nil if not
+	optimize   bool                // optimizes each paystub
+	maxSize    int64               // largest paystub in bytes; 0 for no limit
+	debug      bool                // logs the largest assets of a paystub over maxSize
+	linearize  overlay.Linearizer  // linearizes each paystub; nil if not
+	images     *imageOutput        // renders each paystub to images; nil for PDF
+	track      tracking            // reports progress and keeps the -resume journal
+	// evil are the -evil kinds of inconsistency put in each paystub, in
+	// the order of evilKinds.
+	evil []string
+	// appendTo is the PDF of -append-to, whose pages each document's are
+	// appended to; nil if none.
+	appendTo []byte
+	// stubs, from -roster or -series, replace dataPath and fake: paystub i
+	// of generateFiles is stubs[i].
+	stubs []paystub.Paystub
+}
+
+// layout returns the layout to draw paystubs with.
+func (g generateConfig) layout() (paystub.Layout, error) {
+	base := paystub.DefaultLayout
+	if g.template != "" {
+		var err error
+		if base, err = paystub.LayoutNamed(g.template); err != nil {
+			return paystub.Layout{}, err
+		}
+	}
+	l := base
+	if g.layoutPath != "" {
+		f, err := os.Open(g.layoutPath)
+		if err != nil {
+			return paystub.Layout{}, err
+		}
+		defer f.Close()
+		if l, err = paystub.DecodeLayoutFrom(f, base); err != nil {
+			return paystub.Layout{}, err
+		}
+	}
+	if g.locale != "" {
+		l.Locale = g.locale
+	}
+	return l, nil
+}
+
+// paystub returns the data of the paystub to generate.
+func (g generateConfig) paystub() (paystub.Paystub, error) {
+	if len(g.stubs) > 0 {
+		return g.stubs[0], nil
+	}
+	if g.fake {
+		return newFaker(g.seed, g.rates).Paystub(), nil
+	}
+	data, err := readInput(g.dataPath)
+	if err != nil {
+		return paystub.Paystub{}, err
+	}
+	return paystub.DecodePaystub(bytes.NewReader(data))
+}
+
+// generate builds the paystub PDF and, if g asks for it, its ground truth.
+// output names it in the log.
+func (g generateConfig) generate(output string) ([]byte, []overlay.TruthField, error) {
+	layout, err := g.layout()
+	if err != nil {
+		return nil, nil, err
+	}
+	stub, err := g.paystub()
+	if err != nil {
+		return nil, nil, err
+	}
+	overlays, err := paystub.Overlays(stub, layout)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := paystub.LocaleNamed(layout.Locale)
+	if err != nil {
+		return nil, nil, err
+	}
+	if slices.Contains(g.evil, evilTotals) {
+		if err := misstate(overlays, loc.Amount(stub.Net()+totalsMisstatement)); err != nil {
+			return nil, nil, err
+		}
+	}
+	pdf, err := paystub.Draw(overlays, layout)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The net pay changed after signing is found by its ground truth.
+	afterSigning := slices.Contains(g.evil, evilAfterSigning)
+	var truth []overlay.TruthField
+	if g.truth || afterSigning {
+		if truth, err = overlay.GroundTruth(pdf, overlays); err != nil {
+			return nil, nil, fmt.Errorf("ground truth: %w", err)
+		}
+	}
+	if pdf, err = g.compose(pdf, truth); err != nil {
+		return nil, nil, err
+	}
+	if pdf, err = g.finish(output, pdf); err != nil {
+		return nil, nil, err
+	}
+	if afterSigning {
+		if pdf, err = amendNet(pdf, truth, loc.Amount(stub.Net()+signingMisstatement)); err != nil {
+			return nil, nil, err
+		}
+		if err := g.checkSize(pdf); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !g.truth {
+		truth = nil
+	}
+	return pdf, truth, nil
+}
+
+// compose appends the pages of pdf, a generated document, to those of
+// g.appendTo if it is set, moving the pages of truth, its ground truth,
+// after them.
+func (g generateConfig) compose(pdf []byte, truth []overlay.TruthField) ([]byte, error) {
+	if g.appendTo == nil {
+		return pdf, nil
+	}
+	n, err := overlay.PageCount(g.appendTo)
+	if err != nil {
+		return nil, fmt.Errorf("-append-to: %w", err)
+	}
+	for i := range truth {
+		truth[i].Page += n
+	}
+	return overlay.Merge([][]byte{g.appendTo, pdf})
+}
+
+// finish degrades, optimizes, rewrites the metadata of, signs, encrypts and
+// linearizes a generated PDF as g asks, and checks its size. output names
+// it in the log.
+func (g generateConfig) finish(output string, pdf []byte) ([]byte, error) {
+	pdf, err := g.scan.apply(pdf)
+	if err != nil {
+		return nil, err
+	}
+	if pdf, err = optimize(output, pdf, g.optimize); err != nil {
+		return nil, err
+	}
+	if pdf, err = overlay.EnforceMaxSize(pdf, g.maxSize, g.debug); err != nil {
+		return nil, fmt.Errorf("output size check: %w", err)
+	}
+	if pdf, err = setMetadata(pdf, g.metadata); err != nil {
+		return nil, err
+	}
+	if pdf, err = sign(pdf, g.signer); err != nil {
+		return nil, err
+	}
+	if pdf, err = encrypt(pdf, g.encryption); err != nil {
+		return nil, err
+	}
+	if pdf, err = linearize(pdf, g.linearize); err != nil {
+		return nil, err
+	}
+	return pdf, g.checkSize(pdf)
+}
+
+// checkSize returns an error if pdf, a finished document, is over g.maxSize.
+func (g generateConfig) checkSize(pdf []byte) error {
+	if err := overlay.CheckMaxSize(pdf, g.maxSize); err != nil {
+		return fmt.Errorf("output size check: %w", err)
+	}
+	return nil
+}
+
+// indexPlaceholder is replaced by each paystub's number in the -out pattern
+// of -count.
+const indexPlaceholder = "{index}"
+
+// generateFiles generates count paystubs, numbered from 1, on up to workers
+// goroutines, writing each to pattern with indexPlaceholder replaced by its
+// number, and returns one result per paystub in order. Paystub i is made
+// from seed g.seed+i-1, so the first one matches a single run with g.seed,
+// or is g.stubs[i]. A failing paystub doesn't stop the others.
+func generateFiles(g generateConfig, pattern string, count, workers int) []batchResult {
+	return runJobs(count, workers, g.track, g.truth, func(i int) batchResult {
+		r := batchResult{output: strings.ReplaceAll(pattern, indexPlaceholder, strconv.Itoa(i+1)), evil: g.evil}
+		if len(g.stubs) > 0 {
+			r.input = fmt.Sprintf("paystub %d (%s)", i+1, stubSource(g.stubs[i]))
+			r.data = g.stubs[i]
+		} else {
+			r.seed = g.seed + uint64(i)
+			r.input = fmt.Sprintf("paystub %d (seed %d)", i+1, r.seed)
+			r.data = newFaker(r.seed, g.rates).Paystub()
+		}
+		return r
+	}, func(i int, r *batchResult) {
+		gi := g
+		gi.seed = g.seed + uint64(i)
+		if len(g.stubs) > 0 {
+			gi.stubs = g.stubs[i : i+1]
+		}
+		r.truth, r.err = generateFile(gi, r.output)
+	})
+}
+
+// generateFile generates the paystub of g and writes it to output. It
+// returns the paystub's ground truth if g asks for it.
+func generateFile(g generateConfig, output string) ([]overlay.TruthField, error) {
+	pdf, truth, err := g.generate(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.images.write(output, pdf); err != nil {
+		return nil, fmt.Errorf("%w: %v", errWrite, err)
+	}
+	return truth, nil
+}
+
+// yearPlaceholder is replaced by each form's year in the -tax-form-out
+// pattern.
+const yearPlaceholder = "{year}"
+
+// generateTaxForms generates form for each calendar year of g.stubs,
+// writing each to pattern with yearPlaceholder replaced by its year, and
+// returns one result per form in order.
+func generateTaxForms(g generateConfig, form paystub.TaxForm, pattern string) []batchResult {
+	years := paystub.TaxYears(g.stubs)
+	return runJobs(len(years), 1, g.track, g.truth, func(i int) batchResult {
+		// An error making the values is the job's too.
+		values, _ := form.Values(g.stubs, years[i], g.rates)
+		return batchResult{
+			input:  fmt.Sprintf("%s %d (%s)", form, years[i], g.stubs[0].Employee.Name),
+			output: strings.ReplaceAll(pattern, yearPlaceholder, strconv.Itoa(years[i])),
+			data:   values,
+		}
+	}, func(i int, r *batchResult) {
+		r.truth, r.err = generateTaxForm(g, form, years[i], r.output)
+	})
+}
+
+// generateTaxForm generates form for year of g.stubs and writes it to
+// output. It returns the form's ground truth if g asks for it.
+func generateTaxForm(g generateConfig, form paystub.TaxForm, year int, output string) ([]overlay.TruthField, error) {
+	pdf, err := form.Generate(g.stubs, year, g.rates)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(g, pdf, output, func() ([]overlay.OverlayRectText, error) {
+		return form.Overlays(g.stubs, year, g.rates)
+	})
+}
+
+// monthPlaceholder is replaced by each statement's month in the
+// -bank-statement-out pattern.
+const monthPlaceholder = "{month}"
+
+// generateStatements generates each of statements in the layout of g,
+// writing each to pattern with monthPlaceholder replaced by its month, and
+// returns one result per statement in order.
+func generateStatements(g generateConfig, statements []paystub.BankStatement, pattern string) []batchResult {
+	return runJobs(len(statements), 1, g.track, g.truth, func(i int) batchResult {
+		b := statements[i]
+		month := b.PeriodStart.Format("2006-01")
+		return batchResult{
+			input:  fmt.Sprintf("bank statement %s (%s)", month, b.Holder.Name),
+			output: strings.ReplaceAll(pattern, monthPlaceholder, month),
+			data:   b,
+		}
+	}, func(i int, r *batchResult) {
+		r.truth, r.err = generateStatement(g, statements[i], r.output)
+	})
+}
+
+// generateStatement generates b and writes it to output. It returns the
+// statement's ground truth if g asks for it.
+func generateStatement(g generateConfig, b paystub.BankStatement, output string) ([]overlay.TruthField, error) {
+	layout, err := g.layout()
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := b.Generate(layout)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(g, pdf, output, func() ([]overlay.OverlayRectText, error) {
+		return b.Overlays(layout)
+	})
+}
+
+// generateAdvices generates each of advices in the layout of g, writing
+// advice i to pattern with indexPlaceholder replaced by i+1, the number of
+// its paystub, and returns one result per advice in order.
+func generateAdvices(g generateConfig, advices []paystub.DepositAdvice, pattern string) []batchResult {
+	return runJobs(len(advices), 1, g.track, g.truth, func(i int) batchResult {
+		a := advices[i]
+		return batchResult{
+			input:  fmt.Sprintf("deposit advice %d (%s, paid %s)", i+1, a.Employee.Name, a.PayDate),
+			output: strings.ReplaceAll(pattern, indexPlaceholder, strconv.Itoa(i+1)),
+			data:   a,
+		}
+	}, func(i int, r *batchResult) {
+		r.truth, r.err = generateAdvice(g, advices[i], r.output)
+	})
+}
+
+// generateAdvice generates a and writes it to output. It returns the
+// advice's ground truth if g asks for it.
+func generateAdvice(g generateConfig, a paystub.DepositAdvice, output string) ([]overlay.TruthField, error) {
+	layout, err := g.layout()
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := a.Generate(layout)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocument(g, pdf, output, func() ([]overlay.OverlayRectText, error) {
+		overlays, _, err := a.Overlays(layout)
+		return overlays, err
+	})
+}
+
+// writeDocument finishes pdf, a document generated alongside the paystubs,
+// as g asks and writes it to output. If g asks for the ground truth, it
+// works it out from the overlays pdf was drawn with and returns it.
+func writeDocument(g generateConfig, pdf []byte, output string, overlays func() ([]overlay.OverlayRectText, error)) ([]overlay.TruthField, error) {
+	var truth []overlay.TruthField
+	if g.truth {
+		ovs, err := overlays()
+		if err != nil {
+			return nil, err
+		}
+		if truth, err = overlay.GroundTruth(pdf, ovs); err != nil {
+			return nil, fmt.Errorf("ground truth: %w", err)
+		}
+	}
+	pdf, err := g.compose(pdf, truth)
+	if err != nil {
+		return nil, err
+	}
+	if pdf, err = g.finish(output, pdf); err != nil {
+		return nil, err
+	}
+	if err := g.images.write(output, pdf); err != nil {
+		return nil, fmt.Errorf("%w: %v", errWrite, err)
+	}
+	return truth, nil
+}
+
+// mergeOutputs writes the outputs of results that were written, in order,
+// to path as one PDF, - for stdout.
+func mergeOutputs(path string, results []batchResult) error {
+	var pdfs [][]byte
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		pdf, err := os.ReadFile(r.output)
+		if err != nil {
+			return err
+		}
+		pdfs = append(pdfs, pdf)
+	}
+	if len(pdfs) == 0 {
+		return nil
+	}
+	merged, err := overlay.Merge(pdfs)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(path, merged); err != nil {
+		return fmt.Errorf("%w: %v", errWrite, err)
+	}
+	return nil
+}
+
+// stubSource names s in run summaries by its employee and pay date.
+func stubSource(s paystub.Paystub) string {
+	if s.PayDate.IsZero() {
+		return s.Employee.Name
+	}
+	return fmt.Sprintf("%s, paid %s", s.Employee.Name, s.PayDate)
+}
+
+// readRoster reads the CSV roster at path, - for stdin.
+func readRoster(path string) ([]paystub.Paystub, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	return paystub.DecodeRoster(bytes.NewReader(data))
+}
+
+// newFaker returns a Faker seeded with seed that withholds at rates.
+func newFaker(seed uint64, rates paystub.Rates) *paystub.Faker {
+	f := paystub.NewFaker(seed)
+	f.Rates = rates
+	return f
+}
+
+// readRates reads the tax rates at path, or returns paystub.DefaultRates
+// when path is empty.
+func readRates(path string) (paystub.Rates, error) {
+	if path == "" {
+		return paystub.DefaultRates, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return paystub.Rates{}, err
+	}
+	defer f.Close()
+	return paystub.DecodeRates(f)
+}
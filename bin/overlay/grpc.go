@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/StCredZero/paystub-test-gen/bin/overlay/overlaypb"
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// streamChunkSize is the size of each PDF chunk sent back by ApplyOverlaysStream.
+const streamChunkSize = 1 << 20
+
+// overlayServer implements overlaypb.OverlayServiceServer on top of overlay.ApplyOverlays.
+type overlayServer struct {
+	overlaypb.UnimplementedOverlayServiceServer
+}
+
+// overlaysFromProto converts wire overlays into OverlayRectText values. An
+// overlay with an image_path or font_file is an InvalidArgument error: it
+// names a file on the server, which a caller may not have read into its
+// reply or installed in the user font directory.
+func overlaysFromProto(pbs []*overlaypb.Overlay) ([]overlay.OverlayRectText, error) {
+	overlays := make([]overlay.OverlayRectText, 0, len(pbs))
+	for i, pb := range pbs {
+		if pb.GetImagePath() != "" {
+			return nil, status.Errorf(codes.InvalidArgument, "overlay %d: image_path names a file on the server, which gRPC callers may not read", i)
+		}
+		if pb.GetFontFile() != "" {
+			return nil, status.Errorf(codes.InvalidArgument, "overlay %d: font_file names a file on the server, which gRPC callers may not install; use a built-in font", i)
+		}
+		overlays = append(overlays, overlay.OverlayRectText{
+			Text:        pb.GetText(),
+			X:           pb.GetX(),
+			Y:           pb.GetY(),
+			Width:       pb.GetWidth(),
+			Height:      pb.GetHeight(),
+			Scale:       pb.GetScale(),
+			Anchor:      pb.GetAnchor(),
+			Ops:         pb.GetOps(),
+			FillColor:   pb.GetFillColor(),
+			TextColor:   pb.GetTextColor(),
+			Font:        pb.GetFont(),
+			Pages:       pb.GetPages(),
+			Origin:      pb.GetOrigin(),
+			Wrap:        pb.GetWrap(),
+			AutoFit:     pb.GetAutoFit(),
+			Rotation:    pb.GetRotation(),
+			Units:       pb.GetUnits(),
+			Redact:      pb.GetRedact(),
+			BorderColor: pb.GetBorderColor(),
+			BorderWidth: pb.GetBorderWidth(),
+			TextScale:   pb.GetTextScale(),
+			FontSize:    int(pb.GetFontSize()),
+			Bold:        pb.GetBold(),
+			Italic:      pb.GetItalic(),
+			LineSpacing: pb.GetLineSpacing(),
+			Align:       pb.GetAlign(),
+			VAlign:      pb.GetValign(),
+		})
+		if op := pb.GetOpacity(); op != 0 {
+			// proto3 can't tell an unset opacity from 0, so 0 stays fully
+			// opaque here, as the proto documents.
+			overlays[len(overlays)-1].Opacity = &op
+		}
+	}
+	return overlays, nil
+}
+
+// ApplyOverlays applies the overlays of a single request to its PDF.
+func (s *overlayServer) ApplyOverlays(ctx context.Context, req *overlaypb.ApplyOverlaysRequest) (*overlaypb.ApplyOverlaysResponse, error) {
+	if len(req.GetPdf()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing pdf")
+	}
+	overlays, err := overlaysFromProto(req.GetOverlays())
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := overlay.ApplyOverlays(bytes.NewReader(req.GetPdf()), &out, overlays); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &overlaypb.ApplyOverlaysResponse{Pdf: out.Bytes()}, nil
+}
+
+// ApplyOverlaysStream collects the PDF chunks and overlays of the whole
+// request stream, applies them, and streams the result back in chunks.
+func (s *overlayServer) ApplyOverlaysStream(stream overlaypb.OverlayService_ApplyOverlaysStreamServer) error {
+	var pdf bytes.Buffer
+	var pbs []*overlaypb.Overlay
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		pdf.Write(req.GetPdf())
+		pbs = append(pbs, req.GetOverlays()...)
+	}
+	if pdf.Len() == 0 {
+		return status.Error(codes.InvalidArgument, "missing pdf")
+	}
+
+	overlays, err := overlaysFromProto(pbs)
+	if err != nil {
+		return err
+	}
+	var result bytes.Buffer
+	if err := overlay.ApplyOverlays(&pdf, &result, overlays); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	out := result.Bytes()
+	for len(out) > 0 {
+		n := min(len(out), streamChunkSize)
+		if err := stream.Send(&overlaypb.ApplyOverlaysResponse{Pdf: out[:n]}); err != nil {
+			return err
+		}
+		out = out[n:]
+	}
+	return nil
+}
+
+// serveGRPC listens on addr and serves the overlay gRPC service until it fails.
+func serveGRPC(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer()
+	overlaypb.RegisterOverlayServiceServer(srv, &overlayServer{})
+	slog.Info("Serving overlay gRPC service", "addr", lis.Addr().String())
+	return srv.Serve(lis)
+}
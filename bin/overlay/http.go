@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// multipartMemory is how much of a request's multipart files are held in
+// memory before the rest is spilled to (uniquely named) temp files.
+const multipartMemory = 8 << 20
+
+// overlayHandler serves POST /overlay: a multipart form with the source PDF
+// in its "pdf" part and the overlays JSON in its "overlays" part (a file or
+// a plain field), answered with the overlaid PDF. Overlays may not name
+// files on the server.
+type overlayHandler struct {
+	maxUpload int64 // bytes; larger requests are refused
+}
+
+func (h overlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUpload)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request larger than %d bytes", h.maxUpload), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("invalid multipart form: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	pdf, err := formPart(r.MultipartForm, "pdf")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := formPart(r.MultipartForm, "overlays")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	overlays, err := overlay.DecodeOverlays(bytes.NewReader(data))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkRemoteOverlays(overlays); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := overlay.ApplyOverlays(bytes.NewReader(pdf), &out, overlays); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	if _, err := w.Write(out.Bytes()); err != nil {
+		slog.Error("Writing response failed", "err", err)
+	}
+}
+
+// Limits on the sizes a request sent over the network may ask for, so one
+// request can't have the server lay out and write a huge page or font.
+const (
+	maxRemotePageSize = 14400 // points, either way: 200 inches, the most PDF allows
+	maxRemoteFontSize = 1000  // points
+)
+
+// checkRemoteOverlays returns an error if an overlay sent over the network
+// names a file on the server: an ImagePath would be read into the reply, and
+// a FontFile installed in the user font directory. Images must come inline,
+// as ImageData. Font sizes are limited to maxRemoteFontSize.
+func checkRemoteOverlays(overlays []overlay.OverlayRectText) error {
+	for i, ov := range overlays {
+		if ov.ImagePath != "" {
+			return fmt.Errorf("overlay %d: imagePath names a file on the server; send the image inline as imageData", i)
+		}
+		if ov.FontFile != "" {
+			return fmt.Errorf("overlay %d: fontFile names a file on the server; use a built-in font", i)
+		}
+		if ov.FontSize > maxRemoteFontSize {
+			return fmt.Errorf("overlay %d: fontSize may be at most %d points, got %d", i, maxRemoteFontSize, ov.FontSize)
+		}
+		if ov.MinFontSize > maxRemoteFontSize {
+			return fmt.Errorf("overlay %d: minFontSize may be at most %d points, got %d", i, maxRemoteFontSize, ov.MinFontSize)
+		}
+	}
+	return nil
+}
+
+// checkRemoteLayout returns an error if layout l, sent over the network, has
+// a page larger than maxRemotePageSize either way or a font size over
+// maxRemoteFontSize.
+func checkRemoteLayout(l paystub.Layout) error {
+	if l.PageWidth > maxRemotePageSize || l.PageHeight > maxRemotePageSize {
+		return fmt.Errorf("page of %gx%g points is over the %d points allowed either way", l.PageWidth, l.PageHeight, maxRemotePageSize)
+	}
+	if l.FontSize > maxRemoteFontSize {
+		return fmt.Errorf("fontSize may be at most %d points, got %d", maxRemoteFontSize, l.FontSize)
+	}
+	return nil
+}
+
+// formPart returns the contents of the file or, failing that, the value
+// named name in form.
+func formPart(form *multipart.Form, name string) ([]byte, error) {
+	if files := form.File[name]; len(files) > 0 {
+		f, err := files[0].Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+	if values := form.Value[name]; len(values) > 0 && values[0] != "" {
+		return []byte(values[0]), nil
+	}
+	return nil, fmt.Errorf("missing %s part", name)
+}
+
+// paystubRequest is the JSON body of POST /v1/paystubs. Without Paystub,
+// the data is made up from Seed, or from a random seed when Seed is absent.
+type paystubRequest struct {
+	Paystub  json.RawMessage `json:"paystub"`  // paystub data, as for -generate
+	Seed     *uint64         `json:"seed"`     // seeds made-up data
+	Template string          `json:"template"` // built-in layout Layout starts from
+	Layout   json.RawMessage `json:"layout"`   // default: paystub.DefaultLayout or Template
+	Locale   string          `json:"locale"`   // overrides the layout's locale if set
+	Overlays json.RawMessage `json:"overlays"` // drawn over the paystub, with its data filled into their text
+}
+
+// paystubHandler serves POST /v1/paystubs: a paystubRequest answered with the
+// generated paystub PDF. The seed of made-up data is sent back in the
+// X-Paystub-Seed header so the fixture can be reproduced. As with
+// overlayHandler, its overlays may not name files on the server, and its
+// layout and overlays may not ask for more than maxRemotePageSize and
+// maxRemoteFontSize.
+type paystubHandler struct {
+	maxUpload int64 // bytes; larger requests are refused
+}
+
+func (h paystubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxUpload))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request larger than %d bytes", h.maxUpload), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req paystubRequest
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var stub paystub.Paystub
+	if len(req.Paystub) > 0 {
+		if stub, err = paystub.DecodePaystub(bytes.NewReader(req.Paystub)); err != nil {
+			http.Error(w, fmt.Sprintf("paystub: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		seed := uint64(time.Now().UnixNano())
+		if req.Seed != nil {
+			seed = *req.Seed
+		}
+		stub = paystub.NewFaker(seed).Paystub()
+		w.Header().Set("X-Paystub-Seed", strconv.FormatUint(seed, 10))
+	}
+	layout := paystub.DefaultLayout
+	if req.Template != "" {
+		if layout, err = paystub.LayoutNamed(req.Template); err != nil {
+			http.Error(w, fmt.Sprintf("template: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Layout) > 0 {
+		if layout, err = paystub.DecodeLayoutFrom(bytes.NewReader(req.Layout), layout); err != nil {
+			http.Error(w, fmt.Sprintf("layout: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := checkRemoteLayout(layout); err != nil {
+			http.Error(w, fmt.Sprintf("layout: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Locale != "" {
+		if _, err := paystub.LocaleNamed(req.Locale); err != nil {
+			http.Error(w, fmt.Sprintf("locale: %v", err), http.StatusBadRequest)
+			return
+		}
+		layout.Locale = req.Locale
+	}
+	var overlays []overlay.OverlayRectText
+	if len(req.Overlays) > 0 {
+		if overlays, err = overlay.DecodeOverlays(bytes.NewReader(req.Overlays)); err != nil {
+			http.Error(w, fmt.Sprintf("overlays: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := checkRemoteOverlays(overlays); err != nil {
+			http.Error(w, fmt.Sprintf("overlays: %v", err), http.StatusBadRequest)
+			return
+		}
+		if overlays, err = paystub.FillTemplates(overlays, stub, layout.Locale); err != nil {
+			http.Error(w, fmt.Sprintf("overlays: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pdf, err := paystub.Generate(stub, layout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if len(overlays) > 0 {
+		var out bytes.Buffer
+		if err := overlay.ApplyOverlays(bytes.NewReader(pdf), &out, overlays); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		pdf = out.Bytes()
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	if _, err := w.Write(pdf); err != nil {
+		slog.Error("Writing response failed", "err", err)
+	}
+}
+
+// serveHTTP listens on addr and serves POST /overlay and POST /v1/paystubs
+// until it fails.
+func serveHTTP(addr string, maxUpload int64) error {
+	mux := http.NewServeMux()
+	mux.Handle("/overlay", overlayHandler{maxUpload: maxUpload})
+	mux.Handle("/v1/paystubs", paystubHandler{maxUpload: maxUpload})
+	slog.Info("Serving POST /overlay and POST /v1/paystubs", "addr", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// runServe runs the serve subcommand with its arguments args: the HTTP
+// server of -serve, with its own flags.
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: overlay-rect-text serve [flags]\n\n%s: POST /overlay and POST /v1/paystubs.\n\nOverlays sent to either may not name files on the server: imagePath and fontFile\nare refused with 400 Bad Request, so images must come inline as imageData and\ntext in the built-in fonts. So are font sizes over %d points and, for\n/v1/paystubs, layout pages over %d points either way.\n\nFlags:\n", serveSummary, maxRemoteFontSize, maxRemotePageSize)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", ":8080", "Address to listen on")
+	maxUpload := flags.Int64("max-upload", 64<<20, "Largest request body accepted, in bytes")
+	logs := addLogFlags(flags)
+	flags.Parse(args)
+	logs.setup(false)
+	if flags.NArg() > 0 {
+		fatalf(exitUsage, "serve takes no arguments, got %q\n", flags.Args())
+	}
+	if err := serveHTTP(*addr, *maxUpload); err != nil {
+		fatalf(exitFailure, "HTTP server failed: %v\n", err)
+	}
+}
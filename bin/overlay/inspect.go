@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// runInspect runs the inspect subcommand with its arguments args: it lists
+// the text runs of -pdf with their boxes, as a table or as JSON.
+func runInspect(args []string) {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: overlay-rect-text inspect -pdf=original.pdf [flags]\n\n%s. Boxes are in PDF points from the lower-left corner of the page as shown, the default coordinates of overlays.\n\nFlags:\n", inspectSummary)
+		flags.PrintDefaults()
+	}
+	pdfPath := flags.String("pdf", "", "Path to the PDF to inspect (- for stdin)")
+	pages := flags.String("pages", "", "Pages to inspect, e.g. 1 or 2-3 or last (default: all)")
+	format := flags.String("format", "table", "Output format: table or json")
+	outPath := flags.String("out", "-", "Path to write the listing to (- for stdout)")
+	password := flags.String("inpw", "", "Password (user or owner) to open an encrypted -pdf with")
+	logs := addLogFlags(flags)
+	flags.Parse(args)
+	logs.setup(false)
+	if *pdfPath == "" || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if *format != "table" && *format != "json" {
+		fatalf(exitUsage, "Invalid -format %q (valid: table, json)\n", *format)
+	}
+
+	pdf, err := readPDF(*pdfPath, *password)
+	if err != nil {
+		fatalf(exitPDF, "Could not read PDF file: %v\n", err)
+	}
+	runs, err := overlay.Inspect(pdf, *pages)
+	if err != nil {
+		fatalf(exitStatus(err), "Inspecting %s failed: %v\n", *pdfPath, err)
+	}
+
+	var buf bytes.Buffer
+	if *format == "json" {
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			fatalf(exitFailure, "Could not encode text runs: %v\n", err)
+		}
+		buf.Write(append(data, '\n'))
+	} else {
+		writeRunTable(&buf, runs)
+	}
+	if err := writeOutput(*outPath, buf.Bytes()); err != nil {
+		fatalf(exitOutput, "Could not write listing: %v\n", err)
+	}
+}
+
+// writeRunTable writes runs to w as a table with aligned columns.
+func writeRunTable(w io.Writer, runs []overlay.TextRun) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "PAGE\tX\tY\tWIDTH\tHEIGHT\t\tTEXT")
+	for _, r := range runs {
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%.2f\t%.2f\t\t%s\n", r.Page, r.X, r.Y, r.Width, r.Height, strings.ReplaceAll(r.Text, "\t", " "))
+	}
+	tw.Flush()
+}
+
+// readPDF reads the PDF at path, or from stdin when path is "-", and
+// decrypts it with password if it is encrypted.
+func readPDF(path, password string) ([]byte, error) {
+	var pdf []byte
+	var err error
+	if path == "-" {
+		pdf, err = io.ReadAll(os.Stdin)
+	} else {
+		pdfFS, pdfName := dirFSFor(path)
+		pdf, err = overlay.LoadTemplate(pdfFS, pdfName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", overlay.ErrInvalidPDF, err)
+	}
+	return overlay.Decrypt(pdf, password)
+}
+
+// encrypt returns pdf encrypted as e says, or as it is if e is nil.
+func encrypt(pdf []byte, e *overlay.Encryption) ([]byte, error) {
+	if e == nil {
+		return pdf, nil
+	}
+	return overlay.Encrypt(pdf, *e)
+}
+
+// optimize returns pdf optimized if on, or else as it is, logging its size
+// before and after under name.
+func optimize(name string, pdf []byte, on bool) ([]byte, error) {
+	if !on {
+		return pdf, nil
+	}
+	optimized, err := overlay.Optimize(pdf)
+	if err != nil {
+		return nil, fmt.Errorf("optimizing: %w", err)
+	}
+	slog.Debug("Optimized output", "file", name, "before", len(pdf), "after", len(optimized))
+	return optimized, nil
+}
+
+// linearize returns pdf linearized by l, or as it is if l is nil.
+func linearize(pdf []byte, l overlay.Linearizer) ([]byte, error) {
+	if l == nil {
+		return pdf, nil
+	}
+	pdf, err := l(pdf)
+	if err != nil {
+		return nil, fmt.Errorf("linearizing: %w", err)
+	}
+	return pdf, nil
+}
+
+// sign returns pdf signed by s, or as it is if s is nil.
+func sign(pdf []byte, s *overlay.Signer) ([]byte, error) {
+	if s == nil {
+		return pdf, nil
+	}
+	pdf, err := overlay.Sign(pdf, *s)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return pdf, nil
+}
+
+// setMetadata returns pdf with its metadata rewritten as m says, or as it is
+// if m is nil.
+func setMetadata(pdf []byte, m *overlay.Metadata) ([]byte, error) {
+	if m == nil {
+		return pdf, nil
+	}
+	pdf, err := overlay.SetMetadata(pdf, *m)
+	if err != nil {
+		return nil, fmt.Errorf("setting metadata: %w", err)
+	}
+	return pdf, nil
+}
+
+// parseMetaDate parses a -meta-date, a date (taken as midnight UTC) or an
+// RFC 3339 time.
+func parseMetaDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
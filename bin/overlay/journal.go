@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// journal is the -resume record of the outputs a batch has finished, JSON
+// Lines appended as each one is written, so that a run interrupted part of
+// the way can be resumed by running it again: outputs the journal lists
+// whose files are unchanged are skipped. A nil journal records nothing.
+type journal struct {
+	images *imageOutput // how the outputs are written, to find their files
+
+	mu       sync.Mutex
+	f        *os.File
+	finished map[string]journalRecord // by output
+}
+
+// journalRecord is the journal line of a finished output.
+type journalRecord struct {
+	Input  string            `json:"input"`
+	Output string            `json:"output"`
+	Files  map[string]string `json:"files"` // SHA-256 of each file written, by path
+	// Truth is the ground truth of the output, for runs that work it out.
+	Truth []overlay.TruthField `json:"truth,omitempty"`
+}
+
+// openJournal reads the journal at path, if there is one, and opens it to
+// record more outputs, whose files images says how to find. An empty path
+// returns nil.
+func openJournal(path string, images *imageOutput) (*journal, error) {
+	if path == "" {
+		return nil, nil
+	}
+	j := &journal{images: images, finished: map[string]journalRecord{}}
+	if f, err := os.Open(path); err == nil {
+		s := bufio.NewScanner(f)
+		s.Buffer(nil, 64<<20)
+		for s.Scan() {
+			// An interrupted run can leave its last line cut short; the
+			// output it was recording is made again.
+			var r journalRecord
+			if json.Unmarshal(s.Bytes(), &r) == nil {
+				j.finished[r.Output] = r
+			}
+		}
+		err := s.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	return j, nil
+}
+
+// skip reports whether an earlier run finished r, the same input written to
+// the same output whose files are still as it wrote them, and if so fills
+// in r from the journal. The output must have its ground truth recorded if
+// truth is set.
+func (j *journal) skip(r *batchResult, truth bool) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	rec, ok := j.finished[r.output]
+	j.mu.Unlock()
+	if !ok || rec.Input != r.input || len(rec.Files) == 0 || truth && rec.Truth == nil {
+		return false
+	}
+	for path, sum := range rec.Files {
+		if got, err := fileSum(path); err != nil || got != sum {
+			return false
+		}
+	}
+	r.truth, r.skipped = rec.Truth, true
+	return true
+}
+
+// record adds r, which has just been written, to the journal.
+func (j *journal) record(r batchResult) error {
+	if j == nil || r.err != nil || r.skipped {
+		return nil
+	}
+	rec := journalRecord{Input: r.input, Output: r.output, Files: map[string]string{}, Truth: r.truth}
+	for _, path := range j.images.files(r.output) {
+		sum, err := fileSum(path)
+		if err != nil {
+			return err
+		}
+		rec.Files[path] = sum
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished[r.output] = rec
+	_, err = j.f.Write(append(data, '\n'))
+	return err
+}
+
+// close closes the journal file.
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// fileSum returns the hex SHA-256 of the file at path.
+func fileSum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
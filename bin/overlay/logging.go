@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logFlags are the flags every command takes to choose how much it logs to
+// stderr, and how.
+type logFlags struct {
+	verbose *bool
+	quiet   *bool
+	format  *string
+}
+
+// addLogFlags defines the logging flags on fs.
+func addLogFlags(fs *flag.FlagSet) logFlags {
+	return logFlags{
+		verbose: fs.Bool("v", false, "Verbose: also log debug messages, such as each overlay as it is applied"),
+		quiet:   fs.Bool("q", false, "Quiet: log only errors, and print no messages or summary of what succeeded"),
+		format:  fs.String("log-format", "text", "Format of the log on stderr: text, or json for one structured record per line"),
+	}
+}
+
+// setup installs the default logger the flags ask for, logging debug
+// messages too if debug is set. It exits with status 2 on bad flags.
+func (f logFlags) setup(debug bool) {
+	if *f.verbose && *f.quiet {
+		fmt.Fprintln(os.Stderr, "-v and -q cannot both be set")
+		os.Exit(exitUsage)
+	}
+	level := slog.LevelInfo
+	switch {
+	case *f.verbose || debug:
+		level = slog.LevelDebug
+	case *f.quiet:
+		level = slog.LevelError
+	}
+	switch *f.format {
+	case "text":
+		// The default handler writes through the log package, keeping its
+		// timestamped lines.
+		slog.SetLogLoggerLevel(level)
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid -log-format %q (valid: text, json)\n", *f.format)
+		os.Exit(exitUsage)
+	}
+}
@@ -2,191 +2,473 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
-	"flag"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/pdfcpu/pdfcpu/pkg/api"
-	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
-	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/language"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
 )
 
-// OverlayRectText describes one overlay: a white rectangle and text on top.
-type OverlayRectText struct {
-	Text   string  `json:"text"`
-	X      float64 `json:"x"`
-	Y      float64 `json:"y"`
-	Width  float64 `json:"width"`  // rectangle width in PDF points
-	Height float64 `json:"height"` // rectangle height in PDF points
-	Scale  float64 `json:"scale"`
+// dirFSFor splits an OS path into an os.DirFS rooted at its directory and the
+// file name within it, so CLI paths can be passed to overlay.LoadTemplate.
+func dirFSFor(path string) (fs.FS, string) {
+	return os.DirFS(filepath.Dir(path)), filepath.Base(path)
+}
+
+// readInput reads the file at path, or standard input when path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
 }
 
-// createWhitePNG returns a data URI for a w x h PNG of solid white.
-func createWhitePNG(w, h int) (string, error) {
-	// Create a w x h white image.
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	for y := 0; y < h; y++ {
-		for x := 0; x < w; x++ {
-			img.Set(x, y, color.White)
+// decodeOverlays parses the overlay file read from path in the given format.
+// In form mode it may instead be a JSON object or CSV file of form field
+// values by name.
+func decodeOverlays(data []byte, path, format, mode string) ([]overlay.OverlayRectText, error) {
+	if format == "auto" {
+		format = "json"
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			format = "yaml"
+		case ".toml":
+			format = "toml"
+		case ".csv":
+			format = "csv"
 		}
 	}
-	// Encode to PNG in memory.
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return "", err
+	values := format == "csv" || format == "json" && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+	if values && mode != "form" {
+		return nil, fmt.Errorf("form field values (a JSON object or CSV) need -mode form; overlays are a list")
+	}
+	switch {
+	case values:
+		return overlay.DecodeFormValues(data, format)
+	case format == "json":
+		return overlay.DecodeOverlays(bytes.NewReader(data))
+	case format == "yaml":
+		return overlay.DecodeOverlaysYAML(data)
+	case format == "toml":
+		return overlay.DecodeOverlaysTOML(data)
 	}
-	// Return a data URI: "data:image/png;base64,ABC..."
-	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return "data:image/png;base64," + encoded, nil
+	return nil, fmt.Errorf("unknown format %q (valid: auto, json, yaml, toml, csv)", format)
 }
 
-// saveDataURIToTempFile takes a data URI from createWhitePNG,
-// decodes it, and saves the raw PNG bytes into a temporary file under /tmp.
-func saveDataURIToTempFile(dataURI string) (string, error) {
-	const prefix = "data:image/png;base64,"
-	if !strings.HasPrefix(dataURI, prefix) {
-		return "", errors.New("not a valid PNG data URI")
+// writeManifest writes the placements of overlays on pdf as JSON to path.
+func writeManifest(path string, pdf []byte, overlays []overlay.OverlayRectText) error {
+	placements, err := overlay.Placements(pdf, overlays)
+	if err != nil {
+		return err
 	}
-	base64Data := dataURI[len(prefix):]
-
-	// Decode the base64 string back into raw PNG bytes
-	decoded, err := base64.StdEncoding.DecodeString(base64Data)
+	data, err := json.MarshalIndent(placements, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("base64 decode error: %v", err)
+		return err
 	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
 
-	// Create a temporary file in /tmp
-	tmpFile, err := os.CreateTemp("", "white_*.png")
-	if err != nil {
-		return "", fmt.Errorf("failed creating temp file: %v", err)
+// writePreview writes the -preview PNG of page pageNr to path, or to stdout
+// when path is "-". A path ending in .pdf gets the dry run of
+// overlay.PreviewOverlays on pdf instead, covering every page.
+func writePreview(path string, pdf []byte, overlays []overlay.OverlayRectText, pageNr int) error {
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		if err := overlay.ApplyOverlays(bytes.NewReader(pdf), &buf, overlay.PreviewOverlays(overlays)); err != nil {
+			return err
+		}
+	} else if err := overlay.Preview(pdf, overlays, pageNr, &buf); err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
 	}
-	defer tmpFile.Close()
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
 
-	// Write the PNG bytes to it
-	if _, err := tmpFile.Write(decoded); err != nil {
-		return "", fmt.Errorf("failed writing to temp file: %v", err)
+func main() {
+	c, args := command{main: runLegacy}, os.Args[1:]
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		name := args[0]
+		args = args[1:]
+		if name == "help" {
+			printCommands(os.Stdout)
+			return
+		}
+		var ok bool
+		if c, ok = lookupCommand(name); !ok {
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+			printCommands(os.Stderr)
+			os.Exit(exitUsage)
+		}
 	}
+	c.main(args)
+}
 
-	return tmpFile.Name(), nil
+// runOverlay runs the overlay subcommand with its arguments args: it
+// applies the overlays of -json to -pdf. With -fake or -roster but no
+// -json, it generates the paystubs instead.
+func runOverlay(args []string) {
+	flags := newCommandFlags("overlay", overlaySummary)
+	var o overlayOptions
+	var out outputOptions
+	var data dataOptions
+	o.define(flags.FlagSet)
+	out.define(flags.FlagSet)
+	data.define(flags.FlagSet)
+	flags.parse(args, &out.debug)
+	o.checkData(&data)
+	if o.jsonPath == "" && (data.fake || data.rosterPath != "") {
+		generateDocuments(flags, &out, &data, &generateOptions{}, o.formatLocale(flags))
+		return
+	}
+	if o.jsonPath == "" || o.pdfPath == "" {
+		fmt.Fprintln(os.Stderr, "overlay needs -json and -pdf")
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	overlayFiles(flags, &o, &out, &data)
 }
 
-func main() {
-	// CLI flags
-	jsonPath := flag.String("json", "", "Path to JSON file describing rectangle+text overlays")
-	pdfPath := flag.String("pdf", "", "Path to the original PDF")
-	outPath := flag.String("out", "out.pdf", "Path to the output PDF file")
-	flag.Parse()
-
-	// Basic validation
-	if *jsonPath == "" || *pdfPath == "" {
+// runBatch runs the batch subcommand with its arguments args: it applies
+// the overlays of -json to every PDF in the directory or glob -pdf.
+func runBatch(args []string) {
+	flags := newCommandFlags("batch", batchSummary)
+	var o overlayOptions
+	var out outputOptions
+	var data dataOptions
+	o.define(flags.FlagSet)
+	out.define(flags.FlagSet)
+	data.define(flags.FlagSet)
+	flags.parse(args, &out.debug)
+	o.checkData(&data)
+	if o.jsonPath == "" || o.pdfPath == "" {
+		fmt.Fprintln(os.Stderr, "batch needs -json and -pdf")
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if _, batch, err := batchInputs(o.pdfPath); err == nil && !batch {
+		fatalf(exitUsage, "batch needs -pdf to be a directory or glob of PDFs, not %q\n", o.pdfPath)
+	}
+	overlayFiles(flags, &o, &out, &data)
+}
+
+// runLegacy runs flags given without a command, as they were before there
+// were subcommands: the overlay command, but taking every flag, so
+// -generate, -serve and the rest still work there.
+func runLegacy(args []string) {
+	flags := newCommandFlags("", overlaySummary)
+	var o overlayOptions
+	var out outputOptions
+	var data dataOptions
+	var gen generateOptions
+	var server serverOptions
+	o.define(flags.FlagSet)
+	out.define(flags.FlagSet)
+	data.define(flags.FlagSet)
+	gen.define(flags.FlagSet)
+	server.define(flags.FlagSet)
+	flags.parse(args, &out.debug)
+
+	if server.grpcAddr != "" {
+		if err := serveGRPC(server.grpcAddr); err != nil {
+			fatalf(exitFailure, "gRPC server failed: %v\n", err)
+		}
+		return
+	}
+	if server.serveAddr != "" {
+		if err := serveHTTP(server.serveAddr, server.maxUpload); err != nil {
+			fatalf(exitFailure, "HTTP server failed: %v\n", err)
+		}
+		return
+	}
+
+	o.checkData(&data)
+	if gen.dataPath != "" || o.jsonPath == "" && (data.fake || data.rosterPath != "") {
+		generateDocuments(flags, &out, &data, &gen, o.formatLocale(flags))
+		return
+	}
+	if gen.evilSpec != "" {
+		fatalf(exitUsage, "-evil needs generated paystubs: -generate, or -fake or -roster without -json\n")
+	}
+	if gen.extras() {
+		fatalf(exitUsage, "-tax-form, -bank-statements and -deposit-advice cannot be combined with -json\n")
+	}
+	if o.jsonPath == "" || o.pdfPath == "" {
 		fmt.Println("Usage: overlay-rect-text -json=overlays.json -pdf=original.pdf -out=modified.pdf")
-		os.Exit(1)
+		fmt.Println("       overlay-rect-text -json=- -pdf=original.pdf -out=- < overlays.json > modified.pdf")
+		fmt.Println("       overlay-rect-text -json=overlays.json -pdf='stubs/*.pdf' -out=outdir")
+		fmt.Println("       overlay-rect-text -json=overlays.json -pdf=template.pdf -fake -count=1000 -out='stubs/stub_{index}.pdf'")
+		fmt.Println("       overlay-rect-text -generate=stub.json [-layout=layout.json] -out=stub.pdf")
+		fmt.Println("       overlay-rect-text -fake [-seed=42] [-template=adp | -layout=layout.json] -out=stub.pdf")
+		fmt.Println("       overlay-rect-text -fake -count=1000 -out='stubs/stub_{index}.pdf'")
+		fmt.Println("       overlay-rect-text -fake -series=26 [-series-start=2025-01-01 -frequency=biweekly] [-tax-form=w2 -bank-statements -deposit-advice] -out='stubs/stub_{index}.pdf'")
+		fmt.Println("       overlay-rect-text -roster=employees.csv [-json=overlays.json -pdf=template.pdf] -out='stubs/stub_{index}.pdf'")
+		fmt.Println("       overlay-rect-text -grpc=:50051")
+		fmt.Println("       overlay-rect-text serve [-addr=:8080]")
+		fmt.Println("Run overlay-rect-text help for the list of commands.")
+		os.Exit(exitUsage)
+	}
+	overlayFiles(flags, &o, &out, &data)
+}
+
+// overlayFiles applies the overlays of o to the PDF or PDFs of o, as the
+// flags of flags, o, out and data ask, and exits with status 2 on bad
+// flags.
+func overlayFiles(flags commandFlags, o *overlayOptions, out *outputOptions, d *dataOptions) {
+	if o.jsonPath == "-" && o.pdfPath == "-" {
+		fatalf(exitUsage, "Only one of -json and -pdf can be read from stdin\n")
+	}
+	if o.dataPath == "-" && (o.jsonPath == "-" || o.pdfPath == "-") {
+		fatalf(exitUsage, "Only one of -data, -json and -pdf can be read from stdin\n")
+	}
+	if o.dataPath != "" && d.fake {
+		fatalf(exitUsage, "Use only one of -data and -fake to fill in overlay text\n")
+	}
+	if d.count > 1 && !d.fake {
+		fatalf(exitUsage, "-count needs -fake: the same overlays give the same PDF every time\n")
 	}
+	if o.verifyPath == "-" && out.outPath == "-" || out.truthPath == "-" && (out.outPath == "-" || o.verifyPath == "-") {
+		fatalf(exitUsage, "Only one of -out, -verify and -truth can be written to stdout\n")
+	}
+	if o.flatten && o.mode != "form" {
+		fatalf(exitUsage, "-flatten needs -mode form\n")
+	}
+	if o.mode == "form" && (o.stampHash || o.grid) {
+		fatalf(exitUsage, "-stamp-hash and -grid draw overlays, so they need -mode overlay, not form\n")
+	}
+	output := out.check(flags)
+	if o.verifyPath != "" {
+		if output.scan != nil {
+			fatalf(exitUsage, "-verify cannot check a -scan output, which has no text layer\n")
+		}
+		if output.encryption != nil && out.userPassword != "" {
+			fatalf(exitUsage, "-verify cannot read back an output that -upw keeps closed\n")
+		}
+	}
+	// Messages go to stderr when stdout carries a report.
+	msgOut := output.messages(o.verifyPath == "-" || out.truthPath == "-")
+	data := d.load(out.deterministic)
+	countPattern := output.pattern(data.copies)
 
 	// 1) Read JSON describing overlays
-	data, err := ioutil.ReadFile(*jsonPath)
+	overlayJSON, err := readInput(o.jsonPath)
 	if err != nil {
-		log.Fatalf("Could not read JSON file: %v\n", err)
+		fatalf(exitInput, "Could not read JSON file: %v\n", err)
 	}
-	var overlays []OverlayRectText
-	if err := json.Unmarshal(data, &overlays); err != nil {
-		log.Fatalf("JSON parse error: %v\n", err)
+	overlays, err := decodeOverlays(overlayJSON, o.jsonPath, o.format, o.mode)
+	if err != nil {
+		fatalf(exitInput, "Overlay file parse error: %v\n", err)
+	}
+	for i := range overlays {
+		if overlays[i].Origin == "" {
+			overlays[i].Origin = o.origin
+		}
+		if overlays[i].Units == "" {
+			overlays[i].Units = o.units
+		}
+		if o.redact {
+			overlays[i].Redact = true
+		}
+		if overlays[i].Font == "" && overlays[i].FontFile == "" {
+			if o.font != "" {
+				overlays[i].Font = o.font
+			} else {
+				overlays[i].FontFile = o.fontFile
+			}
+		}
+		if overlays[i].FillColor == "" {
+			overlays[i].FillColor = o.fillColor
+		}
+		if overlays[i].TextColor == "" {
+			overlays[i].TextColor = o.textColor
+		}
 	}
 
-	// 2) Load the original PDF into memory (as bytes).
-	originalPDF, err := ioutil.ReadFile(*pdfPath)
+	tag, err := language.Parse(o.locale)
 	if err != nil {
-		log.Fatalf("Could not read PDF file: %v\n", err)
-	}
-
-	// We'll apply 2 watermarks per overlay (rectangle, then text) in memory.
-	currentPDF := originalPDF
-
-	for i, ov := range overlays {
-		log.Printf("Processing overlay %d: text=%q at (%.2f, %.2f), rect=%.2fx%.2f, scale=%.2f\n",
-			i, ov.Text, ov.X, ov.Y, ov.Width, ov.Height, ov.Scale)
-
-		// -----------------------------------------------------
-		// Pass 1: White rectangle (if width/height > 0)
-		// -----------------------------------------------------
-		if ov.Width > 0 && ov.Height > 0 {
-			// Create a data URI for a white PNG of size (ov.Width x ov.Height) in pixels
-			// because we'll apply scale:1 abs in pdfcpu => it becomes exactly that many PDF points.
-			wInt := int(ov.Width)
-			hInt := int(ov.Height)
-			whitePNGData, err := createWhitePNG(wInt, hInt)
-			if err != nil {
-				log.Fatalf("Failed to create white PNG: %v\n", err)
-			}
-			whitePNGPath, err := saveDataURIToTempFile(whitePNGData)
-			if err != nil {
-				log.Fatalf("Failed to save white PNG: %v\n", err)
-			}
-			// Build the parameter string for the image watermark
-			// pos:bl => anchor at bottom-left
-			// offset:X Y => shift by (ov.X, ov.Y)
-			// scale:1 abs => keep actual pixel size => ov.Width x ov.Height in PDF points
-			// mode:0 => overlay in the foreground (opaque)
-			rectParams := fmt.Sprintf("pos:bl, offset:%f %f, scale:%f abs, rot:0, mode:0", ov.X, ov.Y, ov.Scale)
-			wmRect, err := pdfcpu.ParseImageWatermarkDetails(whitePNGPath, rectParams, true, types.POINTS)
-			if err != nil {
-				log.Fatalf("Failed to parse image watermark details for overlay %d: %v\n", i, err)
-			}
+		fatalf(exitUsage, "Invalid locale %q: %v\n", o.locale, err)
+	}
+	cat, err := overlay.LoadCatalog(o.catalogPath)
+	if err != nil {
+		fatalf(exitInput, "Could not load message catalog: %v\n", err)
+	}
+	if err := overlay.LocalizeLabels(overlays, cat, tag); err != nil {
+		fatalf(exitInput, "Localizing labels failed: %v\n", err)
+	}
 
-			// Apply this rectangle watermark in-memory
-			inBuf := bytes.NewReader(currentPDF)
-			outBuf := new(bytes.Buffer)
+	// Placeholders in the text are filled in last, so translations can
+	// use them too.
+	var templates *templateSource
+	switch {
+	case data.stubs != nil:
+		templates = &templateSource{stubs: data.stubs, locale: o.formatLocale(flags)}
+	case d.fake:
+		templates = &templateSource{seed: d.seed, rates: data.rates, locale: o.formatLocale(flags)}
+	case o.dataPath != "":
+		stubData, err := readInput(o.dataPath)
+		if err != nil {
+			fatalf(exitInput, "Could not read paystub data: %v\n", err)
+		}
+		stub, err := paystub.DecodePaystub(bytes.NewReader(stubData))
+		if err != nil {
+			fatalf(exitInput, "Paystub data parse error: %v\n", err)
+		}
+		templates = &templateSource{stub: &stub, locale: o.formatLocale(flags)}
+	}
 
-			if err := api.AddWatermarks(inBuf, outBuf, nil, wmRect, nil); err != nil {
-				log.Fatalf("Failed adding white rectangle for overlay %d: %v\n", i, err)
-			}
+	cfg := runConfig{
+		mode:          o.mode,
+		password:      o.inPassword,
+		metadata:      output.metadata,
+		encryption:    output.encryption,
+		signer:        output.signer,
+		optimize:      out.optimize,
+		linearize:     output.linearizeWith,
+		flatten:       o.flatten,
+		strict:        o.strict,
+		maxOutputSize: out.maxOutputSize,
+		debug:         out.debug,
+		quiet:         *flags.logs.quiet,
+		stampHash:     o.stampHash,
+		stampStyle:    overlay.DefaultHashStampStyle,
+		overlayJSON:   overlayJSON,
+		verify:        o.verifyPath != "" && o.mode == "overlay",
+		data:          templates,
+		truth:         out.truthPath != "",
+		scan:          output.scan,
+		images:        output.images,
+		grid:          o.grid,
+	}
+	if o.stampHash && o.stampHashStyle != "" {
+		styleData, err := ioutil.ReadFile(o.stampHashStyle)
+		if err != nil {
+			fatalf(exitInput, "Could not read hash stamp style: %v\n", err)
+		}
+		if err := json.Unmarshal(styleData, &cfg.stampStyle); err != nil {
+			fatalf(exitInput, "Hash stamp style parse error: %v\n", err)
+		}
+		if err := overlay.CheckHashStampStyle(cfg.stampStyle); err != nil {
+			fatalf(exitInput, "Invalid hash stamp style: %v\n", err)
+		}
+	}
 
-			updated, err := io.ReadAll(outBuf)
-			if err != nil {
-				log.Fatalf("io.ReadAll (rectangle pass) failed: %v\n", err)
+	// A directory or glob of PDFs is processed as a batch, writing
+	// name.overlaid.pdf for each into the -out directory.
+	inputs, batch, err := batchInputs(o.pdfPath)
+	if err != nil {
+		fatalf(exitPDF, "Could not list PDF files: %v\n", err)
+	}
+	if batch && o.previewPath != "" {
+		fatalf(exitUsage, "-preview needs a single PDF, not %q\n", o.pdfPath)
+	}
+	if batch && o.manifestPath != "" {
+		fatalf(exitUsage, "-manifest needs a single PDF, not %q\n", o.pdfPath)
+	}
+	if batch && data.stubs != nil {
+		fatalf(exitUsage, "-roster and -series need a single PDF file, not %q\n", o.pdfPath)
+	}
+	if data.copies > 1 {
+		if batch || o.pdfPath == "-" {
+			fatalf(exitUsage, "-count, -roster and -series need a single PDF file, not %q\n", o.pdfPath)
+		}
+		if o.previewPath != "" || o.manifestPath != "" {
+			fatalf(exitUsage, "-preview and -manifest need a single output, not %d\n", data.copies)
+		}
+		cfg.track = output.track(data.copies)
+		finishBatch(cfg, o.verifyPath, out.truthPath, msgOut, fillFiles(cfg, o.pdfPath, countPattern, overlays, data.copies, out.workers))
+		return
+	}
+	if batch {
+		if len(inputs) == 0 {
+			fatalf(exitPDF, "No PDF files match %q\n", o.pdfPath)
+		}
+		outDir := ""
+		if flags.isSet("out") {
+			outDir = out.outPath
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				fatalf(exitOutput, "Could not create output directory: %v\n", err)
 			}
-			currentPDF = updated
 		}
+		cfg.track = output.track(len(inputs))
+		finishBatch(cfg, o.verifyPath, out.truthPath, msgOut, processBatch(cfg, inputs, outDir, overlays, out.workers))
+		return
+	}
 
-		// -----------------------------------------------------
-		// Pass 2: Text
-		// -----------------------------------------------------
-		textParams := fmt.Sprintf("pos:bl, offset:%f %f, rot:0, scale:%f, fillc:#000000, mode:0",
-			ov.X, ov.Y, ov.Scale/4)
-		wmText, err := pdfcpu.ParseTextWatermarkDetails(ov.Text, textParams, true, types.POINTS)
-		if err != nil {
-			log.Fatalf("Error creating text watermark for overlay %d: %v\n", i, err)
+	overlays, err = templates.fill(overlays, 0)
+	if err != nil {
+		fatalf(exitInput, "Filling in overlay text failed: %v\n", err)
+	}
+
+	// 2) Load the original PDF into memory (as bytes).
+	originalPDF, err := readPDF(o.pdfPath, o.inPassword)
+	if err != nil {
+		fatalf(exitPDF, "Could not read PDF file: %v\n", err)
+	}
+
+	if o.previewPath != "" {
+		if err := writePreview(o.previewPath, originalPDF, overlays, o.previewPage); err != nil {
+			fatalf(exitStatus(err), "Preview failed: %v\n", err)
 		}
+		if strings.EqualFold(filepath.Ext(o.previewPath), ".pdf") {
+			fmt.Fprintf(os.Stderr, "Preview saved to %q\n", o.previewPath)
+		} else {
+			fmt.Fprintf(os.Stderr, "Preview of page %d saved to %q\n", o.previewPage, o.previewPath)
+		}
+		return
+	}
 
-		// Apply the text watermark in-memory
-		inBuf2 := bytes.NewReader(currentPDF)
-		outBuf2 := new(bytes.Buffer)
+	currentPDF, err := cfg.process(o.pdfPath, originalPDF, overlays)
+	if err != nil {
+		fatalf(exitStatus(err), "Processing %s failed: %v\n", o.pdfPath, err)
+	}
+
+	// 3) Write the final PDF, or its -out-format images. With -out - it
+	// goes to stdout, so the summary goes to stderr to keep the stream clean.
+	if err := output.images.write(out.outPath, currentPDF); err != nil {
+		fatalf(exitOutput, "Could not write output: %v\n", err)
+	}
+	if out.outPath == "-" {
+		fmt.Fprintln(os.Stderr, "Done! Overlays applied. Result written to stdout")
+	} else {
+		fmt.Fprintf(msgOut, "Done! Overlays applied. Result saved to %q\n", out.outPath)
+	}
 
-		if err := api.AddWatermarks(inBuf2, outBuf2, nil, wmText, nil); err != nil {
-			log.Fatalf("Failed adding text for overlay %d: %v\n", i, err)
+	if o.manifestPath != "" {
+		if err := writeManifest(o.manifestPath, originalPDF, overlays); err != nil {
+			fatalf(exitOutput, "Could not write manifest: %v\n", err)
 		}
+	}
 
-		updated, err := io.ReadAll(outBuf2)
+	if cfg.truth {
+		truth, err := overlay.GroundTruth(originalPDF, overlays)
 		if err != nil {
-			log.Fatalf("io.ReadAll (text pass) failed: %v\n", err)
+			fatalf(exitStatus(err), "Working out ground truth failed: %v\n", err)
+		}
+		if err := writeTruth(out.truthPath, truthRecord{Output: out.outPath, Fields: truth}); err != nil {
+			fatalf(exitOutput, "Could not write ground truth: %v\n", err)
 		}
-		currentPDF = updated
 	}
 
-	// 3) Write the final PDF
-	if err := os.WriteFile(*outPath, currentPDF, 0644); err != nil {
-		log.Fatalf("Could not write output PDF: %v\n", err)
+	// 4) Optionally prove the overlays took by re-reading the result.
+	if cfg.verify {
+		report, verifyErr := verifyResult(o.pdfPath, out.outPath, originalPDF, currentPDF, overlays)
+		if report != nil {
+			if err := writeReports(o.verifyPath, []*fileReport{report}); err != nil {
+				fatalf(exitOutput, "Could not write verification report: %v\n", err)
+			}
+		}
+		if verifyErr != nil {
+			fatalf(exitStatus(verifyErr), "%s: %v\n", o.pdfPath, verifyErr)
+		}
 	}
-
-	fmt.Printf("Done! Overlays applied. Result saved to %q\n", *outPath)
 }
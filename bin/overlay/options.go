@@ -0,0 +1,488 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// The flags of the commands that make documents come in groups, each with
+// a type of its own whose define method defines them on a flag set, so a
+// command defines only the groups it takes.
+
+// overlayOptions are the flags of the overlays and how to apply them to
+// -pdf.
+type overlayOptions struct {
+	jsonPath       string
+	format         string
+	pdfPath        string
+	stampHash      bool
+	stampHashStyle string
+	mode           string
+	flatten        bool
+	locale         string
+	catalogPath    string
+	origin         string
+	units          string
+	redact         bool
+	fontFile       string
+	font           string
+	fillColor      string
+	textColor      string
+	strict         bool
+	verifyPath     string
+	previewPath    string
+	previewPage    int
+	grid           bool
+	manifestPath   string
+	dataPath       string
+	inPassword     string
+}
+
+func (o *overlayOptions) define(fs *flag.FlagSet) {
+	fs.StringVar(&o.jsonPath, "json", "", "Path to JSON (or YAML or TOML) file describing rectangle+text overlays (- for stdin)")
+	fs.StringVar(&o.format, "format", "auto", "Format of the -json file: json, yaml, toml, csv (form field values, with -mode form), or auto to go by its extension (.yaml/.yml are YAML, .toml is TOML, .csv is CSV)")
+	fs.StringVar(&o.pdfPath, "pdf", "", "Path to the original PDF (- for stdin), or a directory or glob of PDFs to process as a batch")
+	fs.BoolVar(&o.stampHash, "stamp-hash", false, "Stamp a short SHA-256 of the source PDF and overlay JSON in the page footer")
+	fs.StringVar(&o.stampHashStyle, "stamp-hash-style", "", "Path to a JSON overlay object styling the hash stamp; its text has one %s, replaced by the hex digest, and %% for a percent sign")
+	fs.StringVar(&o.mode, "mode", "overlay", "How to apply the data: overlay (draw rectangles and text) or form (fill AcroForm fields, from overlays with a field, or from a JSON object or CSV file of values by field name)")
+	fs.BoolVar(&o.flatten, "flatten", false, "With -mode form, draw the filled fields into the pages and remove the form, so the values can no longer be edited")
+	fs.StringVar(&o.locale, "locale", "en", "Locale used to translate overlay labels and to write the amounts and dates of generated documents and of the currency, amount, percent, date and longdate functions of {{...}} placeholders, as "+strings.Join(paystub.LocaleNames(), ", ")+" do (generated documents default to their layout's)")
+	fs.StringVar(&o.catalogPath, "catalog", "", "Path to a JSON message catalog ({locale: {label: text}}) for overlay labels")
+	fs.StringVar(&o.origin, "origin", "bl", "Default coordinate origin for overlays without one: bl (Y up from the page bottom) or tl (Y down from the page top)")
+	fs.StringVar(&o.units, "units", "pt", "Default units of X, Y, width and height for overlays without units: pt, in, mm or percent")
+	fs.BoolVar(&o.redact, "redact", false, "Remove the text and images under every overlay rectangle from the PDF instead of only covering them")
+	fs.StringVar(&o.fontFile, "fontfile", "", "Path of a TrueType (.ttf) font for the text of overlays without a font or fontFile")
+	fs.StringVar(&o.font, "font", "", "Default pdfcpu font name (e.g. Courier) for the text of overlays without a font or fontFile, used instead of -fontfile")
+	fs.StringVar(&o.fillColor, "fill-color", "", "Default rectangle colour, #RRGGBB, an SVG colour name or none, for overlays without a fillColor (default white)")
+	fs.StringVar(&o.textColor, "text-color", "", "Default text colour, #RRGGBB or an SVG colour name, for overlays without a textColor (default black)")
+	fs.BoolVar(&o.strict, "strict", false, "Fail instead of warning when an overlay extends past the edge of a page")
+	fs.StringVar(&o.verifyPath, "verify", "", "After applying the overlays, write a JSON report of any original text still extractable under each overlay to this path (- for stdout), failing if there is some")
+	fs.StringVar(&o.previewPath, "preview", "", "Instead of writing a PDF, write a PNG wireframe of a page with the overlay outlines and see-through fills, labelled with their indexes, to this path (- for stdout); a path ending in .pdf gets the original PDF with the same outlines drawn over its content, leaving it visible")
+	fs.IntVar(&o.previewPage, "preview-page", 1, "Page to draw with -preview")
+	fs.BoolVar(&o.grid, "grid", false, fmt.Sprintf("Draw a light coordinate grid over the output, with lines every %d points and labels every %d, in the coordinates of overlays with the default origin and units, to help place them", overlay.GridMinor, overlay.GridMajor))
+	fs.StringVar(&o.manifestPath, "manifest", "", "Also write a JSON manifest of the box each overlay covers, in PDF points, and its pages to this path")
+	fs.StringVar(&o.dataPath, "data", "", "Path to paystub JSON data (- for stdin) to fill in {{...}} placeholders in overlay text, such as {{.EmployeeName}} or {{.NetPay | currency}}")
+	fs.StringVar(&o.inPassword, "inpw", "", "Password (user or owner) to open encrypted -pdf files with")
+}
+
+// formatLocale returns the locale to write amounts and dates in: that of
+// -locale if given, which then must have one, or else "" for the layout's.
+// Labels can be translated into more languages than that.
+func (o *overlayOptions) formatLocale(flags commandFlags) string {
+	if !flags.isSet("locale") {
+		return ""
+	}
+	if _, err := paystub.LocaleNamed(o.locale); err != nil {
+		fatalf(exitUsage, "Invalid -locale for amounts and dates: %v\n", err)
+	}
+	return o.locale
+}
+
+// checkData checks the overlay flags that read paystub data against the
+// data flags d.
+func (o *overlayOptions) checkData(d *dataOptions) {
+	if d.rosterPath != "" && o.dataPath != "" {
+		fatalf(exitUsage, "-roster cannot be combined with -data\n")
+	}
+	if d.rosterPath == "-" && (o.jsonPath == "-" || o.pdfPath == "-") {
+		fatalf(exitUsage, "Only one of -roster, -json and -pdf can be read from stdin\n")
+	}
+}
+
+// outputOptions are the flags of what to write and how.
+type outputOptions struct {
+	outPath          string
+	maxOutputSize    int64
+	truthPath        string
+	workers          int
+	progressFormat   string
+	progressInterval time.Duration
+	corpusPath       string
+	resumePath       string
+	debug            bool
+	scanSpec         string
+	rasterizer       string
+	outFormat        string
+	dpi              int
+	userPassword     string
+	ownerPassword    string
+	perms            string
+	signCert         string
+	signKey          string
+	signReason       string
+	scrubMeta        bool
+	metaTitle        string
+	metaAuthor       string
+	metaSubject      string
+	metaCreator      string
+	metaProducer     string
+	metaDate         string
+	deterministic    bool
+	optimize         bool
+	linearize        bool
+	linearizer       string
+}
+
+func (o *outputOptions) define(fs *flag.FlagSet) {
+	fs.StringVar(&o.outPath, "out", "out.pdf", "Path to the output PDF file (- for stdout); for a batch, the output directory (default: next to each PDF)")
+	fs.Int64Var(&o.maxOutputSize, "max-output-size", 0, "Fail if an output PDF, as written, exceeds this many bytes even after optimizing (0 = no limit)")
+	fs.StringVar(&o.truthPath, "truth", "", "Also write the ground truth of each output PDF to this path (- for stdout): every text field with its final text, page and box in PDF points, as JSON, or as JSON Lines with one PDF per line when there are several")
+	fs.IntVar(&o.workers, "workers", runtime.NumCPU(), "Number of PDFs of a batch, or paystubs of -count, to process at once")
+	fs.StringVar(&o.progressFormat, "progress", "", "Report the progress of a batch on stderr: bar (redrawn in place, with the rate and time left) or json (an event of the counts, rate and time left per line)")
+	fs.DurationVar(&o.progressInterval, "progress-interval", time.Second, "How often -progress reports")
+	fs.StringVar(&o.corpusPath, "corpus-manifest", "", "Also write a manifest of the outputs of a batch to this path (- for stdout), as CSV if it ends in .csv and else JSON: each output with what it was made from, the seed and values of its data, and the SHA-256 of its files, and the command line and flag values of the run, so the corpus can be audited and any document made again")
+	fs.StringVar(&o.resumePath, "resume", "", "Record each output of a batch as it is finished in this JSON Lines journal, and skip the outputs it lists whose files are unchanged, so an interrupted batch can be resumed by running the same command again")
+	fs.BoolVar(&o.debug, "debug", false, "Enable debug logging")
+	fs.StringVar(&o.scanSpec, "scan", "", "Make each output look scanned: rasterize it and put it back with noise, slight rotation and skew, contrast changes, a shadow and JPEG compression; a preset (flatbed, fax, phone) or the path of JSON scan options. The output has no text layer left, and -truth boxes are those before the scan")
+	fs.StringVar(&o.rasterizer, "rasterizer", "pdftoppm", "Path of poppler's pdftoppm, which rasterizes the pages for -scan")
+	fs.StringVar(&o.outFormat, "out-format", "pdf", "Write each output as pdf, or render its pages with -rasterizer to png or jpeg (one file per page, numbered -1, -2, ... when there are several) or tiff (one multi-page file)")
+	fs.IntVar(&o.dpi, "dpi", 150, "Resolution of the -out-format images, in dots per inch")
+	fs.StringVar(&o.userPassword, "upw", "", "Encrypt each output PDF with AES-256 so it needs this password to open; needs -opw")
+	fs.StringVar(&o.ownerPassword, "opw", "", "Encrypt each output PDF with AES-256 with this owner password, which lifts the -perms restrictions")
+	fs.StringVar(&o.perms, "perms", "", "What readers without the -opw password may do with an encrypted output: all, none, or a comma-separated list of print, modify, extract, annotate, fill and assemble (default: print)")
+	fs.StringVar(&o.signCert, "sign-cert", "", "Digitally sign each output PDF with the certificate of this PEM file, such as a self-signed test certificate, and any intermediates after it; the key is read from -sign-key, or else from this file too")
+	fs.StringVar(&o.signKey, "sign-key", "", "PEM file of the RSA or ECDSA private key of -sign-cert")
+	fs.StringVar(&o.signReason, "sign-reason", "", "Reason for signing to record in the -sign-cert signature")
+	fs.BoolVar(&o.scrubMeta, "scrub-meta", false, "Drop the document info and XMP metadata (title, author, producer, dates, ...) of each output before setting the -meta-* fields")
+	fs.StringVar(&o.metaTitle, "meta-title", "", "Title to set in the metadata of each output PDF")
+	fs.StringVar(&o.metaAuthor, "meta-author", "", "Author to set in the metadata of each output PDF")
+	fs.StringVar(&o.metaSubject, "meta-subject", "", "Subject to set in the metadata of each output PDF")
+	fs.StringVar(&o.metaCreator, "meta-creator", "", "Creator (the application the document was made with) to set in the metadata of each output PDF")
+	fs.StringVar(&o.metaProducer, "meta-producer", "", "Producer to set in the metadata of each output PDF instead of pdfcpu's")
+	fs.StringVar(&o.metaDate, "meta-date", "", "Fixed creation and modification date of each output PDF, YYYY-MM-DD or RFC 3339, instead of the time it is written, for reproducible builds")
+	fs.BoolVar(&o.deterministic, "deterministic", false, "Make identical inputs give byte-identical outputs: date each output -meta-date (default: $SOURCE_DATE_EPOCH, or 1970-01-01), derive its file ID from its content, write its objects in order, and default -seed to 1")
+	fs.BoolVar(&o.optimize, "optimize", true, "Make each output PDF smaller: merge its duplicate fonts, images and forms, such as those repeated watermarking embeds again, drop what nothing uses, and compress the rest into object streams; -v logs the size before and after, and -optimize=false turns it off")
+	fs.BoolVar(&o.linearize, "linearize", false, "Linearize each output PDF for fast web view, with -linearizer, so a browser can show its first page before the rest is downloaded")
+	fs.StringVar(&o.linearizer, "linearizer", "qpdf", "Path of qpdf, which linearizes the outputs for -linearize")
+}
+
+// outputs are the output flags, checked, with what they ask to do to each
+// output.
+type outputs struct {
+	*outputOptions
+	flags         commandFlags
+	scan          *scanConfig         // nil if not
+	metadata      *overlay.Metadata   // nil if not
+	encryption    *overlay.Encryption // nil if not
+	signer        *overlay.Signer     // nil if not
+	linearizeWith overlay.Linearizer  // nil if not
+	images        *imageOutput        // nil for PDF
+}
+
+// check checks the output flags of flags, o, and reads the files they
+// name. Without -out, an image -out-format changes its extension.
+func (o *outputOptions) check(flags commandFlags) outputs {
+	out := outputs{outputOptions: o, flags: flags}
+	if o.scanSpec != "" {
+		s, err := readScan(o.scanSpec, o.rasterizer)
+		if err != nil {
+			fatalf(exitUsage, "Invalid -scan: %v\n", err)
+		}
+		out.scan = s
+	}
+
+	if o.ownerPassword != "" {
+		out.encryption = &overlay.Encryption{UserPassword: o.userPassword, OwnerPassword: o.ownerPassword, Permissions: o.perms}
+		if err := out.encryption.Validate(); err != nil {
+			fatalf(exitUsage, "Invalid -perms: %v\n", err)
+		}
+	} else if o.userPassword != "" || o.perms != "" {
+		fatalf(exitUsage, "-upw and -perms need -opw, the owner password\n")
+	}
+
+	if o.scrubMeta || o.metaTitle != "" || o.metaAuthor != "" || o.metaSubject != "" || o.metaCreator != "" || o.metaProducer != "" || o.metaDate != "" || o.deterministic {
+		out.metadata = &overlay.Metadata{Scrub: o.scrubMeta, Title: o.metaTitle, Author: o.metaAuthor, Subject: o.metaSubject, Creator: o.metaCreator, Producer: o.metaProducer, Reproducible: o.deterministic}
+		if o.metaDate != "" {
+			date, err := parseMetaDate(o.metaDate)
+			if err != nil {
+				fatalf(exitUsage, "Invalid -meta-date %q: want YYYY-MM-DD or an RFC 3339 time\n", o.metaDate)
+			}
+			out.metadata.Date = date
+		} else if o.deterministic {
+			out.metadata.Date = time.Unix(0, 0)
+			if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+				secs, err := strconv.ParseInt(epoch, 10, 64)
+				if err != nil {
+					fatalf(exitUsage, "Invalid SOURCE_DATE_EPOCH %q: want seconds since 1970\n", epoch)
+				}
+				out.metadata.Date = time.Unix(secs, 0)
+			}
+		}
+		if out.encryption != nil && (o.metaProducer != "" || o.metaDate != "" || o.deterministic) {
+			fatalf(exitUsage, "-opw writes pdfcpu's producer, the current time and random keys into the output, so it can't be used with -meta-producer, -meta-date or -deterministic\n")
+		}
+	}
+
+	if o.signCert != "" {
+		if out.encryption != nil {
+			fatalf(exitUsage, "-sign-cert cannot be combined with -opw: encrypting rewrites the signed PDF\n")
+		}
+		certPEM, err := os.ReadFile(o.signCert)
+		if err != nil {
+			fatalf(exitInput, "Could not read -sign-cert: %v\n", err)
+		}
+		keyPEM := certPEM
+		if o.signKey != "" {
+			if keyPEM, err = os.ReadFile(o.signKey); err != nil {
+				fatalf(exitInput, "Could not read -sign-key: %v\n", err)
+			}
+		}
+		if out.signer, err = overlay.LoadSigner(certPEM, keyPEM); err != nil {
+			fatalf(exitInput, "Invalid signing certificate: %v\n", err)
+		}
+		out.signer.Reason = o.signReason
+		// -meta-date and -deterministic date the signature too.
+		if out.metadata != nil {
+			out.signer.Time = out.metadata.Date
+		}
+	} else if o.signKey != "" || o.signReason != "" {
+		fatalf(exitUsage, "-sign-key and -sign-reason need -sign-cert\n")
+	}
+
+	if o.linearize {
+		if out.encryption != nil || out.signer != nil {
+			fatalf(exitUsage, "-linearize cannot be combined with -opw or -sign-cert: linearizing rewrites the encrypted or signed PDF\n")
+		}
+		if o.outFormat != "pdf" {
+			fatalf(exitUsage, "-linearize applies to PDF output only, not %s images\n", o.outFormat)
+		}
+		out.linearizeWith = overlay.Qpdf(o.linearizer)
+	}
+
+	if o.outFormat != "pdf" {
+		if out.encryption != nil {
+			fatalf(exitUsage, "-opw encrypts PDF output only, not %s images\n", o.outFormat)
+		}
+		if out.signer != nil {
+			fatalf(exitUsage, "-sign-cert signs PDF output only, not %s images\n", o.outFormat)
+		}
+		if o.outFormat == "jpg" {
+			o.outFormat = "jpeg"
+		}
+		if !slices.Contains(overlay.ImageFormats, o.outFormat) {
+			fatalf(exitUsage, "Invalid -out-format %q (valid: pdf, %s)\n", o.outFormat, strings.Join(overlay.ImageFormats, ", "))
+		}
+		out.images = &imageOutput{format: o.outFormat, dpi: o.dpi, rasterize: overlay.Pdftoppm(o.rasterizer)}
+		if !flags.isSet("out") {
+			o.outPath = out.images.name(o.outPath)
+		}
+	}
+	return out
+}
+
+// messages returns where to print messages of what succeeded: to stdout,
+// or to stderr if reported says stdout carries a report, or nowhere with
+// -q.
+func (o outputs) messages(reported bool) io.Writer {
+	switch {
+	case *o.flags.logs.quiet:
+		return io.Discard
+	case reported:
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// pattern returns the path to write each of copies outputs to, with
+// indexPlaceholder replaced by its number: -out, or by default
+// stub_{index}.pdf.
+func (o outputs) pattern(copies int) string {
+	pattern := o.images.name("stub_" + indexPlaceholder + ".pdf")
+	if o.flags.isSet("out") {
+		pattern = o.outPath
+	}
+	if copies > 1 && !strings.Contains(pattern, indexPlaceholder) {
+		fatalf(exitUsage, "-out %q needs %s to name each of the %d paystubs\n", pattern, indexPlaceholder, copies)
+	}
+	return pattern
+}
+
+// track starts following a batch of total outputs as -progress, -resume
+// and -corpus-manifest ask.
+func (o outputs) track(total int) tracking {
+	p, err := newProgress(o.progressFormat, total, o.progressInterval, os.Stderr)
+	if err != nil {
+		fatalf(exitUsage, "Invalid -progress: %v\n", err)
+	}
+	j, err := openJournal(o.resumePath, o.images)
+	if err != nil {
+		fatalf(exitInput, "Could not open -resume journal: %v\n", err)
+	}
+	t := tracking{progress: p, journal: j}
+	if o.corpusPath != "" {
+		params := map[string]string{}
+		o.flags.VisitAll(func(f *flag.Flag) { params[f.Name] = f.Value.String() })
+		t.corpus = &corpusWriter{path: o.corpusPath, args: os.Args[1:], parameters: params, images: o.images}
+	}
+	return t
+}
+
+// dataOptions are the flags of made-up and roster paystub data.
+type dataOptions struct {
+	fake        bool
+	seed        uint64
+	count       int
+	rosterPath  string
+	series      int
+	seriesStart string
+	frequency   string
+	ratesPath   string
+}
+
+func (d *dataOptions) define(fs *flag.FlagSet) {
+	fs.BoolVar(&d.fake, "fake", false, "Like -generate, but make up realistic paystub data instead of reading it; with -json, fill in the overlay text placeholders from it instead; see -seed")
+	fs.Uint64Var(&d.seed, "seed", 0, "Seed of the -fake data, so runs can be reproduced (0 = pick one and log it)")
+	fs.IntVar(&d.count, "count", 1, "With -fake, generate this many paystubs (or, with -json, overlaid copies of -pdf) from consecutive seeds, writing each to -out with {index} replaced by its number (default stub_{index}.pdf)")
+	fs.StringVar(&d.rosterPath, "roster", "", "Path to a CSV roster (- for stdin) with one employee per row; each row makes one paystub, drawn with -layout or, with -json, filling in the overlay text placeholders, written to -out with {index} replaced by the row number")
+	fs.IntVar(&d.series, "series", 0, "With -fake, make this many consecutive pay stubs for one employee, with year-to-date amounts that add up, written to -out with {index} replaced by the period number")
+	fs.StringVar(&d.seriesStart, "series-start", "2025-01-01", "First day of the first pay period of -series (YYYY-MM-DD)")
+	fs.StringVar(&d.frequency, "frequency", "biweekly", "Pay frequency of -series: weekly, biweekly, semimonthly or monthly")
+	fs.StringVar(&d.ratesPath, "rates", "", "Path to JSON tax rates for the withholding of -fake data (Social Security, Medicare, federal brackets, state rates); default: 2025 US rates")
+}
+
+// paystubData is the paystub data the data flags ask for.
+type paystubData struct {
+	rates paystub.Rates
+	// stubs are the paystubs of -roster or -series, one per output; nil
+	// if neither.
+	stubs []paystub.Paystub
+	// statements and advices are the bank statements and direct-deposit
+	// advices that go with -series.
+	statements []paystub.BankStatement
+	advices    []paystub.DepositAdvice
+	copies     int // the number of paystubs: -count, or one per stub
+}
+
+// load checks the data flags d and reads or makes up the data they ask
+// for. Without -seed, -fake data is seeded by the time, or with 1 if
+// deterministic.
+func (d *dataOptions) load(deterministic bool) paystubData {
+	if d.fake && d.seed == 0 {
+		if deterministic {
+			d.seed = 1
+		} else {
+			d.seed = uint64(time.Now().UnixNano())
+			slog.Info("Using -seed", "seed", d.seed)
+		}
+	}
+	rates, err := readRates(d.ratesPath)
+	if err != nil {
+		fatalf(exitInput, "Could not read tax rates: %v\n", err)
+	}
+	data := paystubData{rates: rates, copies: d.count}
+
+	if d.rosterPath != "" {
+		if d.fake {
+			fatalf(exitUsage, "-roster cannot be combined with -fake\n")
+		}
+		if d.count > 1 {
+			fatalf(exitUsage, "-count does not apply to -roster, which makes one paystub per row\n")
+		}
+		if data.stubs, err = readRoster(d.rosterPath); err != nil {
+			fatalf(exitInput, "Could not read roster: %v\n", err)
+		}
+	}
+	if d.series > 0 {
+		if !d.fake {
+			fatalf(exitUsage, "-series needs -fake\n")
+		}
+		if d.count > 1 {
+			fatalf(exitUsage, "-series cannot be combined with -count\n")
+		}
+		start, err := time.Parse("2006-01-02", d.seriesStart)
+		if err != nil {
+			fatalf(exitUsage, "Invalid -series-start %q: want YYYY-MM-DD\n", d.seriesStart)
+		}
+		freq, err := paystub.ParseFrequency(d.frequency)
+		if err != nil {
+			fatalf(exitUsage, "Invalid -frequency: %v\n", err)
+		}
+		faker := newFaker(d.seed, rates)
+		if data.stubs, err = faker.Series(start, freq, d.series); err != nil {
+			fatalf(exitStatus(err), "Generating series failed: %v\n", err)
+		}
+		// Both are made whichever is asked for, so each comes out the
+		// same either way.
+		account := faker.BankAccount()
+		data.statements = faker.BankStatements(data.stubs, account)
+		data.advices = faker.DepositAdvices(data.stubs, account)
+	}
+	if data.stubs != nil {
+		data.copies = len(data.stubs)
+	}
+	return data
+}
+
+// generateOptions are the flags of paystubs built from scratch.
+type generateOptions struct {
+	dataPath       string // the paystub data of -generate
+	layoutPath     string
+	layoutTemplate string
+	taxForm        string
+	taxFormOut     string
+	bankStatements bool
+	statementOut   string
+	depositAdvice  bool
+	adviceOut      string
+	appendTo       string
+	mergePath      string
+	evilSpec       string
+}
+
+func (g *generateOptions) define(fs *flag.FlagSet) {
+	fs.StringVar(&g.dataPath, "generate", "", "Instead of modifying a PDF, build a paystub from scratch from this JSON data file (- for stdin) and write it to -out")
+	fs.StringVar(&g.taxForm, "tax-form", "", "With -series, also make this year-end tax form, w2 or 1099-nec, for each calendar year of the series, with amounts that reconcile with its year-to-date totals")
+	fs.StringVar(&g.taxFormOut, "tax-form-out", "", "Where -tax-form writes each form, with {year} replaced by its year (default <form>_{year}.pdf)")
+	fs.BoolVar(&g.bankStatements, "bank-statements", false, "With -series, also make a monthly bank statement for each month of the series, whose ledger deposits the net pay of each paystub on its pay date")
+	fs.StringVar(&g.statementOut, "bank-statement-out", "", "Where -bank-statements writes each statement, with {month} replaced by its month as YYYY-MM (default statement_{month}.pdf)")
+	fs.BoolVar(&g.depositAdvice, "deposit-advice", false, "With -series, also make the direct-deposit advice of each paystub, depositing its net pay into the account of -bank-statements")
+	fs.StringVar(&g.adviceOut, "deposit-advice-out", "", "Where -deposit-advice writes each advice, with {index} replaced by the period number (default advice_{index}.pdf)")
+	fs.StringVar(&g.layoutPath, "layout", "", "Path to a JSON layout for -generate (page size, margin, fonts, title, accent colour, sections); fields it leaves out come from -template, or else a US Letter earnings statement")
+	fs.StringVar(&g.appendTo, "append-to", "", "Append the pages of each generated document to those of this existing PDF, writing the whole to its output; -truth numbers its pages after the existing ones")
+	fs.StringVar(&g.mergePath, "merge", "", "Also write the documents of a -count, -series or -roster batch, in order, to this path (- for stdout) as one PDF")
+	fs.StringVar(&g.evilSpec, "evil", "", "Make each generated paystub inconsistent on purpose, for negative testing, as a comma-separated list of: totals (print a net pay 100.00 more than the earnings less the deductions) and after-signing (change the net pay to 1,000.00 more in an update appended after -sign-cert signed it); -truth and -corpus-manifest label the paystubs with them")
+	fs.StringVar(&g.layoutTemplate, "template", "", "Built-in layout for -generate, approximating the style of a kind of payroll provider: "+strings.Join(paystub.LayoutNames(), ", "))
+}
+
+// extras reports whether g asks for documents that go with a -series.
+func (g *generateOptions) extras() bool {
+	return g.taxForm != "" || g.bankStatements || g.depositAdvice
+}
+
+// serverOptions are the flags of the -grpc and -serve servers.
+type serverOptions struct {
+	grpcAddr  string
+	serveAddr string
+	maxUpload int64
+}
+
+func (s *serverOptions) define(fs *flag.FlagSet) {
+	fs.StringVar(&s.grpcAddr, "grpc", "", "Serve the overlay gRPC service on this address (e.g. :50051) instead of processing files")
+	fs.StringVar(&s.serveAddr, "serve", "", "Serve POST /overlay and POST /v1/paystubs over HTTP on this address (e.g. :8080) instead of processing files; same as the serve subcommand, whose help tells what requests may hold")
+	fs.Int64Var(&s.maxUpload, "max-upload", 64<<20, "Largest request body -serve accepts, in bytes")
+}
+
+// configFlags are the names of the flags of every command that reads the
+// config file, which may set any of them whichever command runs.
+func configFlags() map[string]bool {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	new(overlayOptions).define(fs)
+	new(outputOptions).define(fs)
+	new(dataOptions).define(fs)
+	new(generateOptions).define(fs)
+	new(serverOptions).define(fs)
+	names := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	return names
+}
@@ -0,0 +1,4 @@
+// Package overlaypb holds the generated gRPC bindings for the overlay service.
+package overlaypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative overlay.proto
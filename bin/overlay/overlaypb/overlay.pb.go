@@ -0,0 +1,504 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.28.3
+// source: overlay.proto
+
+package overlaypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Overlay mirrors overlay.OverlayRectText: a filled rectangle with text on top.
+type Overlay struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	X             float64                `protobuf:"fixed64,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,3,opt,name=y,proto3" json:"y,omitempty"`
+	Width         float64                `protobuf:"fixed64,4,opt,name=width,proto3" json:"width,omitempty"`   // rectangle width in PDF points
+	Height        float64                `protobuf:"fixed64,5,opt,name=height,proto3" json:"height,omitempty"` // rectangle height in PDF points
+	Scale         float64                `protobuf:"fixed64,6,opt,name=scale,proto3" json:"scale,omitempty"`
+	Anchor        string                 `protobuf:"bytes,7,opt,name=anchor,proto3" json:"anchor,omitempty"`                                 // pdfcpu position anchor, defaults to "bl"
+	Ops           string                 `protobuf:"bytes,8,opt,name=ops,proto3" json:"ops,omitempty"`                                       // raw content-stream operators drawn in the width x height box
+	FillColor     string                 `protobuf:"bytes,9,opt,name=fill_color,json=fillColor,proto3" json:"fill_color,omitempty"`          // rectangle colour as "#RRGGBB" or a name, defaults to white
+	TextColor     string                 `protobuf:"bytes,10,opt,name=text_color,json=textColor,proto3" json:"text_color,omitempty"`         // text colour as "#RRGGBB" or a name, defaults to black
+	Font          string                 `protobuf:"bytes,11,opt,name=font,proto3" json:"font,omitempty"`                                    // pdfcpu font name, defaults to pdfcpu's default
+	Pages         string                 `protobuf:"bytes,12,opt,name=pages,proto3" json:"pages,omitempty"`                                  // pdfcpu page selection, defaults to every page
+	Origin        string                 `protobuf:"bytes,13,opt,name=origin,proto3" json:"origin,omitempty"`                                // "bl" or "tl" coordinate origin, defaults to "bl"
+	Wrap          bool                   `protobuf:"varint,14,opt,name=wrap,proto3" json:"wrap,omitempty"`                                   // wrap text to width, drawing it line by line
+	AutoFit       bool                   `protobuf:"varint,15,opt,name=auto_fit,json=autoFit,proto3" json:"auto_fit,omitempty"`              // size text to fit the width x height box, ignoring scale
+	Rotation      float64                `protobuf:"fixed64,16,opt,name=rotation,proto3" json:"rotation,omitempty"`                          // degrees counterclockwise around the anchor point
+	Units         string                 `protobuf:"bytes,17,opt,name=units,proto3" json:"units,omitempty"`                                  // "pt", "in", "mm" or "percent" of the page size, defaults to "pt"
+	Opacity       float64                `protobuf:"fixed64,18,opt,name=opacity,proto3" json:"opacity,omitempty"`                            // 0 to 1, 0 (unset) means fully opaque
+	Redact        bool                   `protobuf:"varint,19,opt,name=redact,proto3" json:"redact,omitempty"`                               // remove what lies under the rectangle instead of only covering it
+	FontFile      string                 `protobuf:"bytes,20,opt,name=font_file,json=fontFile,proto3" json:"font_file,omitempty"`            // refused: the server installs no font files for callers
+	ImagePath     string                 `protobuf:"bytes,21,opt,name=image_path,json=imagePath,proto3" json:"image_path,omitempty"`         // refused: the server reads no image files for callers
+	BorderColor   string                 `protobuf:"bytes,22,opt,name=border_color,json=borderColor,proto3" json:"border_color,omitempty"`   // rectangle border colour as "#RRGGBB" or a name, defaults to no border
+	BorderWidth   float64                `protobuf:"fixed64,23,opt,name=border_width,json=borderWidth,proto3" json:"border_width,omitempty"` // border width in points, defaults to 1 with a border_color
+	TextScale     float64                `protobuf:"fixed64,24,opt,name=text_scale,json=textScale,proto3" json:"text_scale,omitempty"`       // text size independent of scale, defaults to scale/4
+	FontSize      int32                  `protobuf:"varint,25,opt,name=font_size,json=fontSize,proto3" json:"font_size,omitempty"`           // text size in points, overrides text_scale
+	Bold          bool                   `protobuf:"varint,26,opt,name=bold,proto3" json:"bold,omitempty"`                                   // bold face of a Helvetica, Times or Courier font
+	Italic        bool                   `protobuf:"varint,27,opt,name=italic,proto3" json:"italic,omitempty"`                               // italic face of a Helvetica, Times or Courier font
+	LineSpacing   float64                `protobuf:"fixed64,28,opt,name=line_spacing,json=lineSpacing,proto3" json:"line_spacing,omitempty"` // distance between text lines in line heights, defaults to 1
+	Align         string                 `protobuf:"bytes,29,opt,name=align,proto3" json:"align,omitempty"`                                  // "left", "center" or "right" in the rectangle
+	Valign        string                 `protobuf:"bytes,30,opt,name=valign,proto3" json:"valign,omitempty"`                                // "top", "middle" or "bottom" in the rectangle
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Overlay) Reset() {
+	*x = Overlay{}
+	mi := &file_overlay_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Overlay) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Overlay) ProtoMessage() {}
+
+func (x *Overlay) ProtoReflect() protoreflect.Message {
+	mi := &file_overlay_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Overlay.ProtoReflect.Descriptor instead.
+func (*Overlay) Descriptor() ([]byte, []int) {
+	return file_overlay_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Overlay) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Overlay) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Overlay) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Overlay) GetWidth() float64 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Overlay) GetHeight() float64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Overlay) GetScale() float64 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+func (x *Overlay) GetAnchor() string {
+	if x != nil {
+		return x.Anchor
+	}
+	return ""
+}
+
+func (x *Overlay) GetOps() string {
+	if x != nil {
+		return x.Ops
+	}
+	return ""
+}
+
+func (x *Overlay) GetFillColor() string {
+	if x != nil {
+		return x.FillColor
+	}
+	return ""
+}
+
+func (x *Overlay) GetTextColor() string {
+	if x != nil {
+		return x.TextColor
+	}
+	return ""
+}
+
+func (x *Overlay) GetFont() string {
+	if x != nil {
+		return x.Font
+	}
+	return ""
+}
+
+func (x *Overlay) GetPages() string {
+	if x != nil {
+		return x.Pages
+	}
+	return ""
+}
+
+func (x *Overlay) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *Overlay) GetWrap() bool {
+	if x != nil {
+		return x.Wrap
+	}
+	return false
+}
+
+func (x *Overlay) GetAutoFit() bool {
+	if x != nil {
+		return x.AutoFit
+	}
+	return false
+}
+
+func (x *Overlay) GetRotation() float64 {
+	if x != nil {
+		return x.Rotation
+	}
+	return 0
+}
+
+func (x *Overlay) GetUnits() string {
+	if x != nil {
+		return x.Units
+	}
+	return ""
+}
+
+func (x *Overlay) GetOpacity() float64 {
+	if x != nil {
+		return x.Opacity
+	}
+	return 0
+}
+
+func (x *Overlay) GetRedact() bool {
+	if x != nil {
+		return x.Redact
+	}
+	return false
+}
+
+func (x *Overlay) GetFontFile() string {
+	if x != nil {
+		return x.FontFile
+	}
+	return ""
+}
+
+func (x *Overlay) GetImagePath() string {
+	if x != nil {
+		return x.ImagePath
+	}
+	return ""
+}
+
+func (x *Overlay) GetBorderColor() string {
+	if x != nil {
+		return x.BorderColor
+	}
+	return ""
+}
+
+func (x *Overlay) GetBorderWidth() float64 {
+	if x != nil {
+		return x.BorderWidth
+	}
+	return 0
+}
+
+func (x *Overlay) GetTextScale() float64 {
+	if x != nil {
+		return x.TextScale
+	}
+	return 0
+}
+
+func (x *Overlay) GetFontSize() int32 {
+	if x != nil {
+		return x.FontSize
+	}
+	return 0
+}
+
+func (x *Overlay) GetBold() bool {
+	if x != nil {
+		return x.Bold
+	}
+	return false
+}
+
+func (x *Overlay) GetItalic() bool {
+	if x != nil {
+		return x.Italic
+	}
+	return false
+}
+
+func (x *Overlay) GetLineSpacing() float64 {
+	if x != nil {
+		return x.LineSpacing
+	}
+	return 0
+}
+
+func (x *Overlay) GetAlign() string {
+	if x != nil {
+		return x.Align
+	}
+	return ""
+}
+
+func (x *Overlay) GetValign() string {
+	if x != nil {
+		return x.Valign
+	}
+	return ""
+}
+
+type ApplyOverlaysRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pdf holds the whole source PDF, or the next chunk of it when streaming.
+	Pdf []byte `protobuf:"bytes,1,opt,name=pdf,proto3" json:"pdf,omitempty"`
+	// overlays are accumulated across all messages of a stream.
+	Overlays      []*Overlay `protobuf:"bytes,2,rep,name=overlays,proto3" json:"overlays,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyOverlaysRequest) Reset() {
+	*x = ApplyOverlaysRequest{}
+	mi := &file_overlay_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyOverlaysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyOverlaysRequest) ProtoMessage() {}
+
+func (x *ApplyOverlaysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_overlay_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyOverlaysRequest.ProtoReflect.Descriptor instead.
+func (*ApplyOverlaysRequest) Descriptor() ([]byte, []int) {
+	return file_overlay_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ApplyOverlaysRequest) GetPdf() []byte {
+	if x != nil {
+		return x.Pdf
+	}
+	return nil
+}
+
+func (x *ApplyOverlaysRequest) GetOverlays() []*Overlay {
+	if x != nil {
+		return x.Overlays
+	}
+	return nil
+}
+
+type ApplyOverlaysResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pdf holds the whole result PDF, or the next chunk of it when streaming.
+	Pdf           []byte `protobuf:"bytes,1,opt,name=pdf,proto3" json:"pdf,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyOverlaysResponse) Reset() {
+	*x = ApplyOverlaysResponse{}
+	mi := &file_overlay_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyOverlaysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyOverlaysResponse) ProtoMessage() {}
+
+func (x *ApplyOverlaysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_overlay_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyOverlaysResponse.ProtoReflect.Descriptor instead.
+func (*ApplyOverlaysResponse) Descriptor() ([]byte, []int) {
+	return file_overlay_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ApplyOverlaysResponse) GetPdf() []byte {
+	if x != nil {
+		return x.Pdf
+	}
+	return nil
+}
+
+var File_overlay_proto protoreflect.FileDescriptor
+
+const file_overlay_proto_rawDesc = "" +
+	"\n" +
+	"\roverlay.proto\x12\n" +
+	"overlay.v1\"\xf5\x05\n" +
+	"\aOverlay\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\f\n" +
+	"\x01x\x18\x02 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x03 \x01(\x01R\x01y\x12\x14\n" +
+	"\x05width\x18\x04 \x01(\x01R\x05width\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x01R\x06height\x12\x14\n" +
+	"\x05scale\x18\x06 \x01(\x01R\x05scale\x12\x16\n" +
+	"\x06anchor\x18\a \x01(\tR\x06anchor\x12\x10\n" +
+	"\x03ops\x18\b \x01(\tR\x03ops\x12\x1d\n" +
+	"\n" +
+	"fill_color\x18\t \x01(\tR\tfillColor\x12\x1d\n" +
+	"\n" +
+	"text_color\x18\n" +
+	" \x01(\tR\ttextColor\x12\x12\n" +
+	"\x04font\x18\v \x01(\tR\x04font\x12\x14\n" +
+	"\x05pages\x18\f \x01(\tR\x05pages\x12\x16\n" +
+	"\x06origin\x18\r \x01(\tR\x06origin\x12\x12\n" +
+	"\x04wrap\x18\x0e \x01(\bR\x04wrap\x12\x19\n" +
+	"\bauto_fit\x18\x0f \x01(\bR\aautoFit\x12\x1a\n" +
+	"\brotation\x18\x10 \x01(\x01R\brotation\x12\x14\n" +
+	"\x05units\x18\x11 \x01(\tR\x05units\x12\x18\n" +
+	"\aopacity\x18\x12 \x01(\x01R\aopacity\x12\x16\n" +
+	"\x06redact\x18\x13 \x01(\bR\x06redact\x12\x1b\n" +
+	"\tfont_file\x18\x14 \x01(\tR\bfontFile\x12\x1d\n" +
+	"\n" +
+	"image_path\x18\x15 \x01(\tR\timagePath\x12!\n" +
+	"\fborder_color\x18\x16 \x01(\tR\vborderColor\x12!\n" +
+	"\fborder_width\x18\x17 \x01(\x01R\vborderWidth\x12\x1d\n" +
+	"\n" +
+	"text_scale\x18\x18 \x01(\x01R\ttextScale\x12\x1b\n" +
+	"\tfont_size\x18\x19 \x01(\x05R\bfontSize\x12\x12\n" +
+	"\x04bold\x18\x1a \x01(\bR\x04bold\x12\x16\n" +
+	"\x06italic\x18\x1b \x01(\bR\x06italic\x12!\n" +
+	"\fline_spacing\x18\x1c \x01(\x01R\vlineSpacing\x12\x14\n" +
+	"\x05align\x18\x1d \x01(\tR\x05align\x12\x16\n" +
+	"\x06valign\x18\x1e \x01(\tR\x06valign\"Y\n" +
+	"\x14ApplyOverlaysRequest\x12\x10\n" +
+	"\x03pdf\x18\x01 \x01(\fR\x03pdf\x12/\n" +
+	"\boverlays\x18\x02 \x03(\v2\x13.overlay.v1.OverlayR\boverlays\")\n" +
+	"\x15ApplyOverlaysResponse\x12\x10\n" +
+	"\x03pdf\x18\x01 \x01(\fR\x03pdf2\xc6\x01\n" +
+	"\x0eOverlayService\x12T\n" +
+	"\rApplyOverlays\x12 .overlay.v1.ApplyOverlaysRequest\x1a!.overlay.v1.ApplyOverlaysResponse\x12^\n" +
+	"\x13ApplyOverlaysStream\x12 .overlay.v1.ApplyOverlaysRequest\x1a!.overlay.v1.ApplyOverlaysResponse(\x010\x01B>Z<github.com/StCredZero/paystub-test-gen/bin/overlay/overlaypbb\x06proto3"
+
+var (
+	file_overlay_proto_rawDescOnce sync.Once
+	file_overlay_proto_rawDescData []byte
+)
+
+func file_overlay_proto_rawDescGZIP() []byte {
+	file_overlay_proto_rawDescOnce.Do(func() {
+		file_overlay_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_overlay_proto_rawDesc), len(file_overlay_proto_rawDesc)))
+	})
+	return file_overlay_proto_rawDescData
+}
+
+var file_overlay_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_overlay_proto_goTypes = []any{
+	(*Overlay)(nil),               // 0: overlay.v1.Overlay
+	(*ApplyOverlaysRequest)(nil),  // 1: overlay.v1.ApplyOverlaysRequest
+	(*ApplyOverlaysResponse)(nil), // 2: overlay.v1.ApplyOverlaysResponse
+}
+var file_overlay_proto_depIdxs = []int32{
+	0, // 0: overlay.v1.ApplyOverlaysRequest.overlays:type_name -> overlay.v1.Overlay
+	1, // 1: overlay.v1.OverlayService.ApplyOverlays:input_type -> overlay.v1.ApplyOverlaysRequest
+	1, // 2: overlay.v1.OverlayService.ApplyOverlaysStream:input_type -> overlay.v1.ApplyOverlaysRequest
+	2, // 3: overlay.v1.OverlayService.ApplyOverlays:output_type -> overlay.v1.ApplyOverlaysResponse
+	2, // 4: overlay.v1.OverlayService.ApplyOverlaysStream:output_type -> overlay.v1.ApplyOverlaysResponse
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_overlay_proto_init() }
+func file_overlay_proto_init() {
+	if File_overlay_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_overlay_proto_rawDesc), len(file_overlay_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_overlay_proto_goTypes,
+		DependencyIndexes: file_overlay_proto_depIdxs,
+		MessageInfos:      file_overlay_proto_msgTypes,
+	}.Build()
+	File_overlay_proto = out.File
+	file_overlay_proto_goTypes = nil
+	file_overlay_proto_depIdxs = nil
+}
@@ -0,0 +1,160 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: overlay.proto
+
+package overlaypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OverlayService_ApplyOverlays_FullMethodName       = "/overlay.v1.OverlayService/ApplyOverlays"
+	OverlayService_ApplyOverlaysStream_FullMethodName = "/overlay.v1.OverlayService/ApplyOverlaysStream"
+)
+
+// OverlayServiceClient is the client API for OverlayService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OverlayServiceClient interface {
+	// ApplyOverlays applies overlays to a PDF sent in a single message.
+	ApplyOverlays(ctx context.Context, in *ApplyOverlaysRequest, opts ...grpc.CallOption) (*ApplyOverlaysResponse, error)
+	// ApplyOverlaysStream accepts the source PDF in chunks and streams the
+	// result back in chunks, for files larger than the gRPC message limit.
+	ApplyOverlaysStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ApplyOverlaysRequest, ApplyOverlaysResponse], error)
+}
+
+type overlayServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOverlayServiceClient(cc grpc.ClientConnInterface) OverlayServiceClient {
+	return &overlayServiceClient{cc}
+}
+
+func (c *overlayServiceClient) ApplyOverlays(ctx context.Context, in *ApplyOverlaysRequest, opts ...grpc.CallOption) (*ApplyOverlaysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyOverlaysResponse)
+	err := c.cc.Invoke(ctx, OverlayService_ApplyOverlays_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *overlayServiceClient) ApplyOverlaysStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ApplyOverlaysRequest, ApplyOverlaysResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OverlayService_ServiceDesc.Streams[0], OverlayService_ApplyOverlaysStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ApplyOverlaysRequest, ApplyOverlaysResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OverlayService_ApplyOverlaysStreamClient = grpc.BidiStreamingClient[ApplyOverlaysRequest, ApplyOverlaysResponse]
+
+// OverlayServiceServer is the server API for OverlayService service.
+// All implementations must embed UnimplementedOverlayServiceServer
+// for forward compatibility.
+type OverlayServiceServer interface {
+	// ApplyOverlays applies overlays to a PDF sent in a single message.
+	ApplyOverlays(context.Context, *ApplyOverlaysRequest) (*ApplyOverlaysResponse, error)
+	// ApplyOverlaysStream accepts the source PDF in chunks and streams the
+	// result back in chunks, for files larger than the gRPC message limit.
+	ApplyOverlaysStream(grpc.BidiStreamingServer[ApplyOverlaysRequest, ApplyOverlaysResponse]) error
+	mustEmbedUnimplementedOverlayServiceServer()
+}
+
+// UnimplementedOverlayServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOverlayServiceServer struct{}
+
+func (UnimplementedOverlayServiceServer) ApplyOverlays(context.Context, *ApplyOverlaysRequest) (*ApplyOverlaysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyOverlays not implemented")
+}
+func (UnimplementedOverlayServiceServer) ApplyOverlaysStream(grpc.BidiStreamingServer[ApplyOverlaysRequest, ApplyOverlaysResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ApplyOverlaysStream not implemented")
+}
+func (UnimplementedOverlayServiceServer) mustEmbedUnimplementedOverlayServiceServer() {}
+func (UnimplementedOverlayServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeOverlayServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OverlayServiceServer will
+// result in compilation errors.
+type UnsafeOverlayServiceServer interface {
+	mustEmbedUnimplementedOverlayServiceServer()
+}
+
+func RegisterOverlayServiceServer(s grpc.ServiceRegistrar, srv OverlayServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOverlayServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OverlayService_ServiceDesc, srv)
+}
+
+func _OverlayService_ApplyOverlays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyOverlaysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OverlayServiceServer).ApplyOverlays(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OverlayService_ApplyOverlays_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OverlayServiceServer).ApplyOverlays(ctx, req.(*ApplyOverlaysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OverlayService_ApplyOverlaysStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OverlayServiceServer).ApplyOverlaysStream(&grpc.GenericServerStream[ApplyOverlaysRequest, ApplyOverlaysResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OverlayService_ApplyOverlaysStreamServer = grpc.BidiStreamingServer[ApplyOverlaysRequest, ApplyOverlaysResponse]
+
+// OverlayService_ServiceDesc is the grpc.ServiceDesc for OverlayService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OverlayService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "overlay.v1.OverlayService",
+	HandlerType: (*OverlayServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ApplyOverlays",
+			Handler:    _OverlayService_ApplyOverlays_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ApplyOverlaysStream",
+			Handler:       _OverlayService_ApplyOverlaysStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "overlay.proto",
+}
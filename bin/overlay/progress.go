@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of cells of the -progress bar.
+const progressBarWidth = 30
+
+// progress reports how far a batch has got to w every interval, as a bar
+// redrawn in place or as JSON events, one per line. A nil progress reports
+// nothing.
+type progress struct {
+	w        io.Writer
+	json     bool
+	interval time.Duration
+	start    time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+
+	mu                           sync.Mutex
+	total, done, failed, skipped int
+}
+
+// progressEvent is a JSON -progress event.
+type progressEvent struct {
+	Done    int     `json:"done"` // finished jobs, failed and skipped ones included
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"` // finished by an earlier run, see -resume
+	Total   int     `json:"total"`
+	Elapsed float64 `json:"elapsedSeconds"`
+	Rate    float64 `json:"perSecond"` // jobs done in this run per second
+	ETA     float64 `json:"etaSeconds"`
+	Final   bool    `json:"final,omitempty"` // the batch is over
+}
+
+// newProgress starts reporting the progress of a batch of total jobs in
+// format, bar or json, to w every interval. An empty format reports
+// nothing and returns nil.
+func newProgress(format string, total int, interval time.Duration, w io.Writer) (*progress, error) {
+	if format == "" {
+		return nil, nil
+	}
+	if format != "bar" && format != "json" {
+		return nil, fmt.Errorf("unknown format %q (valid: bar, json)", format)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %v", interval)
+	}
+	p := &progress{w: w, json: format == "json", interval: interval, start: time.Now(), total: total,
+		stop: make(chan struct{}), stopped: make(chan struct{})}
+	go p.run()
+	return p, nil
+}
+
+// run reports every interval until p is finished.
+func (p *progress) run() {
+	defer close(p.stopped)
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.report(false)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// add counts r as done.
+func (p *progress) add(r batchResult) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	switch {
+	case r.err != nil:
+		p.failed++
+	case r.skipped:
+		p.skipped++
+	}
+}
+
+// finish stops reporting after a last report.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	p.report(true)
+}
+
+// report writes the progress so far, the final one if final is set.
+func (p *progress) report(final bool) {
+	p.mu.Lock()
+	e := progressEvent{Done: p.done, Failed: p.failed, Skipped: p.skipped, Total: p.total,
+		Elapsed: time.Since(p.start).Seconds(), Final: final}
+	p.mu.Unlock()
+	// Skipped jobs take no time, so they don't count towards the rate.
+	if worked := e.Done - e.Skipped; worked > 0 && e.Elapsed > 0 {
+		e.Rate = float64(worked) / e.Elapsed
+		e.ETA = float64(e.Total-e.Done) / e.Rate
+	}
+	if p.json {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(p.w, "%s\n", data)
+		return
+	}
+	filled := progressBarWidth
+	if e.Total > 0 {
+		filled = progressBarWidth * e.Done / e.Total
+	}
+	line := fmt.Sprintf("\r[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat(" ", progressBarWidth-filled), e.Done, e.Total)
+	if e.Failed > 0 {
+		line += fmt.Sprintf(", %d failed", e.Failed)
+	}
+	if e.Skipped > 0 {
+		line += fmt.Sprintf(", %d skipped", e.Skipped)
+	}
+	if e.Rate > 0 {
+		line += fmt.Sprintf(", %.1f/s", e.Rate)
+		if !final {
+			line += fmt.Sprintf(", %s left", (time.Duration(e.ETA) * time.Second).Round(time.Second))
+		}
+	}
+	// Pad over the end of a longer earlier line.
+	line = fmt.Sprintf("%-70s", line)
+	if final {
+		line += "\n"
+	}
+	fmt.Fprint(p.w, line)
+}
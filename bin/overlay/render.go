@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// imageOutput says how -out-format renders each output PDF to images
+// instead of writing it.
+type imageOutput struct {
+	format    string // one of overlay.ImageFormats
+	dpi       int
+	rasterize overlay.Rasterizer
+}
+
+// imageExts are the file extensions of overlay.ImageFormats.
+var imageExts = map[string]string{"png": ".png", "jpeg": ".jpg", "tiff": ".tif"}
+
+// name returns path with a .pdf extension replaced by that of o's format,
+// for output names the user didn't give. A nil o leaves path as it is.
+func (o *imageOutput) name(path string) string {
+	if o == nil || !strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return path
+	}
+	return path[:len(path)-len(".pdf")] + imageExts[o.format]
+}
+
+// write writes pdf to path, or to stdout when path is "-", rendering it to
+// images first if o is not nil. The images of a document with several pages
+// are written to path with -1, -2 and so on before the extension, except
+// for TIFF, which holds every page in one file.
+func (o *imageOutput) write(path string, pdf []byte) error {
+	if o == nil {
+		return writeOutput(path, pdf)
+	}
+	images, err := overlay.RenderImages(pdf, o.format, o.dpi, o.rasterize)
+	if err != nil {
+		return fmt.Errorf("rendering %s: %w", o.format, err)
+	}
+	if len(images) == 1 {
+		return writeOutput(path, images[0])
+	}
+	if path == "-" {
+		return fmt.Errorf("cannot write %d %s pages to stdout; use tiff or write to a file", len(images), o.format)
+	}
+	ext := filepath.Ext(path)
+	for i, img := range images {
+		if err := writeOutput(path[:len(path)-len(ext)]+"-"+strconv.Itoa(i+1)+ext, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// files returns the paths of the files write wrote for path: path itself,
+// or the numbered images of its pages.
+func (o *imageOutput) files(path string) []string {
+	if _, err := os.Stat(path); err == nil || o == nil {
+		return []string{path}
+	}
+	var paths []string
+	ext := filepath.Ext(path)
+	for i := 1; ; i++ {
+		page := path[:len(path)-len(ext)] + "-" + strconv.Itoa(i) + ext
+		if _, err := os.Stat(page); err != nil {
+			return paths
+		}
+		paths = append(paths, page)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// scanConfig says how -scan degrades each output PDF.
+type scanConfig struct {
+	opts      overlay.ScanOptions
+	rasterize overlay.Rasterizer
+}
+
+// readScan returns the scanConfig of a -scan value, either the name of an
+// overlay.ScanPresets entry or the path of JSON ScanOptions, which default to
+// the flatbed preset. Pages are rasterized with the pdftoppm at rasterizer.
+func readScan(spec, rasterizer string) (*scanConfig, error) {
+	s := &scanConfig{rasterize: overlay.Pdftoppm(rasterizer)}
+	if preset, ok := overlay.ScanPresets[spec]; ok {
+		s.opts = preset
+		return s, nil
+	}
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		var names []string
+		for name := range overlay.ScanPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%v (presets: %s)", err, strings.Join(names, ", "))
+	}
+	s.opts = overlay.ScanPresets["flatbed"]
+	if err := json.Unmarshal(data, &s.opts); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", spec, err)
+	}
+	return s, nil
+}
+
+// apply returns pdf degraded like a scan, or pdf itself if s is nil.
+func (s *scanConfig) apply(pdf []byte) ([]byte, error) {
+	if s == nil {
+		return pdf, nil
+	}
+	scanned, err := overlay.SimulateScan(pdf, s.opts, s.rasterize)
+	if err != nil {
+		return nil, fmt.Errorf("simulating scan: %w", err)
+	}
+	return scanned, nil
+}
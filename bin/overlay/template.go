@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// templateSource is the paystub data that fills in the {{...}} placeholders
+// of overlay text: one stub read with -data, a -fake stub per output, or one
+// of the stubs of -roster or -series per output.
+type templateSource struct {
+	stub   *paystub.Paystub  // read from -data
+	stubs  []paystub.Paystub // from -roster or -series; output i gets stubs[i]
+	seed   uint64            // with -fake, output i gets the stub made from seed+i
+	rates  paystub.Rates     // withholds the -fake stubs
+	locale string            // formats the amounts and dates; "" for en-US
+}
+
+// fill returns overlays with their text templates filled in for output i of
+// the run. A nil source leaves them as they are.
+func (t *templateSource) fill(overlays []overlay.OverlayRectText, i int) ([]overlay.OverlayRectText, error) {
+	if t == nil {
+		return overlays, nil
+	}
+	stub, _ := t.stubFor(i)
+	return paystub.FillTemplates(overlays, stub, t.locale)
+}
+
+// stubFor returns the data of output i of the run and, if it is made up,
+// the seed it is made from.
+func (t *templateSource) stubFor(i int) (paystub.Paystub, uint64) {
+	switch {
+	case t.stub != nil:
+		return *t.stub, 0
+	case t.stubs != nil:
+		return t.stubs[i], 0
+	}
+	seed := t.seed + uint64(i)
+	return newFaker(seed, t.rates).Paystub(), seed
+}
+
+// describe names the data of output i in run summaries.
+func (t *templateSource) describe(i int) string {
+	if t.stubs != nil {
+		return stubSource(t.stubs[i])
+	}
+	return fmt.Sprintf("seed %d", t.seed+uint64(i))
+}
+
+// describeResult sets the data and seed of r, output i of the run. A nil
+// source leaves r as it is.
+func (t *templateSource) describeResult(r *batchResult, i int) {
+	if t != nil {
+		r.data, r.seed = t.stubFor(i)
+	}
+}
+
+// fillFiles applies overlays to the PDF at input count times, numbered from
+// 1, on up to workers goroutines, filling their text templates from c.data
+// for each copy, and writes copy i to pattern with indexPlaceholder replaced
+// by i. It returns one result per copy in order. A failing copy doesn't stop
+// the others.
+func fillFiles(c runConfig, input, pattern string, overlays []overlay.OverlayRectText, count, workers int) []batchResult {
+	return runJobs(count, workers, c.track, c.truth, func(i int) batchResult {
+		r := batchResult{
+			input:  fmt.Sprintf("%s #%d (%s)", input, i+1, c.data.describe(i)),
+			output: strings.ReplaceAll(pattern, indexPlaceholder, strconv.Itoa(i+1)),
+		}
+		c.data.describeResult(&r, i)
+		return r
+	}, func(i int, r *batchResult) {
+		filled, err := c.data.fill(overlays, i)
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.report, r.truth, r.err = processFile(c, input, r.output, filled)
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// truthRecord is the -truth ground truth of one output PDF: every text
+// field drawn on it with its page and box.
+type truthRecord struct {
+	Output string `json:"output"`
+	// Evil are the -evil kinds of inconsistency put in the output on
+	// purpose; its Fields are what it shows all the same.
+	Evil   []string             `json:"evil,omitempty"`
+	Fields []overlay.TruthField `json:"fields"`
+}
+
+// writeTruth writes the ground truth of a single output PDF as JSON to path,
+// or to stdout when path is "-".
+func writeTruth(path string, record truthRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(data, '\n'))
+}
+
+// writeTruthLines writes the ground truth of several output PDFs as JSON
+// Lines, one record per PDF, to path, or to stdout when path is "-".
+func writeTruthLines(path string, records []truthRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return writeOutput(path, buf.Bytes())
+}
+
+// writeOutput writes data to path, or to stdout when path is "-".
+func writeOutput(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
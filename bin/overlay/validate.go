@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+	"github.com/StCredZero/paystub-test-gen/pkg/paystub"
+)
+
+// runValidate runs the validate subcommand with its arguments args: it
+// checks that the overlays' text can be read back from -pdf where they draw
+// it and that no forbidden text is left, exiting with status 1 if not.
+func runValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: overlay-rect-text validate -pdf=modified.pdf -json=overlays.json [-forbid=123-45-6789] [flags]\n\n%s. Exits with status 1, after reporting what is wrong, if some overlay text isn't found or some forbidden text is.\n\nFlags:\n", validateSummary)
+		flags.PrintDefaults()
+	}
+	pdfPath := flags.String("pdf", "", "Path to the PDF to check, the output of applying -json (- for stdin)")
+	jsonPath := flags.String("json", "", "Path to the overlays applied to -pdf, in JSON, YAML or TOML (- for stdin)")
+	format := flags.String("format", "auto", "Format of the -json file: json, yaml, toml, or auto to go by its extension")
+	origin := flags.String("origin", "bl", "Default coordinate origin of the overlays, as given to the overlay command")
+	units := flags.String("units", "pt", "Default units of the overlays, as given to the overlay command")
+	dataPath := flags.String("data", "", "Path to the paystub JSON data the {{...}} placeholders of the overlay text were filled in from")
+	locale := flags.String("locale", "", "Locale the placeholders of -data were filled in with (default en-US)")
+	var forbidden []string
+	flags.Func("forbid", "Text that must not be found anywhere in -pdf, such as the SSN of the original; may be repeated", func(s string) error {
+		forbidden = append(forbidden, s)
+		return nil
+	})
+	forbidPath := flags.String("forbid-file", "", "Path to a file of text that must not be found, one string per line")
+	tolerance := flags.Float64("tolerance", 2, "How far, in points, overlay text may be found from where the overlay draws it")
+	report := flags.String("report", "text", "Report format: text (a diff of the failures) or json (every check)")
+	outPath := flags.String("out", "-", "Path to write the report to (- for stdout)")
+	password := flags.String("inpw", "", "Password (user or owner) to open an encrypted -pdf with")
+	logs := addLogFlags(flags)
+	flags.Parse(args)
+	logs.setup(false)
+	if *pdfPath == "" || (*jsonPath == "" && len(forbidden) == 0 && *forbidPath == "") || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if *pdfPath == "-" && (*jsonPath == "-" || *dataPath == "-" || *forbidPath == "-") {
+		fatalf(exitUsage, "Only one of -pdf, -json, -data and -forbid-file can be read from stdin\n")
+	}
+	if *report != "text" && *report != "json" {
+		fatalf(exitUsage, "Invalid -report %q (valid: text, json)\n", *report)
+	}
+	if *tolerance < 0 {
+		fatalf(exitUsage, "-tolerance must not be negative\n")
+	}
+
+	var overlays []overlay.OverlayRectText
+	if *jsonPath != "" {
+		data, err := readInput(*jsonPath)
+		if err != nil {
+			fatalf(exitInput, "Could not read JSON file: %v\n", err)
+		}
+		if overlays, err = decodeOverlays(data, *jsonPath, *format, "overlay"); err != nil {
+			fatalf(exitInput, "Overlay file parse error: %v\n", err)
+		}
+		for i := range overlays {
+			if overlays[i].Origin == "" {
+				overlays[i].Origin = *origin
+			}
+			if overlays[i].Units == "" {
+				overlays[i].Units = *units
+			}
+		}
+	}
+	if *dataPath != "" {
+		stubData, err := readInput(*dataPath)
+		if err != nil {
+			fatalf(exitInput, "Could not read paystub data: %v\n", err)
+		}
+		stub, err := paystub.DecodePaystub(bytes.NewReader(stubData))
+		if err != nil {
+			fatalf(exitInput, "Paystub data parse error: %v\n", err)
+		}
+		if overlays, err = paystub.FillTemplates(overlays, stub, *locale); err != nil {
+			fatalf(exitInput, "Filling in overlay text failed: %v\n", err)
+		}
+	}
+	if *forbidPath != "" {
+		data, err := readInput(*forbidPath)
+		if err != nil {
+			fatalf(exitInput, "Could not read forbidden text: %v\n", err)
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				forbidden = append(forbidden, line)
+			}
+		}
+	}
+
+	pdf, err := readPDF(*pdfPath, *password)
+	if err != nil {
+		fatalf(exitPDF, "Could not read PDF file: %v\n", err)
+	}
+	v, err := overlay.Validate(pdf, overlays, forbidden, *tolerance)
+	if err != nil {
+		fatalf(exitStatus(err), "Validating %s failed: %v\n", *pdfPath, err)
+	}
+
+	var buf bytes.Buffer
+	if *report == "json" {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			fatalf(exitFailure, "Could not encode report: %v\n", err)
+		}
+		buf.Write(append(data, '\n'))
+	} else {
+		writeValidationDiff(&buf, v)
+	}
+	if err := writeOutput(*outPath, buf.Bytes()); err != nil {
+		fatalf(exitOutput, "Could not write report: %v\n", err)
+	}
+	if !v.Pass {
+		os.Exit(exitFailure)
+	}
+}
+
+// writeValidationDiff writes the failures of v to w like a unified diff of
+// the text expected under each overlay against the text found there,
+// followed by the forbidden text found and a summary line.
+func writeValidationDiff(w io.Writer, v overlay.Validation) {
+	failed := 0
+	for _, c := range v.Texts {
+		if c.Pass {
+			continue
+		}
+		if failed == 0 {
+			fmt.Fprintln(w, "--- expected")
+			fmt.Fprintln(w, "+++ found")
+		}
+		failed++
+		name := ""
+		if c.Label != "" {
+			name = " " + c.Label
+		} else if c.Field != "" {
+			name = " " + c.Field
+		}
+		fmt.Fprintf(w, "@@ overlay %d%s, page %d, at %.2f,%.2f %.2fx%.2f @@\n", c.Index, name, c.Page, c.X, c.Y, c.Width, c.Height)
+		for _, line := range strings.Split(c.Want, "\n") {
+			fmt.Fprintf(w, "-%s\n", line)
+		}
+		if c.Found != "" {
+			fmt.Fprintf(w, "+%s\n", c.Found)
+		}
+	}
+	for _, m := range v.Forbidden {
+		fmt.Fprintf(w, "forbidden %q found on page %d at %.2f,%.2f %.2fx%.2f\n", m.Text, m.Page, m.X, m.Y, m.Width, m.Height)
+	}
+	fmt.Fprintf(w, "%d of %d overlay texts found, %d forbidden strings found\n", len(v.Texts)-failed, len(v.Texts), len(v.Forbidden))
+}
@@ -2,7 +2,14 @@ module github.com/StCredZero/paystub-test-gen
 
 go 1.23.4
 
-require github.com/pdfcpu/pdfcpu v0.9.1
+require (
+	github.com/pdfcpu/pdfcpu v0.9.1
+	golang.org/x/image v0.21.0
+	golang.org/x/text v0.21.0
+	google.golang.org/grpc v1.71.0
+	google.golang.org/protobuf v1.36.12
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/hhrutter/lzw v1.0.0 // indirect
@@ -11,8 +18,8 @@ require (
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	golang.org/x/image v0.21.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
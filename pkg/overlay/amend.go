@@ -0,0 +1,136 @@
+package overlay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// amendFont is the font Amend writes its text in, and amendResource the
+// name of its resource on the amended page.
+const (
+	amendFont     = "Helvetica"
+	amendResource = "AmendF1"
+)
+
+// Amend returns pdf with f.Text written over the box of f, a field of its
+// ground truth, which is whited out first: the text is right-aligned in
+// the box, in Helvetica as tall as it. The change is appended to pdf as an
+// incremental update, leaving the bytes of pdf as they were, so a
+// signature over them still checks out but no longer covers the whole
+// file, as when a signed document is changed afterwards.
+//
+// pdf must end in a cross-reference table, as the PDFs Sign writes do,
+// and not be encrypted.
+func Amend(pdf []byte, f TruthField) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	if ctx.Encrypt != nil {
+		return nil, errors.New("cannot amend an encrypted PDF")
+	}
+	at := bytes.LastIndex(pdf, []byte("startxref"))
+	fields := bytes.Fields(pdf[max(at, 0):])
+	if at < 0 || len(fields) < 2 {
+		return nil, errors.New("no startxref")
+	}
+	prev, err := strconv.Atoi(string(fields[1]))
+	if err != nil || prev < 0 || prev >= at || !bytes.HasPrefix(pdf[prev:], []byte("xref")) {
+		return nil, errors.New("amending needs a PDF that ends in a cross-reference table, not a stream")
+	}
+	if f.Page < 1 || f.Page > ctx.PageCount {
+		return nil, fmt.Errorf("page %d: the PDF has %d pages", f.Page, ctx.PageCount)
+	}
+	page, pageRef, inherited, err := ctx.XRefTable.PageDict(f.Page, false)
+	if err != nil {
+		return nil, err
+	}
+	// The standard fonts show WinAnsiEncoding, Windows-1252 but for a few
+	// codes.
+	winAnsi, err := charmap.Windows1252.NewEncoder().String(f.Text)
+	if err != nil {
+		return nil, fmt.Errorf("text %q: not in WinAnsiEncoding", f.Text)
+	}
+	text, err := types.Escape(winAnsi)
+	if err != nil {
+		return nil, err
+	}
+
+	// The amendment adds the page's font to a copy of its resources, and
+	// wraps its content in q Q so the new content starts from the default
+	// graphics state.
+	page = page.Clone().(types.Dict)
+	res := types.Dict{}
+	if inherited.Resources != nil {
+		res = inherited.Resources.Clone().(types.Dict)
+	}
+	fonts, err := ctx.XRefTable.DereferenceDict(res["Font"])
+	if err != nil {
+		return nil, fmt.Errorf("page %d fonts: %v", f.Page, err)
+	}
+	if fonts == nil {
+		fonts = types.Dict{}
+	} else {
+		fonts = fonts.Clone().(types.Dict)
+	}
+	fonts[amendResource] = types.Dict{
+		"Type":     types.Name("Font"),
+		"Subtype":  types.Name("Type1"),
+		"BaseFont": types.Name(amendFont),
+		"Encoding": types.Name("WinAnsiEncoding"),
+	}
+	res["Font"] = fonts
+	page["Resources"] = res
+
+	size := max(font.SizeForLineHeight(amendFont, f.Height), 1)
+	w := font.TextWidth(winAnsi, amendFont, size)
+	ops := fmt.Sprintf("Q q 1 g %.2f %.2f %.2f %.2f re f 0 g BT /%s %d Tf %.2f %.2f Td (%s) Tj ET Q",
+		f.X-1, f.Y-1, f.Width+2, f.Height+2, amendResource, size, f.X+f.Width-w, f.Y+font.Descent(amendFont, size), *text)
+	streams := []string{"q", ops}
+
+	size0 := *ctx.XRefTable.Size
+	contents, err := ctx.XRefTable.DereferenceArray(page["Contents"])
+	if err != nil {
+		// A single content stream.
+		contents = types.Array{page["Contents"]}
+	}
+	page["Contents"] = append(append(types.Array{*types.NewIndirectRef(size0, 0)}, contents...), *types.NewIndirectRef(size0+1, 0))
+
+	// The update is the page, the two new content streams, and a
+	// cross-reference section of them that points back to the last one.
+	out := bytes.Clone(pdf)
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	offsets := map[int]int{}
+	pageNr, gen := pageRef.ObjectNumber.Value(), pageRef.GenerationNumber.Value()
+	offsets[pageNr] = len(out)
+	out = fmt.Appendf(out, "%d %d obj\n%s\nendobj\n", pageNr, gen, page.PDFString())
+	for i, s := range streams {
+		offsets[size0+i] = len(out)
+		out = fmt.Appendf(out, "%d 0 obj\n<</Length %d>>\nstream\n%s\nendstream\nendobj\n", size0+i, len(s), s)
+	}
+	xref := len(out)
+	out = fmt.Appendf(out, "xref\n0 1\n0000000000 65535 f \n%d 1\n%010d %05d n \n%d %d\n", pageNr, offsets[pageNr], gen, size0, len(streams))
+	for i := range streams {
+		out = fmt.Appendf(out, "%010d 00000 n \n", offsets[size0+i])
+	}
+	trailer := types.Dict{"Size": types.Integer(size0 + len(streams)), "Root": *ctx.XRefTable.Root, "Prev": types.Integer(prev)}
+	if ctx.XRefTable.Info != nil {
+		trailer["Info"] = *ctx.XRefTable.Info
+	}
+	if ctx.XRefTable.ID != nil {
+		trailer["ID"] = ctx.XRefTable.ID
+	}
+	out = fmt.Appendf(out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer.PDFString(), xref)
+	return out, nil
+}
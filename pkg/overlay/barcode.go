@@ -0,0 +1,672 @@
+package overlay
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// codeSymbology returns the symbology of ov's code, "code128" or "qr", or ""
+// if ov draws no code.
+func codeSymbology(ov OverlayRectText) (string, error) {
+	switch ov.Type {
+	case "", "image", "line", "rect":
+		return "", nil
+	case "qrcode":
+		if ov.Symbology != "" && ov.Symbology != "qr" {
+			return "", fmt.Errorf("type qrcode cannot have symbology %q", ov.Symbology)
+		}
+		return "qr", nil
+	case "barcode":
+		switch ov.Symbology {
+		case "", "code128":
+			if ov.ErrorCorrection != "" {
+				return "", fmt.Errorf("errorCorrection is only for QR codes")
+			}
+			return "code128", nil
+		case "qr":
+			return "qr", nil
+		}
+		return "", fmt.Errorf("invalid symbology %q (valid: code128, qr)", ov.Symbology)
+	}
+	return "", fmt.Errorf("invalid type %q (valid: line, rect, image, barcode, qrcode)", ov.Type)
+}
+
+// codeOps returns the operators drawing ov's code in its TextColor inside
+// the Width x Height box, or "" if ov draws no code. A Code 128 barcode is
+// stretched over the whole box and a QR code is the largest square centred
+// in it; both include their quiet zones.
+func codeOps(ov OverlayRectText) (string, error) {
+	symbology, err := codeSymbology(ov)
+	if err != nil || symbology == "" {
+		return "", err
+	}
+	if ov.Content == "" {
+		return "", fmt.Errorf("%s needs content", ov.Type)
+	}
+	if ov.Width <= 0 || ov.Height <= 0 {
+		return "", fmt.Errorf("%s needs a positive width and height", ov.Type)
+	}
+
+	var dark color.Color = color.Black
+	if ov.TextColor != "" {
+		if dark, err = parseColor(ov.TextColor); err != nil {
+			return "", fmt.Errorf("textColor: %v", err)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s rg", rgOperands(dark))
+	if symbology == "code128" {
+		widths, err := code128(ov.Content)
+		if err != nil {
+			return "", err
+		}
+		total := code128Quiet * 2
+		for _, w := range widths {
+			total += w
+		}
+		module := ov.Width / float64(total)
+		x := code128Quiet
+		for i, w := range widths {
+			// Bars and spaces alternate, starting with a bar.
+			if i%2 == 0 {
+				fmt.Fprintf(&b, " %.3f 0 %.3f %.3f re", float64(x)*module, float64(w)*module, ov.Height)
+			}
+			x += w
+		}
+	} else {
+		level, err := qrLevelFor(ov.ErrorCorrection)
+		if err != nil {
+			return "", err
+		}
+		modules, err := qrCode([]byte(ov.Content), level)
+		if err != nil {
+			return "", err
+		}
+		n := len(modules)
+		module := min(ov.Width, ov.Height) / float64(n+2*qrQuiet)
+		x0 := (ov.Width - float64(n)*module) / 2
+		y0 := (ov.Height - float64(n)*module) / 2
+		for row := range modules {
+			// Each run of dark modules in a row is one rectangle; row 0 is
+			// at the top.
+			y := y0 + float64(n-1-row)*module
+			for col := 0; col < n; {
+				if !modules[row][col] {
+					col++
+					continue
+				}
+				start := col
+				for col < n && modules[row][col] {
+					col++
+				}
+				fmt.Fprintf(&b, " %.3f %.3f %.3f %.3f re", x0+float64(start)*module, y, float64(col-start)*module, module)
+			}
+		}
+	}
+	b.WriteString(" f")
+	return b.String(), nil
+}
+
+// code128Quiet is the width of the quiet zone on either side of a Code 128
+// barcode, in modules.
+const code128Quiet = 10
+
+// code128Patterns are the widths of the bars and spaces of each Code 128
+// symbol value, in modules, alternating from a bar; the last is the stop
+// pattern.
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+// Code 128 code sets and the symbol values that start or switch to them.
+const (
+	code128A = iota
+	code128B
+	code128C
+)
+
+var (
+	code128Start  = [3]int{103, 104, 105}
+	code128Switch = [3]int{101, 100, 99}
+)
+
+// code128 returns the widths of the bars and spaces of s as a Code 128
+// barcode, alternating from a bar, without quiet zones. Runs of digits are
+// packed two to a symbol in code set C; other characters use code set B, or
+// A for control characters.
+func code128(s string) ([]int, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] > 127 {
+			return nil, fmt.Errorf("code128 can only encode ASCII characters")
+		}
+	}
+	digits := func(i int) int {
+		n := 0
+		for i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '9' {
+			n++
+		}
+		return n
+	}
+
+	var values []int
+	set := -1
+	use := func(to int) {
+		switch {
+		case set == to:
+		case set < 0:
+			values = append(values, code128Start[to])
+		default:
+			values = append(values, code128Switch[to])
+		}
+		set = to
+	}
+	for i := 0; i < len(s); {
+		// Set C pays off for a run of digits that saves more symbols than
+		// switching to it and back costs.
+		n := digits(i)
+		edge := i == 0 || i+n == len(s)
+		if set == code128C || n >= 6 || n >= 4 && edge || n == 2 && len(s) == 2 {
+			if n >= 2 {
+				use(code128C)
+				for ; n >= 2; n -= 2 {
+					values = append(values, int(s[i]-'0')*10+int(s[i+1]-'0'))
+					i += 2
+				}
+				continue
+			}
+		}
+		c := s[i]
+		switch {
+		case c < ' ':
+			use(code128A)
+			values = append(values, int(c)+64)
+		case c >= '`':
+			use(code128B)
+			values = append(values, int(c-' '))
+		default:
+			if set != code128A {
+				use(code128B)
+			}
+			values = append(values, int(c-' '))
+		}
+		i++
+	}
+
+	check := values[0]
+	for i, v := range values[1:] {
+		check += (i + 1) * v
+	}
+	values = append(values, check%103, 106)
+	var widths []int
+	for _, v := range values {
+		for _, w := range code128Patterns[v] {
+			widths = append(widths, int(w-'0'))
+		}
+	}
+	return widths, nil
+}
+
+// qrQuiet is the width of the quiet zone around a QR code, in modules.
+const qrQuiet = 4
+
+// QR code error correction levels, indexing the tables below.
+const (
+	qrL = iota
+	qrM
+	qrQ
+	qrH
+)
+
+// qrLevelFor returns the error correction level named e, M by default.
+func qrLevelFor(e string) (int, error) {
+	switch e {
+	case "L":
+		return qrL, nil
+	case "", "M":
+		return qrM, nil
+	case "Q":
+		return qrQ, nil
+	case "H":
+		return qrH, nil
+	}
+	return 0, fmt.Errorf("invalid errorCorrection %q (valid: L, M, Q, H)", e)
+}
+
+// qrECCPerBlock and qrBlocks are the error correction codewords per block
+// and the number of blocks of each level and version (1 to 40).
+var qrECCPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var qrBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// qrFormatLevel is the value of each level in the format information.
+var qrFormatLevel = [4]int{1, 0, 3, 2}
+
+// qrRawModules returns the number of modules of a QR code of version v that
+// hold codewords, rather than patterns or format and version information.
+func qrRawModules(v int) int {
+	n := (16*v+128)*v + 64
+	if v >= 2 {
+		align := v/7 + 2
+		n -= (25*align-10)*align - 55
+		if v >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// qrDataCodewords returns the number of data codewords of a QR code of
+// version v and error correction level.
+func qrDataCodewords(v, level int) int {
+	return qrRawModules(v)/8 - qrECCPerBlock[level][v]*qrBlocks[level][v]
+}
+
+// qrAlignment returns the centre coordinates of the alignment patterns of a
+// QR code of version v along either axis.
+func qrAlignment(v int) []int {
+	if v == 1 {
+		return nil
+	}
+	n := v/7 + 2
+	step := (v*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, v*4+10; i > 0; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// qrCode returns the modules of data as a QR code in byte mode at the
+// smallest version that holds it with error correction level, true for
+// dark, by row from the top.
+func qrCode(data []byte, level int) ([][]bool, error) {
+	v := 1
+	for ; v <= 40; v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrDataCodewords(v, level)*8 {
+			break
+		}
+	}
+	if v > 40 {
+		return nil, fmt.Errorf("%d bytes are too long for a QR code", len(data))
+	}
+
+	// The data codewords: the mode, the byte count, the bytes, a
+	// terminator and padding.
+	var bits []bool
+	put := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, value>>i&1 == 1)
+		}
+	}
+	put(0b0100, 4)
+	if v >= 10 {
+		put(len(data), 16)
+	} else {
+		put(len(data), 8)
+	}
+	for _, c := range data {
+		put(int(c), 8)
+	}
+	capacity := qrDataCodewords(v, level) * 8
+	put(0, min(4, capacity-len(bits)))
+	put(0, -len(bits)&7)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		put(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+
+	q := newQR(v)
+	q.draw(qrInterleave(codewords, v, level))
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.mask(mask)
+		q.format(level, mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.mask(mask)
+	}
+	q.mask(best)
+	q.format(level, best)
+	return q.modules, nil
+}
+
+// qrInterleave splits the data codewords of a QR code of version v into
+// blocks, adds the Reed-Solomon error correction codewords of each and
+// interleaves them.
+func qrInterleave(data []byte, v, level int) []byte {
+	blocks, eccLen := qrBlocks[level][v], qrECCPerBlock[level][v]
+	raw := qrRawModules(v) / 8
+	short, shortLen := blocks-raw%blocks, raw/blocks
+	divisor := rsDivisor(eccLen)
+
+	// Short blocks have one data codeword fewer than long ones.
+	var all [][]byte
+	for i := 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		block := append([]byte(nil), data[:n]...)
+		data = data[n:]
+		all = append(all, append(block, rsRemainder(block, divisor)...))
+	}
+	var out []byte
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range all {
+			switch {
+			case j < short && i == shortLen-eccLen:
+			case j < short && i > shortLen-eccLen:
+				out = append(out, block[i-1])
+			default:
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+// gfMul multiplies x and y in the Galois field GF(2^8) of QR codes.
+func gfMul(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x1D
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of degree n,
+// without its leading coefficient.
+func rsDivisor(n int) []byte {
+	d := make([]byte, n)
+	d[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := range d {
+			d[j] = gfMul(d[j], root)
+			if j+1 < n {
+				d[j] ^= d[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return d
+}
+
+// rsRemainder returns the Reed-Solomon error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	r := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ r[0]
+		copy(r, r[1:])
+		r[len(r)-1] = 0
+		for i, c := range divisor {
+			r[i] ^= gfMul(c, factor)
+		}
+	}
+	return r
+}
+
+// qr is the module grid of a QR code being built.
+type qr struct {
+	size     int
+	modules  [][]bool // dark modules by row, then column
+	function [][]bool // modules of patterns and format information
+}
+
+// newQR returns a QR code of version v with its function patterns drawn.
+func newQR(v int) *qr {
+	n := v*4 + 17
+	q := &qr{size: n}
+	for i := 0; i < n; i++ {
+		q.modules = append(q.modules, make([]bool, n))
+		q.function = append(q.function, make([]bool, n))
+	}
+	for i := 0; i < n; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {n - 4, 3}, {3, n - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < n && y >= 0 && y < n {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	align := qrAlignment(v)
+	last := len(align) - 1
+	for i, x := range align {
+		for j, y := range align {
+			// Skip the corners that hold finder patterns.
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// Reserve the format information, to be drawn with the mask.
+	q.format(0, 0)
+	if v >= 7 {
+		rem := v
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := v<<12 | rem
+		for i := 0; i < 18; i++ {
+			a, b := n-11+i%3, i/3
+			q.set(a, b, bits>>i&1 == 1)
+			q.set(b, a, bits>>i&1 == 1)
+		}
+	}
+	return q
+}
+
+// set sets the function module in column x and row y.
+func (q *qr) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// format draws the format information of level and mask.
+func (q *qr) format(level, mask int) {
+	data := qrFormatLevel[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	n := q.size
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.set(n-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, n-15+i, bit(i))
+	}
+	q.set(8, n-8, true)
+}
+
+// draw places codewords in the modules that aren't function modules, in
+// the zigzag order of QR codes: upwards and downwards in two-column strips
+// from the right.
+func (q *qr) draw(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern.
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// mask inverts the modules that aren't function modules where mask's
+// pattern is dark; masking twice undoes it.
+func (q *qr) mask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// qrFinderLike are the module sequences that look like part of a finder
+// pattern, which penalty counts against a mask.
+var qrFinderLike = [2][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores how hard the modules are to read, the lower the better:
+// long runs and blocks of one colour, finder-like sequences and an
+// imbalance of dark and light modules count against them.
+func (q *qr) penalty() int {
+	n := q.size
+	p := 0
+	at := func(line, i int, transposed bool) bool {
+		if transposed {
+			return q.modules[i][line]
+		}
+		return q.modules[line][i]
+	}
+	for _, transposed := range []bool{false, true} {
+		for line := 0; line < n; line++ {
+			run := 0
+			for i := 0; i < n; i++ {
+				if i > 0 && at(line, i, transposed) == at(line, i-1, transposed) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					p += 3
+				} else if run > 5 {
+					p++
+				}
+				for _, pattern := range qrFinderLike {
+					if i+len(pattern) > n {
+						continue
+					}
+					match := true
+					for k, dark := range pattern {
+						if at(line, i+k, transposed) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						p += 40
+					}
+				}
+			}
+		}
+	}
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					p += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return p + k*10
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
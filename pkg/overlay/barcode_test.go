@@ -0,0 +1,491 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// code128Values decodes the bar and space widths code128 returns back into
+// symbol values.
+func code128Values(t *testing.T, widths []int) []int {
+	t.Helper()
+	byPattern := map[string]int{}
+	for v, p := range code128Patterns {
+		byPattern[p] = v
+	}
+	var values []int
+	for len(widths) > 0 {
+		n := 6
+		if len(widths) == 7 {
+			n = 7 // the stop pattern
+		}
+		if len(widths) < n {
+			t.Fatalf("%d widths left over", len(widths))
+		}
+		var p strings.Builder
+		for _, w := range widths[:n] {
+			fmt.Fprint(&p, w)
+		}
+		v, ok := byPattern[p.String()]
+		if !ok {
+			t.Fatalf("%s is no Code 128 pattern", p.String())
+		}
+		values = append(values, v)
+		widths = widths[n:]
+	}
+	return values
+}
+
+// TestCode128 checks the symbols code128 chooses, with its code set
+// switches and check symbol, against the values worked out by hand.
+func TestCode128(t *testing.T) {
+	// Some patterns from the Code 128 symbol table.
+	for v, want := range map[int]string{
+		0: "212222", 16: "123122", 99: "113141", 100: "114131", 101: "311141",
+		103: "211412", 104: "211214", 105: "211232", 106: "2331112",
+	} {
+		if got := code128Patterns[v]; got != want {
+			t.Errorf("pattern of %d = %s, want %s", v, got, want)
+		}
+	}
+
+	tests := []struct {
+		name, s string
+		want    []int // start, data, check, stop
+	}{
+		// 104 + 33×1 + 34×2 + 17×3 + 18×4 = 328, and 328 mod 103 = 19.
+		{name: "set B", s: "AB12", want: []int{104, 33, 34, 17, 18, 19, 106}},
+		{name: "two digits alone", s: "12", want: []int{105, 12, 14, 106}},
+		{name: "digits only", s: "123456", want: []int{105, 12, 34, 56, 44, 106}},
+		{name: "digits at the end", s: "A1234", want: []int{104, 33, 99, 12, 34, 95, 106}},
+		{name: "odd digits", s: "12345", want: []int{105, 12, 34, 100, 21, 54, 106}},
+		{name: "short run of digits in the middle", s: "A1234B", want: []int{104, 33, 17, 18, 19, 20, 34, 90, 106}},
+		{name: "long run of digits in the middle", s: "A123456B", want: []int{104, 33, 99, 12, 34, 56, 100, 34, 80, 106}},
+		{name: "control character after lower case", s: "a\tb", want: []int{104, 65, 101, 73, 100, 66, 84, 106}},
+		{name: "set A kept for upper case", s: "A\tB", want: []int{104, 33, 101, 73, 34, 76, 106}},
+		{name: "control character first", s: "\n1", want: []int{103, 74, 17, 5, 106}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			widths, err := code128(tt.s)
+			if err != nil {
+				t.Fatalf("code128: %v", err)
+			}
+			got := code128Values(t, widths)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("code128(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+			modules := 0
+			for _, w := range widths {
+				modules += w
+			}
+			if want := 11*(len(tt.want)-1) + 13; modules != want {
+				t.Errorf("%d modules wide, want %d", modules, want)
+			}
+		})
+	}
+
+	if _, err := code128("café"); err == nil {
+		t.Error("code128 encoded a non-ASCII character")
+	}
+}
+
+// qrFormatBits are the format information bits of each error correction
+// level and mask, most significant first, from the QR code standard.
+var qrFormatBits = map[int][8]string{
+	qrL: {"111011111000100", "111001011110011", "111110110101010", "111100010011101", "110011000101111", "110001100011000", "110110001000001", "110100101110110"},
+	qrM: {"101010000010010", "101000100100101", "101111001111100", "101101101001011", "100010111111001", "100000011001110", "100111110010111", "100101010100000"},
+	qrQ: {"011010101011111", "011000001101000", "011111100110001", "011101000000110", "010010010110100", "010000110000011", "010111011011010", "010101111101101"},
+	qrH: {"001011010001001", "001001110111110", "001110011100111", "001100111010000", "000011101100010", "000001001010101", "000110100001100", "000100000111011"},
+}
+
+// qrReadFormat returns the two copies of the format information of modules,
+// most significant bit first.
+func qrReadFormat(modules [][]bool) (string, string) {
+	n := len(modules)
+	bit := func(x, y int) byte {
+		if modules[y][x] {
+			return '1'
+		}
+		return '0'
+	}
+	first, second := make([]byte, 15), make([]byte, 15)
+	// Bit i is at index 14-i. The first copy runs down column 8 and then
+	// left along row 8 around the top-left finder; the second is split
+	// between the top-right and bottom-left ones.
+	for i := 0; i <= 5; i++ {
+		first[14-i] = bit(8, i)
+	}
+	first[14-6], first[14-7], first[14-8] = bit(8, 7), bit(8, 8), bit(7, 8)
+	for i := 9; i < 15; i++ {
+		first[14-i] = bit(14-i, 8)
+	}
+	for i := 0; i < 8; i++ {
+		second[14-i] = bit(n-1-i, 8)
+	}
+	for i := 8; i < 15; i++ {
+		second[14-i] = bit(8, n-15+i)
+	}
+	return string(first), string(second)
+}
+
+// TestQRFormat checks the format information drawn for each level and mask
+// against the standard's table.
+func TestQRFormat(t *testing.T) {
+	for level, masks := range qrFormatBits {
+		for mask, want := range masks {
+			q := newQR(1)
+			q.format(level, mask)
+			first, second := qrReadFormat(q.modules)
+			if first != want || second != want {
+				t.Errorf("level %d, mask %d: format %s and %s, want %s", level, mask, first, second, want)
+			}
+			if !q.modules[q.size-8][8] {
+				t.Errorf("level %d, mask %d: the dark module is light", level, mask)
+			}
+		}
+	}
+}
+
+// TestQRVersionInfo checks the version information of versions 7 and up,
+// against values from the standard, in both of its blocks.
+func TestQRVersionInfo(t *testing.T) {
+	for v, want := range map[int]int{7: 0x07C94, 8: 0x085BC, 21: 0x15683, 40: 0x28C69} {
+		q := newQR(v)
+		n := q.size
+		var bottomLeft, topRight int
+		for i := 0; i < 18; i++ {
+			if q.modules[n-11+i%3][i/3] {
+				bottomLeft |= 1 << i
+			}
+			if q.modules[i/3][n-11+i%3] {
+				topRight |= 1 << i
+			}
+		}
+		if bottomLeft != want || topRight != want {
+			t.Errorf("version %d: information %#05x and %#05x, want %#05x", v, bottomLeft, topRight, want)
+		}
+	}
+	if q := newQR(6); q.function[q.size-11][0] {
+		t.Error("version 6 reserves modules for version information")
+	}
+}
+
+// TestQRVersion checks that qrCode picks the smallest version that holds
+// the data, at the byte capacities the standard lists.
+func TestQRVersion(t *testing.T) {
+	for level, want := range [4][41]int{
+		qrL: {1: 19, 5: 108, 40: 2956},
+		qrM: {1: 16, 5: 86, 40: 2334},
+		qrQ: {1: 13, 5: 62, 40: 1666},
+		qrH: {1: 9, 5: 46, 40: 1276},
+	} {
+		for v, n := range want {
+			if got := qrDataCodewords(v, level); n != 0 && got != n {
+				t.Errorf("version %d, level %d: %d data codewords, want %d", v, level, got, n)
+			}
+		}
+	}
+
+	tests := []struct {
+		level, bytes, version int
+	}{
+		{qrL, 17, 1}, {qrL, 18, 2},
+		{qrM, 14, 1}, {qrM, 15, 2},
+		{qrQ, 11, 1}, {qrQ, 12, 2},
+		{qrH, 7, 1}, {qrH, 8, 2},
+		// The byte count takes 16 bits from version 10.
+		{qrL, 230, 9}, {qrL, 231, 10}, {qrL, 271, 10}, {qrL, 272, 11},
+		{qrM, 2331, 40}, {qrH, 1273, 40},
+	}
+	for _, tt := range tests {
+		modules, err := qrCode(bytes.Repeat([]byte("a"), tt.bytes), tt.level)
+		if err != nil {
+			t.Errorf("level %d, %d bytes: %v", tt.level, tt.bytes, err)
+			continue
+		}
+		if want := tt.version*4 + 17; len(modules) != want {
+			t.Errorf("level %d, %d bytes: %d modules wide, want %d (version %d)", tt.level, tt.bytes, len(modules), want, tt.version)
+		}
+	}
+	for level, n := range [4]int{qrL: 2953, qrM: 2331, qrQ: 1663, qrH: 1273} {
+		if _, err := qrCode(make([]byte, n+1), level); err == nil {
+			t.Errorf("level %d: %d bytes didn't fail", level, n+1)
+		}
+	}
+}
+
+// rsSyndromesZero reports whether codeword, data followed by its error
+// correction, is a Reed-Solomon codeword of its error correction length:
+// whether it has the generator's roots 1, 2, ..., 2^(eccLen-1) in GF(2^8).
+func rsSyndromesZero(codeword []byte, eccLen int) bool {
+	root := byte(1)
+	for i := 0; i < eccLen; i++ {
+		var s byte
+		for _, c := range codeword {
+			s = gfMul(s, root) ^ c
+		}
+		if s != 0 {
+			return false
+		}
+		root = gfMul(root, 2)
+	}
+	return true
+}
+
+// TestReedSolomon checks the error correction codewords against the
+// example of the QR code standard (version 1-M), and that those of other
+// lengths make valid codewords.
+func TestReedSolomon(t *testing.T) {
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = % X, want % X", got, want)
+	}
+
+	for _, n := range []int{7, 10, 13, 17, 18, 22, 26, 28, 30} {
+		data := make([]byte, 40)
+		for i := range data {
+			data[i] = byte(i*37 + n)
+		}
+		if !rsSyndromesZero(append(data, rsRemainder(data, rsDivisor(n))...), n) {
+			t.Errorf("%d error correction codewords don't make a valid codeword", n)
+		}
+	}
+}
+
+// TestQRInterleave checks the blocks of a single-block version against a
+// known encoding, and the split into short and long blocks of one with
+// several.
+func TestQRInterleave(t *testing.T) {
+	data := []byte{32, 65, 205, 69, 41, 220, 46, 128, 236}
+	want := []byte{32, 65, 205, 69, 41, 220, 46, 128, 236, 42, 159, 74, 221, 244, 169, 239, 150, 138, 70, 237, 85, 224, 96, 74, 219, 61}
+	if got := qrInterleave(data, 1, qrH); !bytes.Equal(got, want) {
+		t.Errorf("version 1-H = %v, want %v", got, want)
+	}
+
+	// Version 5-Q has two blocks of 15 data codewords and two of 16, each
+	// with 18 error correction codewords.
+	sizes, eccLen := []int{15, 15, 16, 16}, 18
+	data = make([]byte, 62)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	out := qrInterleave(data, 5, qrQ)
+	if len(out) != 134 {
+		t.Fatalf("%d codewords, want 134", len(out))
+	}
+	blocks := make([][]byte, len(sizes))
+	pos := 0
+	for i := 0; i < 16; i++ {
+		for j, n := range sizes {
+			if i < n {
+				blocks[j] = append(blocks[j], out[pos])
+				pos++
+			}
+		}
+	}
+	if got := bytes.Join(blocks, nil); !bytes.Equal(got, data) {
+		t.Errorf("data codewords by block = %v, want %v", got, data)
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], out[pos])
+			pos++
+		}
+	}
+	for j, block := range blocks {
+		if !rsSyndromesZero(block, eccLen) {
+			t.Errorf("block %d isn't a valid codeword", j)
+		}
+	}
+}
+
+// qrMasks are the mask patterns of the standard, for row i and column j.
+var qrMasks = [8]func(i, j int) bool{
+	func(i, j int) bool { return (i+j)%2 == 0 },
+	func(i, j int) bool { return i%2 == 0 },
+	func(i, j int) bool { return j%3 == 0 },
+	func(i, j int) bool { return (i+j)%3 == 0 },
+	func(i, j int) bool { return (i/2+j/3)%2 == 0 },
+	func(i, j int) bool { return i*j%2+i*j%3 == 0 },
+	func(i, j int) bool { return (i*j%2+i*j%3)%2 == 0 },
+	func(i, j int) bool { return ((i+j)%2+i*j%3)%2 == 0 },
+}
+
+// qrDecode reads the byte-mode data of the QR code modules, checking its
+// format information and error correction.
+func qrDecode(t *testing.T, modules [][]bool, wantLevel int) []byte {
+	t.Helper()
+	n := len(modules)
+	v := (n - 17) / 4
+	first, second := qrReadFormat(modules)
+	if first != second {
+		t.Fatalf("format copies differ: %s and %s", first, second)
+	}
+	level, mask := -1, -1
+	for l, masks := range qrFormatBits {
+		for m, bits := range masks {
+			if bits == first {
+				level, mask = l, m
+			}
+		}
+	}
+	if level != wantLevel {
+		t.Fatalf("format %s is level %d, want %d", first, level, wantLevel)
+	}
+
+	// Read the codewords in zigzag order, upwards first, from the
+	// rightmost pair of columns, skipping column 6 and function modules.
+	function := newQR(v).function
+	var bits []bool
+	up := true
+	for right := n - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for k := 0; k < n; k++ {
+			i := k
+			if up {
+				i = n - 1 - k
+			}
+			for _, j := range []int{right, right - 1} {
+				if !function[i][j] {
+					bits = append(bits, modules[i][j] != qrMasks[mask](i, j))
+				}
+			}
+		}
+		up = !up
+	}
+	codewords := make([]byte, qrRawModules(v)/8)
+	for k := range codewords {
+		for b := 0; b < 8; b++ {
+			if bits[k*8+b] {
+				codewords[k] |= 0x80 >> b
+			}
+		}
+	}
+	// Undo the interleaving: the data codewords of the blocks take turns,
+	// the short blocks, first, having one fewer, and then their error
+	// correction codewords do.
+	blocks, eccLen := qrBlocks[level][v], qrECCPerBlock[level][v]
+	short := blocks - len(codewords)%blocks
+	dataLen := func(j int) int {
+		n := len(codewords)/blocks - eccLen
+		if j >= short {
+			n++
+		}
+		return n
+	}
+	split := make([][]byte, blocks)
+	pos := 0
+	for i := 0; i <= dataLen(blocks-1); i++ {
+		for j := range split {
+			if i < dataLen(j) {
+				split[j] = append(split[j], codewords[pos])
+				pos++
+			}
+		}
+	}
+	var data []byte
+	for _, block := range split {
+		data = append(data, block...)
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range split {
+			split[j] = append(split[j], codewords[pos])
+			pos++
+		}
+	}
+	for j, block := range split {
+		if !rsSyndromesZero(block, eccLen) {
+			t.Fatalf("block %d, % X, isn't a valid codeword", j, block)
+		}
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	count := int(data[0]&0x0F)<<4 | int(data[1]>>4)
+	out := make([]byte, count)
+	for k := range out {
+		out[k] = data[1+k]<<4 | data[2+k]>>4
+	}
+	if data[1+count]&0x0F != 0 {
+		t.Errorf("no terminator after the data")
+	}
+	for k, pad := range data[2+count:] {
+		if want := []byte{0xEC, 0x11}[k%2]; pad != want {
+			t.Errorf("pad codeword %d = %#x, want %#x", k, pad, want)
+		}
+	}
+	return out
+}
+
+// TestQRCode checks a small QR code against its golden image, and that
+// its format information, mask, error correction and data read back.
+func TestQRCode(t *testing.T) {
+	// Version 1-M of "PAY-0042"; # is dark.
+	golden := []string{
+		"#######..#.#..#######",
+		"#.....#..#....#.....#",
+		"#.###.#.####..#.###.#",
+		"#.###.#.#..#..#.###.#",
+		"#.###.#.##.##.#.###.#",
+		"#.....#.###.#.#.....#",
+		"#######.#.#.#.#######",
+		"........#..##........",
+		"#.#####.....#.#####..",
+		"....#...##..#...#.#..",
+		"##.#..##.###..##.###.",
+		"..##.#..###....#.##..",
+		"..#.#.###..#.....##..",
+		"........##.####.#..#.",
+		"#######..##.#.##.###.",
+		"#.....#.########..#..",
+		"#.###.#.##..#..#....#",
+		"#.###.#.#.#.#...##...",
+		"#.###.#.##.#.#.#.##..",
+		"#.....#........#..#..",
+		"#######.#..#.#.#.#.#.",
+	}
+	modules, err := qrCode([]byte("PAY-0042"), qrM)
+	if err != nil {
+		t.Fatalf("qrCode: %v", err)
+	}
+	var got []string
+	for _, row := range modules {
+		var line strings.Builder
+		for _, dark := range row {
+			if dark {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		got = append(got, line.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(golden, "\n") {
+		t.Errorf("QR code differs from the golden image:\n%s", strings.Join(got, "\n"))
+	}
+
+	for _, tt := range []struct {
+		data  string
+		level int
+	}{
+		{"PAY-0042", qrM},
+		{"", qrL},
+		{"Net pay: $1,234.56", qrL},
+		{"https://example.com/stub?id=42", qrH},
+	} {
+		modules, err := qrCode([]byte(tt.data), tt.level)
+		if err != nil {
+			t.Fatalf("qrCode(%q): %v", tt.data, err)
+		}
+		if got := qrDecode(t, modules, tt.level); string(got) != tt.data {
+			t.Errorf("%q read back as %q", tt.data, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package overlay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// boundsTolerance absorbs rounding in the sizes pdfcpu draws parts at.
+const boundsTolerance = 0.01
+
+// BoundsError lists the overlays CheckBounds found extending past a page.
+// It is an ErrInvalidOverlay.
+type BoundsError struct {
+	// Problems holds one error per offending overlay, giving its index,
+	// coordinates and pages.
+	Problems []error
+}
+
+func (e *BoundsError) Error() string {
+	return errors.Join(e.Problems...).Error()
+}
+
+func (e *BoundsError) Is(target error) bool { return target == ErrInvalidOverlay }
+
+// CheckBounds reports every overlay that would extend past the edges of a
+// page of pdf it is drawn on, as a *BoundsError. Overlays are measured as
+// they will be drawn, rotation included, against the page's visible area
+// (its crop box, which defaults to the media box, turned by the page's
+// /Rotate). Other errors mean the
+// check itself failed.
+func CheckBounds(pdf []byte, overlays []OverlayRectText) error {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	var errs []error
+	for i, ov := range overlays {
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return &OverlayError{i, err}
+		}
+		var outside []string
+		var first *types.Rectangle
+		var firstW, firstH float64
+		for page := 1; page <= ctx.PageCount; page++ {
+			if !pages[page] {
+				continue
+			}
+			pageW, pageH := viewOf(boundaries[page-1]).size()
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return err
+			}
+			b := plan.bounds(pageW, pageH)
+			if b == nil || (b.LL.X >= -boundsTolerance && b.LL.Y >= -boundsTolerance &&
+				b.UR.X <= pageW+boundsTolerance && b.UR.Y <= pageH+boundsTolerance) {
+				continue
+			}
+			if first == nil {
+				first, firstW, firstH = b, pageW, pageH
+			}
+			outside = append(outside, strconv.Itoa(page))
+		}
+		if first != nil {
+			onPages := "page"
+			if len(outside) > 1 {
+				onPages = "pages"
+			}
+			errs = append(errs, fmt.Errorf("overlay %d (x=%g, y=%g, width=%g, height=%g) covers (%.2f, %.2f)-(%.2f, %.2f), outside the %gx%g page, on %s %s",
+				i, ov.X, ov.Y, ov.Width, ov.Height, first.LL.X, first.LL.Y, first.UR.X, first.UR.Y,
+				firstW, firstH, onPages, strings.Join(outside, ", ")))
+		}
+	}
+	if len(errs) > 0 {
+		return &BoundsError{Problems: errs}
+	}
+	return nil
+}
+
+// partArea returns the area a w x h part of p, moved dx points right of the
+// overlay's X and dy points above its Y, covers on a pageW x pageH page, relative to the page's
+// lower-left corner.
+func (p overlayPlan) partArea(pageW, pageH, w, h, dx, dy float64) *types.Rectangle {
+	cx, cy := rotatedCenter(p.anchor, pageW, pageH, w, h, p.x, p.offsetY(pageH), dx, dy, p.rotation)
+	sin, cos := math.Sincos(p.rotation * math.Pi / 180)
+	m := matrix{cos, sin, -sin, cos, cx, cy}
+	return m.bounds(-w/2, -h/2, w/2, h/2)
+}
+
+// bounds returns the area everything p draws covers on a pageW x pageH page,
+// relative to the page's lower-left corner, or nil if p draws nothing.
+func (p overlayPlan) bounds(pageW, pageH float64) *types.Rectangle {
+	r := p.rectArea(pageW, pageH)
+	if p.ops != "" {
+		r = union(r, p.partArea(pageW, pageH, p.opsW*p.opsScale, p.opsH*p.opsScale, p.opsDX, p.opsDY))
+	}
+	return union(r, p.textBounds(pageW, pageH))
+}
+
+// textBounds returns the area the text of p covers on a pageW x pageH page,
+// relative to the page's lower-left corner, or nil if p has no text.
+func (p overlayPlan) textBounds(pageW, pageH float64) *types.Rectangle {
+	var r *types.Rectangle
+	for i, line := range p.lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		size := p.textSize
+		if size == 0 {
+			size = relativeFontSize(line, p.textFont, p.textRelScale, pageW)
+		}
+		w, h := font.TextWidth(line, p.textFont, size), font.LineHeight(p.textFont, size)
+		dx, dy := p.lineOffset(i, w, h, pageW, pageH)
+		r = union(r, p.partArea(pageW, pageH, w, h, dx, dy))
+	}
+	return r
+}
+
+// union returns the smallest rectangle covering a and b, either of which
+// may be nil for none.
+func union(a, b *types.Rectangle) *types.Rectangle {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return types.NewRectangle(min(a.LL.X, b.LL.X), min(a.LL.Y, b.LL.Y), max(a.UR.X, b.UR.X), max(a.UR.Y, b.UR.Y))
+}
@@ -0,0 +1,89 @@
+package overlay
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// parseColor parses a colour written as "#RRGGBB" or as an SVG colour name
+// such as "lightgray" or "navy", in any case.
+func parseColor(s string) (color.RGBA, error) {
+	if c, ok := colornames.Map[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: want #RRGGBB or a color name such as \"lightgray\"", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: want #RRGGBB or a color name such as \"lightgray\"", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// fillColorFor returns the rectangle fill colour of ov, defaulting to white,
+// or nil when its FillColor is "none".
+func fillColorFor(ov OverlayRectText) (color.Color, error) {
+	switch ov.FillColor {
+	case "":
+		return color.White, nil
+	case "none":
+		return nil, nil
+	}
+	return parseColor(ov.FillColor)
+}
+
+// borderFor returns the border colour of ov and its width in points,
+// defaulting to 1, or a nil colour when ov has no border.
+func borderFor(ov OverlayRectText) (color.Color, float64, error) {
+	if ov.BorderColor == "" {
+		return nil, 0, nil
+	}
+	if ov.BorderWidth < 0 {
+		return nil, 0, fmt.Errorf("invalid border width %g", ov.BorderWidth)
+	}
+	c, err := parseColor(ov.BorderColor)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ov.BorderWidth == 0 {
+		return c, 1, nil
+	}
+	return c, ov.BorderWidth, nil
+}
+
+// rgOperands returns the colour c as the r g b operands of a PDF rg or RG
+// operator.
+func rgOperands(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("%.4f %.4f %.4f", float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff)
+}
+
+// textColorFor returns the text colour of ov as "#RRGGBB", as pdfcpu takes
+// it, defaulting to black.
+func textColorFor(ov OverlayRectText) (string, error) {
+	if ov.TextColor == "" {
+		return "#000000", nil
+	}
+	c, err := parseColor(ov.TextColor)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B), nil
+}
+
+// opacityFor returns the opacity of ov, where nil (unset) means fully
+// opaque.
+func opacityFor(ov OverlayRectText) (float64, error) {
+	if ov.Opacity == nil {
+		return 1, nil
+	}
+	if op := *ov.Opacity; op < 0 || op > 1 {
+		return 0, fmt.Errorf("invalid opacity %g: want a value between 0 and 1", op)
+	}
+	return *ov.Opacity, nil
+}
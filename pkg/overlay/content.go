@@ -0,0 +1,359 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// contentOp is one operation of a page content stream: its operands, its
+// operator and where it sits in the stream.
+type contentOp struct {
+	operands   []contentObj
+	op         string
+	start, end int // byte range of the whole operation in the stream
+}
+
+// contentObj is an operand of a content stream operation. Only the parts the
+// redaction code needs are decoded; everything else is kept as raw bytes.
+type contentObj struct {
+	kind  byte         // 'n' number, 's' string, 'a' array, '/' name, 'o' other
+	num   float64      // for numbers
+	str   []byte       // decoded bytes, for strings
+	name  string       // for names
+	elems []contentObj // for arrays
+}
+
+// contentScanner splits a decoded content stream into operations.
+type contentScanner struct {
+	b   []byte
+	pos int
+}
+
+// parseContent parses a decoded content stream into its operations.
+func parseContent(b []byte) ([]contentOp, error) {
+	s := &contentScanner{b: b}
+	var ops []contentOp
+	var operands []contentObj
+	start := -1
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.b) {
+			break
+		}
+		if start < 0 {
+			start = s.pos
+		}
+		c := s.b[s.pos]
+		if isRegular(c) && !isNumberStart(c) {
+			kw := s.keyword()
+			switch kw {
+			case "true", "false", "null":
+				operands = append(operands, contentObj{kind: 'o'})
+				continue
+			case "BI":
+				if err := s.skipInlineImage(); err != nil {
+					return nil, err
+				}
+			}
+			ops = append(ops, contentOp{operands: operands, op: kw, start: start, end: s.pos})
+			operands, start = nil, -1
+			continue
+		}
+		obj, err := s.object()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, obj)
+	}
+	return ops, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func isRegular(c byte) bool {
+	return !isSpace(c) && !isDelimiter(c)
+}
+
+func isNumberStart(c byte) bool {
+	return c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9')
+}
+
+// skipSpace skips white space and comments.
+func (s *contentScanner) skipSpace() {
+	for s.pos < len(s.b) {
+		c := s.b[s.pos]
+		if c == '%' {
+			for s.pos < len(s.b) && s.b[s.pos] != '\n' && s.b[s.pos] != '\r' {
+				s.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		s.pos++
+	}
+}
+
+// keyword reads a run of regular characters.
+func (s *contentScanner) keyword() string {
+	start := s.pos
+	for s.pos < len(s.b) && isRegular(s.b[s.pos]) {
+		s.pos++
+	}
+	return string(s.b[start:s.pos])
+}
+
+// object reads one operand.
+func (s *contentScanner) object() (contentObj, error) {
+	c := s.b[s.pos]
+	switch {
+	case c == '(':
+		str, err := s.literalString()
+		return contentObj{kind: 's', str: str}, err
+	case c == '<' && s.pos+1 < len(s.b) && s.b[s.pos+1] == '<':
+		return contentObj{kind: 'o'}, s.skipDict()
+	case c == '<':
+		str, err := s.hexString()
+		return contentObj{kind: 's', str: str}, err
+	case c == '[':
+		s.pos++
+		var elems []contentObj
+		for {
+			s.skipSpace()
+			if s.pos >= len(s.b) {
+				return contentObj{}, fmt.Errorf("unterminated array")
+			}
+			if s.b[s.pos] == ']' {
+				s.pos++
+				return contentObj{kind: 'a', elems: elems}, nil
+			}
+			if isRegular(s.b[s.pos]) && !isNumberStart(s.b[s.pos]) {
+				s.keyword() // true, false, null
+				elems = append(elems, contentObj{kind: 'o'})
+				continue
+			}
+			elem, err := s.object()
+			if err != nil {
+				return contentObj{}, err
+			}
+			elems = append(elems, elem)
+		}
+	case c == '/':
+		s.pos++
+		return contentObj{kind: '/', name: s.keyword()}, nil
+	case isNumberStart(c):
+		kw := s.keyword()
+		n, err := strconv.ParseFloat(kw, 64)
+		if err != nil {
+			return contentObj{}, fmt.Errorf("invalid number %q", kw)
+		}
+		return contentObj{kind: 'n', num: n}, nil
+	}
+	return contentObj{}, fmt.Errorf("unexpected %q at offset %d", c, s.pos)
+}
+
+// literalString reads a (...) string, decoding its escapes.
+func (s *contentScanner) literalString() ([]byte, error) {
+	s.pos++
+	var out []byte
+	depth := 1
+	for s.pos < len(s.b) {
+		c := s.b[s.pos]
+		s.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, nil
+			}
+		case '\\':
+			if s.pos >= len(s.b) {
+				continue
+			}
+			e := s.b[s.pos]
+			s.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if s.pos < len(s.b) && s.b[s.pos] == '\n' {
+					s.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && s.pos < len(s.b) && s.b[s.pos] >= '0' && s.b[s.pos] <= '7'; i++ {
+						v = v*8 + int(s.b[s.pos]-'0')
+						s.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+// hexString reads a <...> string.
+func (s *contentScanner) hexString() ([]byte, error) {
+	s.pos++
+	var digits []byte
+	for s.pos < len(s.b) && s.b[s.pos] != '>' {
+		if !isSpace(s.b[s.pos]) {
+			digits = append(digits, s.b[s.pos])
+		}
+		s.pos++
+	}
+	if s.pos >= len(s.b) {
+		return nil, fmt.Errorf("unterminated hex string")
+	}
+	s.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string")
+		}
+		out[i] = byte(v)
+	}
+	return out, nil
+}
+
+// skipDict skips a <<...>> dictionary operand.
+func (s *contentScanner) skipDict() error {
+	s.pos += 2
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.b) {
+			return fmt.Errorf("unterminated dictionary")
+		}
+		if s.b[s.pos] == '>' && s.pos+1 < len(s.b) && s.b[s.pos+1] == '>' {
+			s.pos += 2
+			return nil
+		}
+		if isRegular(s.b[s.pos]) && !isNumberStart(s.b[s.pos]) {
+			s.keyword()
+			continue
+		}
+		if _, err := s.object(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipInlineImage skips from just after BI to just after the matching EI.
+func (s *contentScanner) skipInlineImage() error {
+	params := map[string]contentObj{}
+	var key string
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.b) {
+			return fmt.Errorf("unterminated inline image")
+		}
+		if isRegular(s.b[s.pos]) && !isNumberStart(s.b[s.pos]) {
+			kw := s.keyword()
+			if kw == "ID" {
+				break
+			}
+			if key != "" {
+				params[key], key = contentObj{kind: 'o'}, ""
+			}
+			continue
+		}
+		obj, err := s.object()
+		if err != nil {
+			return err
+		}
+		if key == "" && obj.kind == '/' {
+			key = obj.name
+			continue
+		}
+		if key != "" {
+			params[key], key = obj, ""
+		}
+	}
+	// The image data follows a single white-space byte.
+	s.pos++
+	if n := inlineImageSize(params); n > 0 && s.pos+n <= len(s.b) {
+		end := s.pos + n
+		for end < len(s.b) && isSpace(s.b[end]) {
+			end++
+		}
+		if end+1 < len(s.b) && s.b[end] == 'E' && s.b[end+1] == 'I' {
+			s.pos = end + 2
+			return nil
+		}
+	}
+	// Filtered or unusual data: look for an EI followed by white space.
+	for i := s.pos; i+1 < len(s.b); i++ {
+		if s.b[i] == 'E' && s.b[i+1] == 'I' && (i+2 == len(s.b) || isSpace(s.b[i+2])) {
+			s.pos = i + 2
+			return nil
+		}
+	}
+	return fmt.Errorf("unterminated inline image")
+}
+
+// inlineImageSize returns the length of the data of an unfiltered inline
+// image with the given parameters, or 0 if it cannot tell.
+func inlineImageSize(params map[string]contentObj) int {
+	get := func(long, short string) (contentObj, bool) {
+		if o, ok := params[long]; ok {
+			return o, true
+		}
+		o, ok := params[short]
+		return o, ok
+	}
+	if _, ok := get("Filter", "F"); ok {
+		return 0
+	}
+	w, okW := get("Width", "W")
+	h, okH := get("Height", "H")
+	if !okW || !okH || w.kind != 'n' || h.kind != 'n' {
+		return 0
+	}
+	bpc, colors := 1.0, 1
+	if o, ok := get("BitsPerComponent", "BPC"); ok && o.kind == 'n' {
+		bpc = o.num
+	}
+	if o, ok := get("ColorSpace", "CS"); ok {
+		switch o.name {
+		case "DeviceRGB", "RGB":
+			colors = 3
+		case "DeviceCMYK", "CMYK":
+			colors = 4
+		case "DeviceGray", "G", "Indexed", "I":
+		default:
+			return 0
+		}
+	}
+	rowBytes := (int(w.num)*colors*int(bpc) + 7) / 8
+	return rowBytes * int(h.num)
+}
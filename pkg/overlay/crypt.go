@@ -0,0 +1,101 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Permissions are the permissions Encrypt can grant the readers of a PDF who
+// don't have its owner password, by name.
+var Permissions = map[string]model.PermissionFlags{
+	"print":    model.PermissionPrintRev2 | model.PermissionPrintRev3,
+	"modify":   model.PermissionModify,
+	"extract":  model.PermissionExtract | model.PermissionExtractRev3,
+	"annotate": model.PermissionModAnnFillForm,
+	"fill":     model.PermissionFillRev3,
+	"assemble": model.PermissionAssembleRev3,
+}
+
+// Encryption says how Encrypt protects a PDF.
+type Encryption struct {
+	UserPassword  string // needed to open the PDF; empty lets anyone open it
+	OwnerPassword string // needed to lift the permissions
+	// Permissions is a comma-separated list of Permissions names, "all" or
+	// "none"; empty grants printing only.
+	Permissions string
+}
+
+// permissionFlags returns the flags of e.Permissions.
+func (e Encryption) permissionFlags() (model.PermissionFlags, error) {
+	switch e.Permissions {
+	case "":
+		return model.PermissionsPrint, nil
+	case "all":
+		return model.PermissionsAll, nil
+	case "none":
+		return model.PermissionsNone, nil
+	}
+	flags := model.PermissionsNone
+	for _, name := range strings.Split(e.Permissions, ",") {
+		p, ok := Permissions[strings.TrimSpace(name)]
+		if !ok {
+			names := make([]string, 0, len(Permissions))
+			for n := range Permissions {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return 0, fmt.Errorf("unknown permission %q (valid: all, none, %s)", name, strings.Join(names, ", "))
+		}
+		flags |= p
+	}
+	return flags, nil
+}
+
+// Validate reports what is wrong with e, if anything.
+func (e Encryption) Validate() error {
+	if e.OwnerPassword == "" {
+		return fmt.Errorf("encrypting needs an owner password")
+	}
+	_, err := e.permissionFlags()
+	return err
+}
+
+// Encrypt returns pdf encrypted with AES-256 as e says.
+func Encrypt(pdf []byte, e Encryption) ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	perms, _ := e.permissionFlags()
+	conf := model.NewAESConfiguration(e.UserPassword, e.OwnerPassword, 256)
+	conf.Permissions = perms
+	var buf bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(pdf), &buf, conf); err != nil {
+		return nil, fmt.Errorf("encrypting: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt returns pdf with its encryption removed, opening it with password,
+// its user or owner password. A PDF that isn't encrypted comes back as it
+// is.
+func Decrypt(pdf []byte, password string) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW, conf.OwnerPW = password, password
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	if ctx.Encrypt == nil {
+		return pdf, nil
+	}
+	var buf bytes.Buffer
+	if err := api.Decrypt(bytes.NewReader(pdf), &buf, conf); err != nil {
+		return nil, fmt.Errorf("decrypting: %w", readError(err))
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,292 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// maxOverlays is the most overlays DecodeOverlays returns, once groups are
+// expanded.
+const maxOverlays = 100_000
+
+// DecodeOverlays reads a JSON array of overlays from r. Unlike
+// json.Unmarshal it rejects fields OverlayRectText doesn't have, so a
+// misspelt field is an error rather than silently ignored, and every error
+// names the overlay's index and the field at fault. Each overlay must also
+// describe something to draw. A Group entry is replaced by its overlays,
+// so the overlays after it have higher indexes than their entries. There
+// may be at most 100000 overlays, groups expanded.
+func DecodeOverlays(r io.Reader) ([]OverlayRectText, error) {
+	// The whole document is read first so syntax errors can give a line.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading overlays: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if tok, err := dec.Token(); err != nil {
+		return nil, invalidf("reading overlays: %s", describeJSONError(err, data))
+	} else if tok != json.Delim('[') {
+		return nil, invalidf("overlays must be a JSON array, not %v", tok)
+	}
+
+	var overlays []OverlayRectText
+	for dec.More() {
+		// Each entry is read whole first to tell groups from overlays.
+		i := len(overlays)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, &OverlayError{i, errors.New(describeJSONError(err, data))}
+		}
+		g, isGroup, err := decodeGroup(raw)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		if isGroup {
+			if n := len(overlays) + g.Size(); n > maxOverlays {
+				return nil, &OverlayError{i, fmt.Errorf("group: expands to %d overlays in all, more than the %d allowed", n, maxOverlays)}
+			}
+			for j, ov := range g.Expand() {
+				if err := validateOverlay(ov); err != nil {
+					return nil, &OverlayError{i + j, fmt.Errorf("group row %d, overlay %d: %v", j/len(g.Overlays), j%len(g.Overlays), err)}
+				}
+				overlays = append(overlays, ov)
+			}
+			continue
+		}
+		var ov OverlayRectText
+		ovDec := json.NewDecoder(bytes.NewReader(raw))
+		ovDec.DisallowUnknownFields()
+		if err := ovDec.Decode(&ov); err != nil {
+			return nil, &OverlayError{i, errors.New(describeJSONError(err, raw))}
+		}
+		if err := validateOverlay(ov); err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		if len(overlays) == maxOverlays {
+			return nil, &OverlayError{i, fmt.Errorf("more than the %d overlays allowed", maxOverlays)}
+		}
+		overlays = append(overlays, ov)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, invalidf("after overlay %d: %s", len(overlays)-1, describeJSONError(err, data))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, invalidf("unexpected data after the overlays array")
+	}
+	return overlays, nil
+}
+
+// describeJSONError rewrites the decoding errors a typo produces to name
+// the field involved, or where in data the syntax goes wrong.
+func describeJSONError(err error, data []byte) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		before := data[:min(int(syntaxErr.Offset), len(data))]
+		line := bytes.Count(before, []byte("\n")) + 1
+		col := len(before) - bytes.LastIndexByte(before, '\n')
+		return fmt.Sprintf("line %d, column %d: %v", line, col, err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("field %q: expected %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	}
+	// encoding/json reports unknown fields only in the message text.
+	const unknownPrefix = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownPrefix) {
+		name := strings.Trim(strings.TrimPrefix(msg, unknownPrefix), `"`)
+		if suggestion := closestField(name); suggestion != "" {
+			return fmt.Sprintf("unknown field %q (did you mean %q?)", name, suggestion)
+		}
+		return fmt.Sprintf("unknown field %q", name)
+	}
+	return err.Error()
+}
+
+// jsonTypeName describes the Go type t as the JSON value it is read from.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.String:
+		return "a string"
+	}
+	return t.String()
+}
+
+// closestField returns the OverlayRectText JSON field name nearest to name,
+// or "" if none is close.
+func closestField(name string) string {
+	best, bestDist := "", 3 // suggest only within two edits
+	t := reflect.TypeOf(OverlayRectText{})
+	for i := 0; i < t.NumField(); i++ {
+		field, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if d := editDistance(strings.ToLower(name), strings.ToLower(field)); d < bestDist {
+			best, bestDist = field, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// validateOverlay checks that ov describes something to draw or fill and
+// that its size makes sense.
+func validateOverlay(ov OverlayRectText) error {
+	if ov.Width < 0 || ov.Height < 0 {
+		return fmt.Errorf("width and height must not be negative, got %gx%g", ov.Width, ov.Height)
+	}
+	hasBox := ov.Width > 0 && ov.Height > 0
+	if strings.TrimSpace(ov.Text) == "" && ov.Label == "" && ov.Field == "" &&
+		!hasBox && ov.Ops == "" && !hasImage(ov) && ov.Type == "" {
+		switch {
+		case ov.Width > 0:
+			return fmt.Errorf("nothing to draw: a rectangle needs a height as well as its width of %g", ov.Width)
+		case ov.Height > 0:
+			return fmt.Errorf("nothing to draw: a rectangle needs a width as well as its height of %g", ov.Height)
+		}
+		return fmt.Errorf("nothing to draw: set text, label or field, or a non-zero width and height")
+	}
+	if ov.TextScale < 0 {
+		return fmt.Errorf("textScale must not be negative, got %g", ov.TextScale)
+	}
+	if ov.LineSpacing < 0 {
+		return fmt.Errorf("lineSpacing must not be negative, got %g", ov.LineSpacing)
+	}
+	if ov.FontSize < 0 {
+		return fmt.Errorf("fontSize must not be negative, got %d", ov.FontSize)
+	}
+	if ov.MinFontSize < 0 {
+		return fmt.Errorf("minFontSize must not be negative, got %d", ov.MinFontSize)
+	}
+	if ov.Fit && ov.AutoFit {
+		return fmt.Errorf("set fit or autoFit, not both")
+	}
+	if ov.Scale <= 0 && (hasBox || hasImage(ov) || ov.Type == "line") {
+		return fmt.Errorf("scale must be positive, got %g", ov.Scale)
+	}
+	if ov.Redact {
+		switch {
+		case ov.Type == "line":
+			return fmt.Errorf("redact needs a rectangle to clear, which a line doesn't have")
+		case !hasBox && !hasImage(ov):
+			return fmt.Errorf("redact needs a rectangle to clear: set a non-zero width and height")
+		}
+	}
+	if ov.ImagePath != "" && ov.ImageData != "" {
+		return fmt.Errorf("set imagePath or imageData, not both")
+	}
+	switch ov.Type {
+	case "line":
+		if ov.Width == 0 && ov.Height == 0 {
+			return fmt.Errorf("a line needs a non-zero width or height")
+		}
+	case "rect":
+		if !hasBox {
+			return fmt.Errorf("a rect needs a non-zero width and height")
+		}
+	case "image":
+		if !hasImage(ov) {
+			return fmt.Errorf("an image needs imagePath or imageData")
+		}
+	case "barcode", "qrcode":
+		if !hasBox {
+			return fmt.Errorf("a %s needs a non-zero width and height", ov.Type)
+		}
+		if ov.Content == "" {
+			return fmt.Errorf("a %s needs content to encode", ov.Type)
+		}
+	}
+	if ov.BorderWidth < 0 {
+		return fmt.Errorf("field \"borderWidth\": must not be negative, got %g", ov.BorderWidth)
+	}
+	if len(ov.Dash) > 0 {
+		total := 0.0
+		for _, d := range ov.Dash {
+			if d < 0 {
+				return fmt.Errorf("field \"dash\": lengths must not be negative, got %g", d)
+			}
+			total += d
+		}
+		if total == 0 {
+			return fmt.Errorf("field \"dash\": lengths must not all be 0")
+		}
+	}
+	return checkFields(ov)
+}
+
+// checkFields checks the enumerated and formatted fields of ov, such as
+// colours, anchor and units, which drawing would otherwise only reject
+// once it gets to ov.
+func checkFields(ov OverlayRectText) error {
+	for _, c := range []struct{ field, value string }{
+		{"fillColor", ov.FillColor}, {"borderColor", ov.BorderColor}, {"textColor", ov.TextColor},
+	} {
+		if c.value == "" || c.value == "none" && c.field == "fillColor" {
+			continue
+		}
+		if _, err := parseColor(c.value); err != nil {
+			return fmt.Errorf("field %q: %v", c.field, err)
+		}
+	}
+	if _, err := opacityFor(ov); err != nil {
+		return fmt.Errorf("field \"opacity\": %v", err)
+	}
+	anchor, err := anchorFor(ov)
+	if err != nil {
+		return fmt.Errorf("field \"anchor\": %v", err)
+	}
+	if _, err := fromTopFor(ov, anchor); err != nil {
+		return fmt.Errorf("field \"origin\": %v", err)
+	}
+	if ov.FindText != "" && anchor != "bl" {
+		return fmt.Errorf("field \"findText\": cannot be combined with anchor %q", anchor)
+	}
+	if ov.FindOccurrence < 0 {
+		return fmt.Errorf("field \"findOccurrence\": must not be negative, got %d", ov.FindOccurrence)
+	}
+	if _, err := percentUnits(ov); err != nil {
+		return fmt.Errorf("field \"units\": %v", err)
+	}
+	if err := checkAlign(ov); err != nil {
+		return err
+	}
+	if _, err := codeSymbology(ov); err != nil {
+		return err
+	}
+	if ov.ImageData != "" {
+		if _, err := imageFor(ov); err != nil {
+			return err
+		}
+	}
+	return nil
+}
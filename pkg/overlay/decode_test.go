@@ -0,0 +1,38 @@
+package overlay
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestDecodeOverlaysLimits checks that groups can't expand to more
+// overlays than DecodeOverlays allows, before any are made.
+func TestDecodeOverlaysLimits(t *testing.T) {
+	tests := []struct {
+		name, json string
+		wantErr    string // "" if the overlays decode
+	}{
+		{"repeat at the limit", fmt.Sprintf(`[{"group":{"repeat":%d,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}}]`, maxGroupRepeat), ""},
+		{"huge repeat", `[{"group":{"repeat":3000000000,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}}]`, "repeat must be from 0 to"},
+		{"repeat over the limit", fmt.Sprintf(`[{"group":{"repeat":%d,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}}]`, maxGroupRepeat+1), "repeat must be from 0 to"},
+		{"negative repeat", `[{"group":{"repeat":-1,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}}]`, "repeat must be from 0 to"},
+		{"too many in all", `[` + strings.Repeat(fmt.Sprintf(`{"group":{"repeat":%d,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}},`, maxGroupRepeat), maxOverlays/maxGroupRepeat) +
+			`{"text":"x","x":1,"y":1,"scale":1}]`, "more than the 100000 overlays allowed"},
+		{"too many from groups", `[{"text":"x","x":1,"y":1,"scale":1},` + strings.Repeat(fmt.Sprintf(`{"group":{"repeat":%d,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}},`, maxGroupRepeat), maxOverlays/maxGroupRepeat-1) +
+			fmt.Sprintf(`{"group":{"repeat":%d,"overlays":[{"text":"x","x":1,"y":1,"scale":1}]}}]`, maxGroupRepeat), "expands to 100001 overlays in all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeOverlays(strings.NewReader(tt.json))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("DecodeOverlays: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("DecodeOverlays succeeded, want an error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("DecodeOverlays: %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
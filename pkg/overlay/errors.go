@@ -0,0 +1,52 @@
+package overlay
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors of this package wrap one of these, by what went wrong, so that
+// callers can tell the kinds of failure apart with errors.Is.
+var (
+	// ErrInvalidOverlay is an overlay list that cannot be decoded, or an
+	// overlay that cannot be drawn as it is described.
+	ErrInvalidOverlay = errors.New("invalid overlay")
+	// ErrInvalidPDF is a PDF that cannot be read.
+	ErrInvalidPDF = errors.New("failed reading PDF")
+	// ErrTooLarge is an output over its size limit even after optimizing,
+	// or once signed, encrypted or linearized.
+	ErrTooLarge = errors.New("output too large")
+)
+
+// OverlayError is an error in the overlay at Index of a list. It is an
+// ErrInvalidOverlay.
+type OverlayError struct {
+	Index int
+	Err   error
+}
+
+func (e *OverlayError) Error() string {
+	return fmt.Sprintf("overlay %d: %v", e.Index, e.Err)
+}
+
+func (e *OverlayError) Unwrap() error { return e.Err }
+
+func (e *OverlayError) Is(target error) bool { return target == ErrInvalidOverlay }
+
+// invalidError is an error in an overlay list as a whole, such as its
+// syntax. It is an ErrInvalidOverlay.
+type invalidError struct{ msg string }
+
+// invalidf returns an invalidError with the message of format and args.
+func invalidf(format string, args ...any) error {
+	return &invalidError{fmt.Sprintf(format, args...)}
+}
+
+func (e *invalidError) Error() string { return e.msg }
+
+func (e *invalidError) Is(target error) bool { return target == ErrInvalidOverlay }
+
+// readError returns the error of reading a PDF that fails with err.
+func readError(err error) error {
+	return fmt.Errorf("%w: %v", ErrInvalidPDF, err)
+}
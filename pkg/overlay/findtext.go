@@ -0,0 +1,183 @@
+package overlay
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// LocateText returns a copy of overlays with those that have a FindText
+// placed where it is on pdf: each one's X and Y become the page position of
+// the match plus their offsets, in points from the bottom-left corner, and
+// its Pages the page of the match. Overlays without a FindText are kept as
+// they are, and if there are none pdf isn't read. A FindText pdf doesn't
+// show is an ErrInvalidOverlay.
+func LocateText(pdf []byte, overlays []OverlayRectText) ([]OverlayRectText, error) {
+	if !slices.ContainsFunc(overlays, func(ov OverlayRectText) bool { return ov.FindText != "" }) {
+		return overlays, nil
+	}
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+	all, err := pagesFor("", ctx.PageCount)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := pageTextRuns(ctx, boundaries, all, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]OverlayRectText, len(overlays))
+	copy(out, overlays)
+	for i, ov := range out {
+		if ov.FindText == "" {
+			continue
+		}
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		match, ok := findText(runs, pages, ov.FindText, max(ov.FindOccurrence, 1))
+		if !ok {
+			where := "in the PDF"
+			if ov.Pages != "" {
+				where = "on pages " + ov.Pages
+			}
+			if ov.FindOccurrence > 1 {
+				return nil, &OverlayError{i, fmt.Errorf("findText %q: found fewer than %d times %s", ov.FindText, ov.FindOccurrence, where)}
+			}
+			return nil, &OverlayError{i, fmt.Errorf("findText %q: not found %s", ov.FindText, where)}
+		}
+		pageW, pageH := viewOf(boundaries[match.Page-1]).size()
+		fromTop := ov.Origin == "tl"
+		ov = inPoints(ov, pageW, pageH)
+		ov.X += match.X
+		if fromTop {
+			// Y is down from the top of the match to the top of the box.
+			ov.Y = match.Y + match.Height - ov.Y - ov.Height
+		} else {
+			ov.Y += match.Y
+		}
+		ov.Origin, ov.Pages, ov.FindText, ov.FindOccurrence = "", strconv.Itoa(match.Page), "", 0
+		out[i] = ov
+	}
+	return out, nil
+}
+
+// textLine is the text runs of a page on one line, left to right, and
+// their text joined into one string, with a space where there is a gap
+// between them.
+type textLine struct {
+	runs   []TextRun
+	starts []int // where the text of each run starts in text
+	text   string
+}
+
+// textLines returns the lines of runs, page by page, top to bottom. Runs
+// are on one line when their boxes overlap by at least half the height of
+// the smaller one.
+func textLines(runs []TextRun) []textLine {
+	sorted := slices.Clone(runs)
+	slices.SortStableFunc(sorted, func(a, b TextRun) int {
+		if a.Page != b.Page {
+			return a.Page - b.Page
+		}
+		return cmp.Compare(b.Y+b.Height, a.Y+a.Height)
+	})
+	var lines [][]TextRun
+	for _, r := range sorted {
+		if n := len(lines); n > 0 {
+			last := lines[n-1][0]
+			overlap := min(last.Y+last.Height, r.Y+r.Height) - max(last.Y, r.Y)
+			if last.Page == r.Page && overlap >= min(last.Height, r.Height)/2 {
+				lines[n-1] = append(lines[n-1], r)
+				continue
+			}
+		}
+		lines = append(lines, []TextRun{r})
+	}
+	out := make([]textLine, len(lines))
+	for i, runs := range lines {
+		slices.SortStableFunc(runs, func(a, b TextRun) int { return cmp.Compare(a.X, b.X) })
+		var b strings.Builder
+		l := textLine{runs: runs}
+		for j, r := range runs {
+			if j > 0 {
+				prev := runs[j-1]
+				if r.X-(prev.X+prev.Width) > min(prev.Height, r.Height)/5 {
+					b.WriteByte(' ')
+				}
+			}
+			l.starts = append(l.starts, b.Len())
+			b.WriteString(r.Text)
+		}
+		l.text = b.String()
+		out[i] = l
+	}
+	return out
+}
+
+// findText returns the box of the nth occurrence of s, counting from 1, on
+// the lines of runs on pages, in page order, top to bottom and left to
+// right. White space in s matches a gap between runs. A match that takes
+// up part of a run gets the part of its box s takes up, as if its
+// characters were all as wide.
+func findText(runs []TextRun, pages map[int]bool, s string, n int) (TextRun, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, l := range textLines(runs) {
+		if !pages[l.runs[0].Page] {
+			continue
+		}
+		for from := 0; ; {
+			j := strings.Index(l.text[from:], s)
+			if j < 0 {
+				break
+			}
+			j += from
+			if n--; n == 0 {
+				return l.box(j, j+len(s)), true
+			}
+			from = j + len(s)
+		}
+	}
+	return TextRun{}, false
+}
+
+// box returns the box of the bytes from start to end of the text of l.
+func (l textLine) box(start, end int) TextRun {
+	first, _ := slices.BinarySearch(l.starts, start+1)
+	last, _ := slices.BinarySearch(l.starts, end)
+	first, last = first-1, last-1
+	// x returns the x of the byte at i of the text of run k.
+	x := func(k, i int) float64 {
+		r := l.runs[k]
+		chars := utf8.RuneCountInString(r.Text)
+		i = min(max(i-l.starts[k], 0), len(r.Text))
+		return r.X + r.Width*float64(utf8.RuneCountInString(r.Text[:i]))/float64(max(chars, 1))
+	}
+	m := TextRun{Page: l.runs[first].Page, Text: l.text[start:end], Y: math.Inf(1)}
+	top := math.Inf(-1)
+	for _, r := range l.runs[first : last+1] {
+		m.Y = min(m.Y, r.Y)
+		top = max(top, r.Y+r.Height)
+	}
+	m.X = round2(x(first, start))
+	m.Width = round2(x(last, end) - m.X)
+	m.Y, m.Height = round2(m.Y), round2(top-m.Y)
+	return m
+}
@@ -0,0 +1,224 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// annotHidden and annotNoView are the annotation flags that keep a widget
+// from being shown.
+const (
+	annotHidden = 1 << 1
+	annotNoView = 1 << 5
+)
+
+// FlattenForm reads a PDF with an AcroForm from in and writes it to out with
+// every form field drawn into its page as the field appears, and the fields
+// themselves removed, so their values can no longer be edited. Hidden fields
+// and fields without an appearance disappear.
+func FlattenForm(in io.Reader, out io.Writer) error {
+	pdf, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return readError(err)
+	}
+	for page := 1; page <= ctx.PageCount; page++ {
+		if err := flattenPage(ctx.XRefTable, page); err != nil {
+			return fmt.Errorf("page %d: %v", page, err)
+		}
+	}
+	root, err := ctx.XRefTable.Catalog()
+	if err != nil {
+		return err
+	}
+	root.Delete("AcroForm")
+	if err := api.Write(ctx, out, conf); err != nil {
+		return fmt.Errorf("writing PDF: %v", err)
+	}
+	return nil
+}
+
+// flattenPage draws the normal appearance of every widget annotation of page
+// pageNr into its content and removes the widgets.
+func flattenPage(xRefTable *model.XRefTable, pageNr int) error {
+	d, _, inhPAttrs, err := xRefTable.PageDict(pageNr, true)
+	if err != nil {
+		return err
+	}
+	annots, err := xRefTable.DereferenceArray(d["Annots"])
+	if err != nil || len(annots) == 0 {
+		return err
+	}
+
+	// The consolidated resources are a copy, so the appearances can be added
+	// to them and the result made the page's own.
+	resources := inhPAttrs.Resources
+	if resources == nil {
+		resources = types.Dict{}
+	}
+	xObjects, _ := resources["XObject"].(types.Dict)
+	if xObjects == nil {
+		xObjects = types.Dict{}
+		resources["XObject"] = xObjects
+	}
+
+	var keep types.Array
+	var content bytes.Buffer
+	for _, a := range annots {
+		ad, err := xRefTable.DereferenceDict(a)
+		if err != nil {
+			return err
+		}
+		if subtype := ad.NameEntry("Subtype"); subtype == nil || *subtype != "Widget" {
+			keep = append(keep, a)
+			continue
+		}
+		if f := ad.IntEntry("F"); f != nil && *f&(annotHidden|annotNoView) != 0 {
+			continue
+		}
+		ap, err := widgetAppearance(xRefTable, ad)
+		if err != nil {
+			return err
+		}
+		if ap == nil {
+			continue
+		}
+		m, err := appearanceMatrix(xRefTable, ad, *ap)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			continue
+		}
+		name := fmt.Sprintf("Flat%d", len(xObjects))
+		for xObjects[name] != nil {
+			name += "x"
+		}
+		xObjects[name] = *ap
+		fmt.Fprintf(&content, "q %.4f %.4f %.4f %.4f %.4f %.4f cm /%s Do Q\n", m[0], m[1], m[2], m[3], m[4], m[5], name)
+	}
+	if len(keep) == 0 {
+		d.Delete("Annots")
+	} else {
+		d.Update("Annots", keep)
+	}
+	if content.Len() == 0 {
+		return nil
+	}
+	d.Update("Resources", resources)
+
+	// The old content is wrapped in q ... Q so any transformation it leaves
+	// in place doesn't move the fields.
+	var parts types.Array
+	if old, err := xRefTable.Dereference(d["Contents"]); err != nil {
+		return err
+	} else if a, ok := old.(types.Array); ok {
+		parts = a
+	} else if old != nil {
+		parts = types.Array{d["Contents"]}
+	}
+	before, err := newContentStream(xRefTable, []byte("q\n"))
+	if err != nil {
+		return err
+	}
+	after, err := newContentStream(xRefTable, append([]byte("Q\n"), content.Bytes()...))
+	if err != nil {
+		return err
+	}
+	d.Update("Contents", append(append(types.Array{*before}, parts...), *after))
+	return nil
+}
+
+// widgetAppearance returns the normal appearance stream of the widget
+// annotation ad in its current state, or nil if it has none.
+func widgetAppearance(xRefTable *model.XRefTable, ad types.Dict) (*types.IndirectRef, error) {
+	ap, err := xRefTable.DereferenceDict(ad["AP"])
+	if err != nil || ap == nil {
+		return nil, err
+	}
+	n := ap["N"]
+	o, err := xRefTable.Dereference(n)
+	if err != nil {
+		return nil, err
+	}
+	// Check boxes and radio buttons have an appearance per state.
+	if states, ok := o.(types.Dict); ok {
+		state := ad.NameEntry("AS")
+		if state == nil {
+			return nil, nil
+		}
+		n = states[*state]
+	}
+	if ir, ok := n.(types.IndirectRef); ok {
+		return &ir, nil
+	}
+	return nil, nil
+}
+
+// appearanceMatrix returns the matrix that fits the appearance stream ap into
+// the rectangle of the widget annotation ad, as a PDF viewer draws it, or nil
+// if either is empty.
+func appearanceMatrix(xRefTable *model.XRefTable, ad types.Dict, ap types.IndirectRef) (*matrix, error) {
+	sd, _, err := xRefTable.DereferenceStreamDict(ap)
+	if err != nil || sd == nil {
+		return nil, err
+	}
+	rect, err := annotRect(xRefTable, ad["Rect"])
+	if err != nil {
+		return nil, err
+	}
+	bbox, err := annotRect(xRefTable, sd.Dict["BBox"])
+	if err != nil {
+		return nil, err
+	}
+	m := identity
+	if a, err := xRefTable.DereferenceArray(sd.Dict["Matrix"]); err != nil {
+		return nil, err
+	} else if len(a) == 6 {
+		for i, o := range a {
+			if m[i], err = xRefTable.DereferenceNumber(o); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t := m.bounds(bbox.LL.X, bbox.LL.Y, bbox.UR.X, bbox.UR.Y)
+	if t.Width() == 0 || t.Height() == 0 || rect.Width() == 0 || rect.Height() == 0 {
+		return nil, nil
+	}
+	// Scale the transformed box to the rectangle; Do applies Matrix itself.
+	sx, sy := rect.Width()/t.Width(), rect.Height()/t.Height()
+	return &matrix{sx, 0, 0, sy, rect.LL.X - t.LL.X*sx, rect.LL.Y - t.LL.Y*sy}, nil
+}
+
+// annotRect reads the rectangle array o.
+func annotRect(xRefTable *model.XRefTable, o types.Object) (*types.Rectangle, error) {
+	a, err := xRefTable.DereferenceArray(o)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) != 4 {
+		return nil, fmt.Errorf("invalid rectangle %v", o)
+	}
+	return xRefTable.RectForArray(a)
+}
+
+// newContentStream adds a content stream holding b to xRefTable.
+func newContentStream(xRefTable *model.XRefTable, b []byte) (*types.IndirectRef, error) {
+	sd, err := xRefTable.NewStreamDictForBuf(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.Encode(); err != nil {
+		return nil, err
+	}
+	return xRefTable.IndRefForNewObject(*sd)
+}
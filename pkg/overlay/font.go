@@ -0,0 +1,152 @@
+package overlay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+var (
+	fontFilesMu sync.Mutex
+	fontFiles   = map[string]string{} // TrueType file path => installed font name
+)
+
+// coreFontFaces lists the faces of the core font families: regular, bold,
+// italic and bold italic.
+var coreFontFaces = [][4]string{
+	{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique"},
+	{"Times-Roman", "Times-Bold", "Times-Italic", "Times-BoldItalic"},
+	{"Courier", "Courier-Bold", "Courier-Oblique", "Courier-BoldOblique"},
+}
+
+// styledFont returns the face of the core font family of name that is bold
+// and/or italic, where "" means Helvetica.
+func styledFont(name string, bold, italic bool) (string, error) {
+	if name == "" {
+		name = defaultFontName
+	}
+	face := 0
+	if bold {
+		face |= 1
+	}
+	if italic {
+		face |= 2
+	}
+	for _, faces := range coreFontFaces {
+		for _, f := range faces {
+			if f == name {
+				return faces[face], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("bold and italic need a Helvetica, Times or Courier font, not %q", name)
+}
+
+// fontFor returns the font named by ov, or "" to keep pdfcpu's default. The
+// name must be one of pdfcpu's core fonts or an installed user font. A
+// FontFile is installed first and takes precedence over Font.
+func fontFor(ov OverlayRectText) (string, error) {
+	if ov.FontFile != "" && (ov.Bold || ov.Italic) {
+		return "", fmt.Errorf("bold and italic cannot restyle fontFile %s: use the file of its bold or italic face", ov.FontFile)
+	}
+	if ov.FontFile != "" {
+		name, err := installFontFile(ov.FontFile)
+		if err != nil {
+			return "", fmt.Errorf("fontFile %s: %v", ov.FontFile, err)
+		}
+		if err := checkGlyphs(name, ov.Text); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	if ov.Bold || ov.Italic {
+		return styledFont(ov.Font, ov.Bold, ov.Italic)
+	}
+	if ov.Font == "" || font.SupportedFont(ov.Font) {
+		return ov.Font, nil
+	}
+	names := append(font.CoreFontNames(), font.UserFontNames()...)
+	sort.Strings(names)
+	return "", fmt.Errorf("unsupported font %q (valid: %s)", ov.Font, strings.Join(names, ", "))
+}
+
+// installFontFile installs the TrueType font at path into pdfcpu's user font
+// directory, where pdfcpu looks when it embeds the font, and returns the name
+// to reference it by. Each path is installed once per process.
+func installFontFile(path string) (string, error) {
+	fontFilesMu.Lock()
+	defer fontFilesMu.Unlock()
+	if name, ok := fontFiles[path]; ok {
+		return name, nil
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".ttf") {
+		return "", fmt.Errorf("only .ttf fonts are supported")
+	}
+	if font.UserFontDir == "" {
+		// Loading the configuration sets up pdfcpu's font directory.
+		model.NewDefaultConfiguration()
+	}
+	if font.UserFontDir == "" {
+		return "", fmt.Errorf("pdfcpu has no user font directory")
+	}
+
+	// pdfcpu names an installed font after its PostScript name. Install into
+	// a scratch directory first to learn it.
+	tmpDir, err := os.MkdirTemp("", "font_*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := font.InstallTrueTypeFont(tmpDir, path); err != nil {
+		return "", err
+	}
+	matches, err := filepath.Glob(filepath.Join(tmpDir, "*.gob"))
+	if err != nil || len(matches) != 1 {
+		return "", fmt.Errorf("could not determine the font name")
+	}
+	name := strings.TrimSuffix(filepath.Base(matches[0]), ".gob")
+
+	if err := os.MkdirAll(font.UserFontDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := font.InstallTrueTypeFont(font.UserFontDir, path); err != nil {
+		return "", err
+	}
+	if err := font.LoadUserFonts(); err != nil {
+		return "", err
+	}
+	fontFiles[path] = name
+	return name, nil
+}
+
+// checkGlyphs reports an error naming the characters of text that the user
+// font fontName has no glyphs for; pdfcpu would silently drop them.
+func checkGlyphs(fontName, text string) error {
+	font.UserFontMetricsLock.RLock()
+	ttf, ok := font.UserFontMetrics[fontName]
+	font.UserFontMetricsLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("font %q is not installed", fontName)
+	}
+	var missing []string
+	seen := map[rune]bool{}
+	for _, r := range text {
+		if r == '\n' || seen[r] {
+			continue
+		}
+		seen[r] = true
+		if _, ok := ttf.Chars[uint32(r)]; !ok {
+			missing = append(missing, fmt.Sprintf("%q", r))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("font %q has no glyphs for %s", fontName, strings.Join(missing, ", "))
+	}
+	return nil
+}
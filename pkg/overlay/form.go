@@ -0,0 +1,216 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/form"
+)
+
+// FillForm reads a PDF with an AcroForm from in, fills the field named by each
+// overlay's Field with the overlay's Text, and writes the result to out. The
+// fields stay editable. Every Field must name a field (by name or ID) that
+// exists in the form.
+func FillForm(in io.Reader, out io.Writer, overlays []OverlayRectText) error {
+	pdf, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	fg, err := api.ExportForm(bytes.NewReader(pdf), "template", nil)
+	if err != nil {
+		return fmt.Errorf("reading form fields: %w", readError(err))
+	}
+	f := &fg.Forms[0]
+
+	var unknown []string
+	for i, ov := range overlays {
+		if ov.Field == "" {
+			return &OverlayError{i, errors.New("form mode needs a field name")}
+		}
+		found, err := setFormValue(f, ov.Field, ov.Text)
+		if err != nil {
+			return &OverlayError{i, fmt.Errorf("field %q: %v", ov.Field, err)}
+		}
+		if !found {
+			unknown = append(unknown, ov.Field)
+		}
+	}
+	if len(unknown) > 0 {
+		return invalidf("unknown form fields %s (form has: %s)",
+			strings.Join(unknown, ", "), strings.Join(formFieldNames(f), ", "))
+	}
+
+	data, err := json.Marshal(fg)
+	if err != nil {
+		return err
+	}
+	if err := api.FillForm(bytes.NewReader(pdf), bytes.NewReader(data), out, nil); err != nil {
+		return fmt.Errorf("filling form: %v", err)
+	}
+	return nil
+}
+
+// DecodeFormValues reads the values of form fields by name, as a JSON object
+// or, with format "csv", as CSV rows of a field name and its value (after an
+// optional "field,value" header), into overlays for FillForm. JSON values may
+// be strings, numbers, true or false, or, for list boxes, arrays of strings.
+func DecodeFormValues(data []byte, format string) ([]OverlayRectText, error) {
+	values := map[string]string{}
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			return nil, invalidf("reading form values: %v", err)
+		}
+		for name, v := range m {
+			text, err := formValueText(v)
+			if err != nil {
+				return nil, invalidf("field %q: %v", name, err)
+			}
+			values[name] = text
+		}
+	case "csv":
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, invalidf("reading form values: %v", err)
+		}
+		for i, row := range rows {
+			if len(row) != 2 {
+				return nil, invalidf("row %d: want a field name and a value, got %d columns", i+1, len(row))
+			}
+			if i == 0 && strings.EqualFold(row[0], "field") && strings.EqualFold(row[1], "value") {
+				continue
+			}
+			if _, dup := values[row[0]]; dup {
+				return nil, invalidf("row %d: field %q is given twice", i+1, row[0])
+			}
+			values[row[0]] = row[1]
+		}
+	default:
+		return nil, invalidf("unknown form values format %q (valid: json, csv)", format)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	overlays := make([]OverlayRectText, len(names))
+	for i, name := range names {
+		overlays[i] = OverlayRectText{Field: name, Text: values[name]}
+	}
+	return overlays, nil
+}
+
+// formValueText returns the JSON value v as setFormValue takes it.
+func formValueText(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("list values must be strings")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("want a string, number, true or false, or a list of strings")
+}
+
+// setFormValue sets the value of the field called name (or with that ID) in f
+// from its string representation. It reports whether such a field exists.
+func setFormValue(f *form.Form, name, value string) (bool, error) {
+	matches := func(id, n string) bool { return id == name || n == name }
+
+	for _, tf := range f.TextFields {
+		if matches(tf.ID, tf.Name) {
+			tf.Value = value
+			return true, nil
+		}
+	}
+	for _, df := range f.DateFields {
+		if matches(df.ID, df.Name) {
+			df.Value = value
+			return true, nil
+		}
+	}
+	for _, cb := range f.CheckBoxes {
+		if matches(cb.ID, cb.Name) {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return true, fmt.Errorf("checkbox value must be true or false, got %q", value)
+			}
+			cb.Value = v
+			return true, nil
+		}
+	}
+	for _, rb := range f.RadioButtonGroups {
+		if matches(rb.ID, rb.Name) {
+			rb.Value = value
+			return true, nil
+		}
+	}
+	for _, cb := range f.ComboBoxes {
+		if matches(cb.ID, cb.Name) {
+			cb.Value = value
+			return true, nil
+		}
+	}
+	for _, lb := range f.ListBoxes {
+		if matches(lb.ID, lb.Name) {
+			lb.Values = strings.Split(value, ",")
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// formFieldNames returns the sorted names of all fields in f, falling back to
+// the field ID for unnamed fields.
+func formFieldNames(f *form.Form) []string {
+	var names []string
+	add := func(id, name string) {
+		if name == "" {
+			name = id
+		}
+		names = append(names, name)
+	}
+	for _, x := range f.TextFields {
+		add(x.ID, x.Name)
+	}
+	for _, x := range f.DateFields {
+		add(x.ID, x.Name)
+	}
+	for _, x := range f.CheckBoxes {
+		add(x.ID, x.Name)
+	}
+	for _, x := range f.RadioButtonGroups {
+		add(x.ID, x.Name)
+	}
+	for _, x := range f.ComboBoxes {
+		add(x.ID, x.Name)
+	}
+	for _, x := range f.ListBoxes {
+		add(x.ID, x.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Spacing of the lines of GridOverlays, in points.
+const (
+	GridMinor = 10
+	GridMajor = 50
+)
+
+// GridOverlays returns overlays that draw a coordinate grid over the pages of
+// pdf, to help place other overlays: a light line every minor points and a
+// darker one, labelled with its coordinate along the bottom and left edges,
+// every major points. The coordinates are those of overlays with the default
+// bl origin and pt units. Pages of different sizes get grids of their own.
+func GridOverlays(pdf []byte, minor, major float64) ([]OverlayRectText, error) {
+	if minor <= 0 || major < minor {
+		return nil, fmt.Errorf("invalid grid spacing %g/%g", minor, major)
+	}
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	// The pages of each size, in the order the sizes first appear.
+	type size struct{ w, h float64 }
+	var sizes []size
+	pages := map[size][]string{}
+	for i, b := range boundaries {
+		w, h := viewOf(b).size()
+		s := size{w, h}
+		if pages[s] == nil {
+			sizes = append(sizes, s)
+		}
+		pages[s] = append(pages[s], strconv.Itoa(i+1))
+	}
+
+	var overlays []OverlayRectText
+	for _, s := range sizes {
+		selection := strings.Join(pages[s], ",")
+		var minorOps, majorOps strings.Builder
+		var labels []OverlayRectText
+		line := func(x0, y0, x1, y1, at float64, label OverlayRectText) {
+			if math.Abs(math.Remainder(at, major)) < 1e-6 {
+				fmt.Fprintf(&majorOps, "%g %g m %g %g l ", x0, y0, x1, y1)
+				label.Text = strconv.FormatFloat(at, 'f', -1, 64)
+				labels = append(labels, label)
+			} else {
+				fmt.Fprintf(&minorOps, "%g %g m %g %g l ", x0, y0, x1, y1)
+			}
+		}
+		for i := 1; float64(i)*minor < s.w; i++ {
+			x := float64(i) * minor
+			line(x, 0, x, s.h, x, gridLabel(x+1, 2, selection))
+		}
+		for i := 1; float64(i)*minor < s.h; i++ {
+			y := float64(i) * minor
+			line(0, y, s.w, y, y, gridLabel(2, y+1, selection))
+		}
+		overlays = append(overlays, OverlayRectText{
+			Width: s.w, Height: s.h, Scale: 1, Anchor: "bl", Origin: "bl", Units: "pt",
+			FillColor: "none", Pages: selection,
+			Ops: "q 0.80 0.85 1 RG 0.25 w " + minorOps.String() + "S Q " +
+				"q 0.45 0.55 0.95 RG 0.5 w " + majorOps.String() + "S Q",
+		})
+		overlays = append(overlays, labels...)
+	}
+	return overlays, nil
+}
+
+// gridLabel returns a label overlay of GridOverlays at (x, y) on pages.
+func gridLabel(x, y float64, pages string) OverlayRectText {
+	return OverlayRectText{
+		X: x, Y: y, Anchor: "bl", Origin: "bl", Units: "pt",
+		FontSize: 6, TextColor: "#3050c0", Pages: pages,
+	}
+}
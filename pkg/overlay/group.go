@@ -0,0 +1,91 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RowPlaceholder is replaced by the row number, from 0, in the text, field
+// and content of the overlays of a Group, so each row can show its own
+// values, as in {{(index .Stub.Earnings {row}).Description}}.
+const RowPlaceholder = "{row}"
+
+// maxGroupRepeat is the most rows a Group may have.
+const maxGroupRepeat = 10_000
+
+// Group is an entry of an overlay list that stands for its Overlays laid
+// out as rows, such as the lines of an earnings table, so the layout of a
+// row is described once. It is written as an object with only a "group"
+// field:
+//
+//	{"group": {"x": 50, "y": 500, "dy": -14, "repeat": 15, "overlays": [
+//	  {"x": 0, "y": 0, "text": "{{(index .Stub.Earnings {row}).Description}}"},
+//	  {"x": 300, "y": 0, "field": "earnings[{row}].current", "text": "..."}
+//	]}}
+//
+// The X and Y of the overlays are offsets from those of the group, and
+// each row is DX and DY further on than the one before; all are in the
+// units and from the origin of each overlay.
+type Group struct {
+	X        float64           `json:"x"`
+	Y        float64           `json:"y"`
+	DX       float64           `json:"dx"`
+	DY       float64           `json:"dy"`
+	Repeat   int               `json:"repeat"` // the number of rows; 0 means 1, at most 10000
+	Overlays []OverlayRectText `json:"overlays"`
+}
+
+// Size returns the number of overlays g expands to.
+func (g Group) Size() int {
+	return max(g.Repeat, 1) * len(g.Overlays)
+}
+
+// Expand returns the overlays of g, row by row.
+func (g Group) Expand() []OverlayRectText {
+	rows := max(g.Repeat, 1)
+	out := make([]OverlayRectText, 0, g.Size())
+	for r := range rows {
+		row := strconv.Itoa(r)
+		for _, ov := range g.Overlays {
+			ov.X += g.X + float64(r)*g.DX
+			ov.Y += g.Y + float64(r)*g.DY
+			ov.Text = strings.ReplaceAll(ov.Text, RowPlaceholder, row)
+			ov.Field = strings.ReplaceAll(ov.Field, RowPlaceholder, row)
+			ov.Content = strings.ReplaceAll(ov.Content, RowPlaceholder, row)
+			out = append(out, ov)
+		}
+	}
+	return out
+}
+
+// decodeGroup decodes raw, an entry of an overlay list, as a Group if it is
+// one. ok reports whether it is.
+func decodeGroup(raw json.RawMessage) (g Group, ok bool, err error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return g, false, nil
+	}
+	body, ok := fields["group"]
+	if !ok {
+		return g, false, nil
+	}
+	if len(fields) > 1 {
+		return g, true, errors.New("a group entry has only the group field; put the overlays in group.overlays")
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&g); err != nil {
+		return g, true, fmt.Errorf("group: %s", describeJSONError(err, body))
+	}
+	if g.Repeat < 0 || g.Repeat > maxGroupRepeat {
+		return g, true, fmt.Errorf("group: repeat must be from 0 to %d, got %d", maxGroupRepeat, g.Repeat)
+	}
+	if len(g.Overlays) == 0 {
+		return g, true, errors.New("group: nothing to draw: set overlays")
+	}
+	return g, true, nil
+}
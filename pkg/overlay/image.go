@@ -0,0 +1,86 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"os"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// hasImage reports whether ov draws an image.
+func hasImage(ov OverlayRectText) bool {
+	return ov.ImagePath != "" || ov.ImageData != ""
+}
+
+// imageName names ov's image in errors.
+func imageName(ov OverlayRectText) string {
+	if ov.ImagePath != "" {
+		return "image " + ov.ImagePath
+	}
+	return "imageData"
+}
+
+// imageFor returns the encoded image ov draws: the file at ImagePath, which
+// must have the extension of a type pdfcpu can embed, or the decoded
+// ImageData.
+func imageFor(ov OverlayRectText) ([]byte, error) {
+	if ov.ImagePath != "" {
+		if !model.ImageFileName(ov.ImagePath) {
+			return nil, fmt.Errorf("%s: unsupported image type (valid: .png, .jpg, .jpeg, .tif, .tiff, .webp)", imageName(ov))
+		}
+		data, err := os.ReadFile(ov.ImagePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", imageName(ov), err)
+		}
+		return data, nil
+	}
+	s := ov.ImageData
+	if strings.HasPrefix(s, "data:") {
+		_, after, ok := strings.Cut(s, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("imageData: a data URI must be base64 encoded")
+		}
+		s = after
+	}
+	// Long base64 is often wrapped over several lines.
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, fmt.Errorf("imageData: invalid base64: %v", err)
+	}
+	return data, nil
+}
+
+// imageScale checks that data is an image of a type pdfcpu can embed, and
+// returns its size in pixels together with the absolute pdfcpu scale that
+// draws it for ov: fitted inside a positive Width x Height box keeping its
+// aspect ratio, otherwise at one point per pixel, and in both cases
+// multiplied by Scale.
+func imageScale(data []byte, ov OverlayRectText) (w, h int, scale float64, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("could not read image: %v", err)
+	}
+	switch format {
+	case "png", "jpeg", "tiff", "webp":
+	default:
+		return 0, 0, 0, fmt.Errorf("unsupported image type %s (valid: png, jpeg, tiff, webp)", format)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return 0, 0, 0, fmt.Errorf("image is empty")
+	}
+	scale = 1
+	switch {
+	case ov.Width > 0 && ov.Height > 0:
+		scale = min(ov.Width/float64(cfg.Width), ov.Height/float64(cfg.Height))
+	case ov.Width > 0:
+		scale = ov.Width / float64(cfg.Width)
+	case ov.Height > 0:
+		scale = ov.Height / float64(cfg.Height)
+	}
+	return cfg.Width, cfg.Height, scale * ov.Scale, nil
+}
@@ -0,0 +1,83 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// TextRun is a piece of text a PDF page shows with one text operator,
+// usually a word, a field or a line. Its box is in the coordinates overlays
+// use by default: PDF points from the lower-left corner of the page as the
+// reader sees it, y up, so an overlay with the same X, Y, Width and Height
+// covers it.
+type TextRun struct {
+	Page   int     `json:"page"`
+	Text   string  `json:"text"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Inspect returns the text runs shown inside the crop box of the pages of
+// pdf in the page selection pages, with the syntax of OverlayRectText.Pages
+// ("" for every page), in page and content order. Like
+// -verify, it reads character codes as ASCII, so text in fonts with other
+// encodings shows up as "?".
+func Inspect(pdf []byte, pages string) ([]TextRun, error) {
+	return textRuns(pdf, pages, false)
+}
+
+// textRuns returns the text runs of Inspect, along with those inside the
+// form XObjects the pages draw if forms is set.
+func textRuns(pdf []byte, pages string, forms bool) ([]TextRun, error) {
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+	selected, err := pagesFor(pages, ctx.PageCount)
+	if err != nil {
+		return nil, err
+	}
+	return pageTextRuns(ctx, boundaries, selected, forms)
+}
+
+// pageTextRuns returns the text runs of the selected pages of ctx, whose
+// boundaries are boundaries, like textRuns.
+func pageTextRuns(ctx *model.Context, boundaries []model.PageBoundaries, selected types.IntSet, forms bool) ([]TextRun, error) {
+	mode := formsSkipped
+	if forms {
+		mode = formsRead
+	}
+	runs := []TextRun{}
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		if !selected[pageNr] {
+			continue
+		}
+		view := viewOf(boundaries[pageNr-1])
+		r, _, _, _, err := scanPage(ctx.XRefTable, pageNr, []*types.Rectangle{view.crop}, mode)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		for _, run := range r.runs {
+			b := view.fromUser(run.box)
+			runs = append(runs, TextRun{
+				Page: pageNr, Text: run.text,
+				X: round2(b.LL.X), Y: round2(b.LL.Y), Width: round2(b.Width()), Height: round2(b.Height()),
+			})
+		}
+	}
+	return runs, nil
+}
@@ -0,0 +1,76 @@
+package overlay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message/catalog"
+)
+
+// LoadCatalog reads a JSON message catalog mapping locale to label key to
+// translation, e.g. {"fr": {"Gross Pay": "Salaire brut"}}. An empty path
+// yields an empty catalog.
+func LoadCatalog(path string) (catalog.Catalog, error) {
+	b := catalog.NewBuilder()
+	if path == "" {
+		return b, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for locale, messages := range entries {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("locale %q: %v", locale, err)
+		}
+		for key, msg := range messages {
+			if err := b.SetString(tag, key, msg); err != nil {
+				return nil, fmt.Errorf("locale %q, key %q: %v", locale, key, err)
+			}
+		}
+	}
+	return b, nil
+}
+
+// labelRenderer collects the text of a catalog message.
+type labelRenderer struct {
+	strings.Builder
+}
+
+func (r *labelRenderer) Render(s string)       { r.WriteString(s) }
+func (r *labelRenderer) Arg(i int) interface{} { return nil }
+
+// LocalizeLabels sets the Text of every overlay that has a Label to that
+// label's translation for tag, falling back to the label key itself (with a
+// warning) when the catalog has no translation.
+func LocalizeLabels(overlays []OverlayRectText, cat catalog.Catalog, tag language.Tag) error {
+	for i := range overlays {
+		key := overlays[i].Label
+		if key == "" {
+			continue
+		}
+		var r labelRenderer
+		err := cat.Context(tag, &r).Execute(key)
+		if errors.Is(err, catalog.ErrNotFound) {
+			slog.Warn("No translation for label, using the key", "overlay", i, "locale", tag.String(), "label", key)
+			overlays[i].Text = key
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("overlay %d: label %q: %v", i, key, err)
+		}
+		overlays[i].Text = r.String()
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// OverlayPlacement records where one overlay ends up in a PDF.
+type OverlayPlacement struct {
+	Index int    `json:"index"`
+	Label string `json:"label,omitempty"`
+	Field string `json:"field,omitempty"`
+	// Boxes holds one entry per distinct position; overlays drawn on pages
+	// of different sizes can land in different places.
+	Boxes []PlacedBox `json:"boxes"`
+}
+
+// PlacedBox is the area an overlay covers on some pages, in PDF points in
+// the pages' default user space (origin at the media box's lower-left
+// corner, y up). It is the axis-aligned bounding box of everything the
+// overlay draws, after origin flipping, unit conversion, auto-fitting and
+// rotation.
+type PlacedBox struct {
+	Pages  []int   `json:"pages"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Placements computes where each overlay would be drawn on pdf. Overlays
+// that draw nothing get no boxes.
+func Placements(pdf []byte, overlays []OverlayRectText) ([]OverlayPlacement, error) {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return nil, err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	placements := make([]OverlayPlacement, len(overlays))
+	for i, ov := range overlays {
+		placement := OverlayPlacement{Index: i, Label: ov.Label, Field: ov.Field, Boxes: []PlacedBox{}}
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		for page := 1; page <= ctx.PageCount; page++ {
+			if !pages[page] {
+				continue
+			}
+			vp := boundaries[page-1].CropBox()
+			pageW, pageH := viewOf(boundaries[page-1]).size()
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return nil, err
+			}
+			b := plan.bounds(pageW, pageH)
+			if b == nil {
+				continue
+			}
+			b.Translate(vp.LL.X, vp.LL.Y)
+			box := PlacedBox{X: round2(b.LL.X), Y: round2(b.LL.Y), Width: round2(b.Width()), Height: round2(b.Height())}
+			if n := len(placement.Boxes); n > 0 && sameBox(placement.Boxes[n-1], box) {
+				placement.Boxes[n-1].Pages = append(placement.Boxes[n-1].Pages, page)
+				continue
+			}
+			box.Pages = []int{page}
+			placement.Boxes = append(placement.Boxes, box)
+		}
+		placements[i] = placement
+	}
+	return placements, nil
+}
+
+// round2 rounds v to hundredths of a point.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// sameBox reports whether a and b cover the same area.
+func sameBox(a, b PlacedBox) bool {
+	return a.X == b.X && a.Y == b.Y && a.Width == b.Width && a.Height == b.Height
+}
+
+// TruthField is the text one overlay draws on one page, as ground truth for
+// extracting it again: its name, its final text and the box the text covers
+// in the same coordinates as PlacedBox.
+type TruthField struct {
+	Index  int     `json:"index"`
+	Field  string  `json:"field,omitempty"`
+	Label  string  `json:"label,omitempty"`
+	Text   string  `json:"text"`
+	Page   int     `json:"page"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// GroundTruth returns one TruthField per page for every overlay that draws
+// text on pdf, in overlay and then page order. The boxes cover the text
+// alone, not the overlay's rectangle.
+func GroundTruth(pdf []byte, overlays []OverlayRectText) ([]TruthField, error) {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return nil, err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	fields := []TruthField{}
+	for i, ov := range overlays {
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		for page := 1; page <= ctx.PageCount; page++ {
+			if !pages[page] {
+				continue
+			}
+			vp := boundaries[page-1].CropBox()
+			pageW, pageH := viewOf(boundaries[page-1]).size()
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return nil, err
+			}
+			b := plan.textBounds(pageW, pageH)
+			if b == nil {
+				continue
+			}
+			b.Translate(vp.LL.X, vp.LL.Y)
+			fields = append(fields, TruthField{
+				Index: i, Field: ov.Field, Label: ov.Label, Text: strings.Join(plan.lines, "\n"), Page: page,
+				X: round2(b.LL.X), Y: round2(b.LL.Y), Width: round2(b.Width()), Height: round2(b.Height()),
+			})
+		}
+	}
+	return fields, nil
+}
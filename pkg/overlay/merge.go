@@ -0,0 +1,37 @@
+package overlay
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Merge returns one PDF of the pages of pdfs, in order, such as the pages of
+// generated documents appended to an existing PDF. A single PDF comes back
+// as it is.
+func Merge(pdfs [][]byte) ([]byte, error) {
+	if len(pdfs) == 1 {
+		return pdfs[0], nil
+	}
+	rs := make([]io.ReadSeeker, len(pdfs))
+	for i, pdf := range pdfs {
+		rs[i] = bytes.NewReader(pdf)
+	}
+	conf := model.NewDefaultConfiguration()
+	var buf bytes.Buffer
+	if err := api.MergeRaw(rs, &buf, false, conf); err != nil {
+		return nil, readError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// PageCount returns the number of pages of pdf.
+func PageCount(pdf []byte) (int, error) {
+	n, err := api.PageCount(bytes.NewReader(pdf), model.NewDefaultConfiguration())
+	if err != nil {
+		return 0, readError(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,178 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Metadata says how SetMetadata rewrites the document information of a PDF.
+// Empty fields are left as they are.
+type Metadata struct {
+	// Scrub drops the document information and XMP metadata of the PDF,
+	// including the producer and dates pdfcpu stamps on everything it
+	// writes, before the fields below are set.
+	Scrub    bool
+	Title    string
+	Author   string
+	Subject  string
+	Creator  string // the application the original was made with
+	Producer string // the application that wrote the PDF
+	// Date, if not zero, is both the creation and the modification date, so
+	// the same input gives the same dates however often it is processed.
+	Date time.Time
+	// Reproducible derives the file identifier from the content of the PDF
+	// instead of the time it is written, so that, with Date set, the same
+	// input gives the same bytes.
+	Reproducible bool
+}
+
+// setsXMP reports whether m has values for an XMP packet.
+func (m Metadata) setsXMP() bool {
+	return m.Title != "" || m.Author != "" || m.Subject != "" || m.Creator != "" || m.Producer != "" || !m.Date.IsZero()
+}
+
+// SetMetadata returns pdf with its document information dictionary and XMP
+// metadata rewritten as m says. The XMP metadata is replaced by a packet of
+// the resulting fields when m sets any, as it would otherwise contradict
+// them.
+//
+// pdfcpu stamps its own producer and the current time into the document
+// information of every PDF it writes, so the dictionary is patched into the
+// written file afterwards; for that, the PDF is written without object
+// streams. The objects are then put in the order of their numbers, not the
+// order pdfcpu happens to write them in.
+func SetMetadata(pdf []byte, m Metadata) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	root, err := ctx.XRefTable.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	if m.Scrub {
+		root.Delete("Metadata")
+	}
+	if m.setsXMP() {
+		// The packet keeps the fields of the document information m leaves.
+		x := m
+		if ctx.Info != nil && !m.Scrub {
+			old, _ := ctx.XRefTable.DereferenceDict(*ctx.Info)
+			for key, field := range map[string]*string{"Title": &x.Title, "Author": &x.Author, "Subject": &x.Subject, "Creator": &x.Creator} {
+				if *field == "" && old[key] != nil {
+					*field, _ = ctx.XRefTable.DereferenceStringOrHexLiteral(old[key], model.V10, nil)
+				}
+			}
+		}
+		// Metadata streams are left uncompressed so tools can find them.
+		sd := types.StreamDict{Dict: types.Dict{"Type": types.Name("Metadata"), "Subtype": types.Name("XML")}, Content: x.xmp()}
+		if err := sd.Encode(); err != nil {
+			return nil, err
+		}
+		ir, err := ctx.XRefTable.IndRefForNewObject(sd)
+		if err != nil {
+			return nil, err
+		}
+		root["Metadata"] = *ir
+	}
+
+	var buf bytes.Buffer
+	if err := api.Write(ctx, &buf, conf); err != nil {
+		return nil, fmt.Errorf("writing PDF: %v", err)
+	}
+	written, err := splitXRef(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	// PDF 2.0 has no document information dictionary.
+	if ctx.Info != nil {
+		info, err := ctx.XRefTable.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return nil, err
+		}
+		if m.Scrub || info == nil {
+			info = types.Dict{}
+		}
+		for key, value := range map[string]string{"Title": m.Title, "Author": m.Author, "Subject": m.Subject, "Creator": m.Creator, "Producer": m.Producer} {
+			if value != "" {
+				s, err := infoString(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+				info[key] = s
+			}
+		}
+		if !m.Date.IsZero() {
+			date := types.StringLiteral(types.DateString(m.Date.UTC()))
+			info["CreationDate"], info["ModDate"] = date, date
+		}
+		written.replace(*ctx.Info, info)
+	}
+	if m.Reproducible {
+		written.renumber()
+		written.contentID()
+	}
+	return written.bytes(), nil
+}
+
+// infoString returns s as a document information string: escaped, and in
+// UTF-16 if it isn't ASCII.
+func infoString(s string) (types.StringLiteral, error) {
+	for _, r := range s {
+		if r > '~' {
+			e, err := types.EscapedUTF16String(s)
+			if err != nil {
+				return "", err
+			}
+			return types.StringLiteral(*e), nil
+		}
+	}
+	e, err := types.Escape(s)
+	if err != nil {
+		return "", err
+	}
+	return types.StringLiteral(*e), nil
+}
+
+// xmp returns the XMP packet of m's fields.
+func (m Metadata) xmp() []byte {
+	var b bytes.Buffer
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	if m.Title != "" {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(m.Title))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(m.Author))
+	}
+	if m.Subject != "" {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", esc(m.Subject))
+	}
+	if m.Creator != "" {
+		fmt.Fprintf(&b, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", esc(m.Creator))
+	}
+	if m.Producer != "" {
+		fmt.Fprintf(&b, "<pdf:Producer>%s</pdf:Producer>\n", esc(m.Producer))
+	}
+	if !m.Date.IsZero() {
+		date := m.Date.UTC().Format(time.RFC3339)
+		fmt.Fprintf(&b, "<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n", date, date)
+	}
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
@@ -0,0 +1,224 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// allowedOps lists the content-stream operators accepted in OverlayRectText.Ops,
+// each with the operands it takes: one 'n' per number and 'a' for an array
+// of numbers. Only path construction, path painting, clipping, device
+// colours and self-contained graphics state are allowed: anything that
+// references resources (fonts, XObjects, shadings, ExtGStates), shows text
+// or embeds inline images is rejected so raw ops cannot corrupt the
+// document.
+var allowedOps = map[string]string{
+	// Path construction
+	"m": "nn", "l": "nn", "c": "nnnnnn", "v": "nnnn", "y": "nnnn", "h": "", "re": "nnnn",
+	// Path painting
+	"S": "", "s": "", "f": "", "F": "", "f*": "",
+	"B": "", "B*": "", "b": "", "b*": "", "n": "",
+	// Clipping
+	"W": "", "W*": "",
+	// Graphics state
+	"q": "", "Q": "", "cm": "nnnnnn", "w": "n", "J": "n", "j": "n", "M": "n", "d": "an",
+	// Device colours
+	"g": "n", "G": "n", "rg": "nnn", "RG": "nnn", "k": "nnnn", "K": "nnnn",
+}
+
+// sanitizeOps validates raw content-stream operators and returns them
+// normalised to single-space separated tokens. Each operator must have the
+// operands allowedOps gives it, numbers or arrays of numbers, and q/Q must
+// be balanced.
+func sanitizeOps(ops string) (string, error) {
+	ops = strings.NewReplacer("[", " [ ", "]", " ] ").Replace(ops)
+	tokens := strings.Fields(ops)
+
+	depth := 0
+	inArray := false
+	operands := "" // those since the last operator, as in allowedOps
+	for _, tok := range tokens {
+		switch {
+		case tok == "[":
+			if inArray {
+				return "", fmt.Errorf("nested arrays are not allowed")
+			}
+			inArray = true
+		case tok == "]":
+			if !inArray {
+				return "", fmt.Errorf("unbalanced ']'")
+			}
+			inArray = false
+			operands += "a"
+		case numberPattern.MatchString(tok):
+			if v, _ := strconv.ParseFloat(tok, 64); math.Abs(v) > maxOpsNumber {
+				return "", fmt.Errorf("number %s is out of range (at most ±%d)", tok, maxOpsNumber)
+			}
+			if !inArray {
+				operands += "n"
+			}
+		case inArray:
+			return "", fmt.Errorf("arrays may only contain numbers, got %q", tok)
+		default:
+			want, ok := allowedOps[tok]
+			if !ok {
+				return "", fmt.Errorf("operator or operand %q is not allowed", tok)
+			}
+			if operands != want {
+				return "", fmt.Errorf("operator %q takes %s, got %s", tok, describeOperands(want), describeOperands(operands))
+			}
+			operands = ""
+			if tok == "q" {
+				depth++
+			}
+			if tok == "Q" {
+				if depth == 0 {
+					return "", fmt.Errorf("'Q' without matching 'q'")
+				}
+				depth--
+			}
+		}
+	}
+	if inArray {
+		return "", fmt.Errorf("unterminated array")
+	}
+	if operands != "" {
+		return "", fmt.Errorf("%s at the end without an operator", describeOperands(operands))
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("%d unmatched 'q'", depth)
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+// describeOperands describes operands, written as in allowedOps, in words,
+// such as "2 numbers" or "an array and a number".
+func describeOperands(operands string) string {
+	if operands == "" {
+		return "no operands"
+	}
+	var parts []string
+	for len(operands) > 0 {
+		kind := operands[0]
+		n := len(operands) - len(strings.TrimLeft(operands, string(kind)))
+		operands = operands[n:]
+		switch {
+		case kind == 'a' && n == 1:
+			parts = append(parts, "an array")
+		case kind == 'a':
+			parts = append(parts, fmt.Sprintf("%d arrays", n))
+		case n == 1:
+			parts = append(parts, "a number")
+		default:
+			parts = append(parts, fmt.Sprintf("%d numbers", n))
+		}
+	}
+	return strings.Join(parts, " and ")
+}
+
+// numberPattern matches a PDF number: an optional sign and digits with at
+// most one decimal point, without the exponents, hex digits, NaN and Inf
+// that strconv.ParseFloat takes.
+var numberPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+// maxOpsNumber is the largest magnitude of a number in Ops: the limit on
+// reals in Annex C of the PDF 1.4 reference, which older readers still
+// keep, and far beyond any page.
+const maxOpsNumber = 32767
+
+// rectOps returns the operators drawing a w x h rectangle filled with fill,
+// unless it is nil, and, when border is not nil, stroked with a border of
+// width bw and dash pattern dash inside its edge. It returns "" when there
+// is nothing to draw.
+func rectOps(w, h float64, fill, border color.Color, bw float64, dash []float64) string {
+	ops := ""
+	if fill != nil {
+		ops = fmt.Sprintf("%s rg 0 0 %f %f re f", rgOperands(fill), w, h)
+	}
+	if border == nil {
+		return ops
+	}
+	if 2*bw >= min(w, h) {
+		// The border covers the whole rectangle.
+		return fmt.Sprintf("%s rg 0 0 %f %f re f", rgOperands(border), w, h)
+	}
+	return ops + fmt.Sprintf(" q %s RG %f w%s %f %f %f %f re S Q",
+		rgOperands(border), bw, dashOps(dash), bw/2, bw/2, w-bw, h-bw)
+}
+
+// lineOps returns the operators drawing a line of width bw and dash
+// pattern dash in c from the bottom-left corner of a w x h box to its
+// top-right corner, on a page bw/2 larger than the box on every side.
+func lineOps(w, h float64, c color.Color, bw float64, dash []float64) string {
+	return fmt.Sprintf("q %s RG %f w%s %f %f m %f %f l S Q",
+		rgOperands(c), bw, dashOps(dash), bw/2, bw/2, w+bw/2, h+bw/2)
+}
+
+// dashOps returns the operator setting the dash pattern dash, with a
+// leading space, or "" for a solid line.
+func dashOps(dash []float64) string {
+	if len(dash) == 0 {
+		return ""
+	}
+	s := make([]string, len(dash))
+	for i, d := range dash {
+		s[i] = strconv.FormatFloat(d, 'f', -1, 64)
+	}
+	return fmt.Sprintf(" [%s] 0 d", strings.Join(s, " "))
+}
+
+// BlankPDF returns a single-page PDF of w x h points with nothing on it, for
+// drawing overlays on from scratch.
+func BlankPDF(w, h float64) []byte {
+	return BlankPages(w, h, 1)
+}
+
+// BlankPages returns a PDF of n pages of w x h points with nothing on them,
+// like BlankPDF, for overlays drawn across several pages.
+func BlankPages(w, h float64, n int) []byte {
+	return pagesPDF(w, h, max(n, 1), "")
+}
+
+// opsPDF builds a minimal single-page PDF of w x h points whose page content
+// is ops. It is used as a PDF stamp so the operators are drawn in a
+// coordinate system with its origin at the overlay's bottom-left corner.
+func opsPDF(w, h float64, ops string) []byte {
+	return pagesPDF(w, h, 1, ops)
+}
+
+// pagesPDF builds a minimal PDF of n pages of w x h points whose page
+// content is ops.
+func pagesPDF(w, h float64, n int, ops string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Each page is an object 3, 5, 7, ... followed by its content stream.
+	kids := make([]string, n)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	buf.WriteString("%PDF-1.7\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n))
+	for i := range n {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %f %f] /Resources << >> /Contents %d 0 R >>", w, h, 4+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(ops), ops))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
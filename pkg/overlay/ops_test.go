@@ -0,0 +1,56 @@
+package overlay
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSanitizeOps checks that raw ops are accepted only with the operands
+// their operators take, and that errors name the operator at fault.
+func TestSanitizeOps(t *testing.T) {
+	tests := []struct {
+		ops     string
+		want    string // the normalised ops, if accepted
+		wantErr string // "" if the ops are accepted
+	}{
+		{ops: "0 0 m 10 10 l S", want: "0 0 m 10 10 l S"},
+		{ops: "q 1 0 0 rg 0 0 10 5 re f Q", want: "q 1 0 0 rg 0 0 10 5 re f Q"},
+		{ops: "[3 2]0 d 0 0 m 5 5 l S", want: "[ 3 2 ] 0 d 0 0 m 5 5 l S"},
+		{ops: "[] 0 d", want: "[ ] 0 d"},
+		{ops: "0 0 1 0 k -.5 +2 10 10 re f*", want: "0 0 1 0 k -.5 +2 10 10 re f*"},
+		{ops: "1 2 3 4 5 6 7 rg", wantErr: `operator "rg" takes 3 numbers, got 7 numbers`},
+		{ops: "m", wantErr: `operator "m" takes 2 numbers, got no operands`},
+		{ops: "1 re", wantErr: `operator "re" takes 4 numbers, got a number`},
+		{ops: "1 h", wantErr: `operator "h" takes no operands, got a number`},
+		{ops: "0 [3] d", wantErr: `operator "d" takes an array and a number, got a number and an array`},
+		{ops: "[1] [2] 0 d", wantErr: `operator "d" takes an array and a number, got 2 arrays and a number`},
+		{ops: "[1] 0 0 m", wantErr: `operator "m" takes 2 numbers, got an array and 2 numbers`},
+		{ops: "0 0 m 1 1", wantErr: "2 numbers at the end without an operator"},
+		{ops: "/F1 12 Tf", wantErr: `"/F1" is not allowed`},
+		{ops: "1e5 0 m", wantErr: `"1e5" is not allowed`},
+		{ops: "40000 0 m", wantErr: "out of range"},
+		{ops: "[1 [2]] 0 d", wantErr: "nested arrays"},
+		{ops: "[1 0 d", wantErr: `arrays may only contain numbers, got "d"`},
+		{ops: "[1", wantErr: "unterminated array"},
+		{ops: "1] 0 d", wantErr: "unbalanced ']'"},
+		{ops: "q q Q", wantErr: "1 unmatched 'q'"},
+		{ops: "Q", wantErr: "'Q' without matching 'q'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ops, func(t *testing.T) {
+			got, err := sanitizeOps(tt.ops)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("sanitizeOps = %q, %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sanitizeOps: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sanitizeOps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
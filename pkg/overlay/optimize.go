@@ -0,0 +1,83 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Optimize returns pdf made smaller. pdfcpu's optimizer merges the
+// duplicate fonts, images, forms and content streams of its pages and
+// drops the resources they don't use; then objects that are copies of one
+// another, such as the font files each pass of overlays embeds again, are
+// merged too, objects nothing refers to are dropped, and the rest are
+// written in compressed object streams. If that doesn't make pdf smaller,
+// it comes back as it is.
+func Optimize(pdf []byte) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.OptimizeDuplicateContentStreams = true
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	var buf bytes.Buffer
+	if err := api.Write(ctx, &buf, conf); err != nil {
+		return nil, fmt.Errorf("writing PDF: %v", err)
+	}
+	written, err := splitXRef(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	written.dedupe()
+	written.renumber()
+
+	var out bytes.Buffer
+	if err := api.Optimize(bytes.NewReader(written.bytes()), &out, model.NewDefaultConfiguration()); err != nil {
+		return nil, fmt.Errorf("writing PDF: %v", err)
+	}
+	if out.Len() >= len(pdf) {
+		return pdf, nil
+	}
+	return out.Bytes(), nil
+}
+
+// A Linearizer rewrites pdf linearized, for fast web view: the first page
+// first, with hint tables, so a browser can show it before the rest has
+// been downloaded.
+type Linearizer func(pdf []byte) ([]byte, error)
+
+// Qpdf returns a Linearizer that runs qpdf, found at cmd or on the PATH.
+// The file identifier it writes is derived from the content, so the same
+// input gives the same output.
+func Qpdf(cmd string) Linearizer {
+	return func(pdf []byte) ([]byte, error) {
+		dir, err := os.MkdirTemp("", "linearize")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		in, out := filepath.Join(dir, "in.pdf"), filepath.Join(dir, "out.pdf")
+		if err := os.WriteFile(in, pdf, 0600); err != nil {
+			return nil, err
+		}
+		// qpdf exits with 3 when it only warns about the input.
+		msg, err := exec.Command(cmd, "--linearize", "--deterministic-id", in, out).CombinedOutput()
+		if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 3 {
+			err = nil
+		}
+		if msg = bytes.TrimSpace(msg); err != nil && len(msg) > 0 {
+			return nil, fmt.Errorf("%s: %v: %s", cmd, err, msg)
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %v", cmd, err)
+		}
+		return os.ReadFile(out)
+	}
+}
@@ -0,0 +1,726 @@
+// Package overlay covers regions of existing PDFs with rectangles and draws
+// replacement text on top, using pdfcpu watermarks.
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// OverlayRectText describes one overlay: a filled (white by default) rectangle
+// and text on top.
+type OverlayRectText struct {
+	Text   string  `json:"text"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`  // rectangle width in PDF points
+	Height float64 `json:"height"` // rectangle height in PDF points
+	Scale  float64 `json:"scale"`  // size multiplier of the rectangle, image and ops
+	Anchor string  `json:"anchor"` // pdfcpu position anchor; X/Y are offsets from it (default "bl")
+	// TextScale sets the size of the text independently of Scale. A single
+	// line spans TextScale of the page width (0.25 is a quarter of it);
+	// wrapped or multi-line text is drawn at 48 points times TextScale. 0
+	// means Scale/4, which is how Scale used to size the text as well.
+	TextScale float64 `json:"textScale"`
+	// FontSize is the text size in points. It takes precedence over
+	// TextScale and Scale for single-line, wrapped and multi-line text; 0
+	// means sized by TextScale.
+	FontSize int `json:"fontSize"`
+	// FillColor is the rectangle colour as "#RRGGBB" or an SVG colour name
+	// such as "lightgray"; empty means white and "none" leaves the rectangle
+	// unfilled, drawing only its border.
+	FillColor string `json:"fillColor"`
+	// BorderColor is the colour of a border drawn inside the edge of the
+	// rectangle, like FillColor; empty means no border.
+	BorderColor string `json:"borderColor"`
+	// BorderWidth is the border's width in points, drawn inside the
+	// rectangle; 0 means 1 when there is a BorderColor.
+	BorderWidth float64 `json:"borderWidth"`
+	// Dash is the dash pattern of the border or line in points: the lengths
+	// of alternating dashes and gaps, such as [3, 2]; empty means solid.
+	Dash []float64 `json:"dash"`
+	// TextColor is the text colour, like FillColor; empty means black.
+	TextColor string `json:"textColor"`
+	// Opacity is how opaque the rectangle and text are, from 0 (invisible)
+	// to 1; nil means fully opaque.
+	Opacity *float64 `json:"opacity"`
+	// Font is a pdfcpu font name (e.g. "Courier"); empty means pdfcpu's default.
+	Font string `json:"font"`
+	// Bold and Italic pick the bold, italic or bold italic face of Font's
+	// family, which must be Helvetica (the default), Times or Courier. For a
+	// FontFile, give the file of the face instead.
+	Bold   bool `json:"bold"`
+	Italic bool `json:"italic"`
+	// FontFile is the path of a TrueType (.ttf) font to install and draw the
+	// text with; it takes precedence over Font. Every character of Text must
+	// have a glyph in it.
+	FontFile string `json:"fontFile"`
+	// Ops holds raw PDF content-stream operators drawn inside the Width x Height
+	// box, in PDF points with the origin at the box's bottom-left corner and y
+	// pointing up. Only path, colour and graphics-state operators are allowed,
+	// each with the number of operands it takes, which are plain decimal
+	// numbers of at most 32767.
+	Ops string `json:"ops"`
+	// Type "barcode" draws Content as a barcode of Symbology filling the
+	// Width x Height box, and "qrcode" is short for symbology "qr". The code
+	// is drawn in TextColor over the rectangle and under Ops, placed and
+	// scaled like them; empty draws no code. Type "image" only requires an
+	// ImagePath or ImageData, which are drawn whatever the type.
+	//
+	// Type "line" draws a straight line, instead of the rectangle, from the
+	// bottom-left corner of the box to its top-right corner: a horizontal
+	// rule when Height is 0 and a vertical one when Width is 0. Type "rect"
+	// draws the rectangle unfilled unless FillColor is set. Both are
+	// stroked BorderWidth wide in BorderColor, black by default, with Dash.
+	Type string `json:"type"`
+	// Content is what the code encodes, such as a check number or a
+	// verification URL. Its {{...}} placeholders are filled in like Text's.
+	Content string `json:"content"`
+	// Symbology is "code128" (the default), a Code 128 barcode of ASCII text
+	// stretched across the box, or "qr", the largest QR code that fits in it.
+	Symbology string `json:"symbology"`
+	// ErrorCorrection is the error correction level of a QR code, "L", "M"
+	// (the default), "Q" or "H": how much of it can be damaged and still
+	// read.
+	ErrorCorrection string `json:"errorCorrection"`
+	// ImagePath is the path of a PNG, JPEG, TIFF or WebP image (e.g. a logo,
+	// signature scan or stamp) drawn instead of the filled rectangle. It is
+	// fitted inside the Width x Height box keeping its aspect ratio, or drawn
+	// at one point per pixel when the box is empty, and then scaled by Scale.
+	ImagePath string `json:"imagePath"`
+	// ImageData is an image like ImagePath's embedded in the overlay, base64
+	// encoded, optionally as a "data:image/png;base64,..." URI. Only one of
+	// ImagePath and ImageData may be set.
+	ImageData string `json:"imageData"`
+	// Label is a message catalog key; when set, LocalizeLabels replaces Text
+	// with the label's translation.
+	Label string `json:"label"`
+	// Field names the AcroForm field FillForm fills with Text. Overlays
+	// drawn as usual report it with their text in GroundTruth.
+	Field string `json:"field"`
+	// Pages is a pdfcpu page selection (e.g. "1", "2-3", "even", "last")
+	// limiting the overlay to those pages; empty means every page.
+	Pages string `json:"pages"`
+	// Wrap breaks Text into lines no wider than Width. Text that wraps or
+	// contains newlines is drawn line by line at FontSize, or else 12 points
+	// times Scale (or 48 times TextScale), the first line at Y and the rest
+	// stacked below it.
+	Wrap bool `json:"wrap"`
+	// Align places each line of text at the "left", "center" or "right" of
+	// the rectangle (Width times Scale wide) and VAlign places the block of
+	// lines at its "top", "middle" or "bottom". Empty keeps the text at X and
+	// Y as described for Wrap.
+	Align  string `json:"align"`
+	VAlign string `json:"valign"`
+	// LineSpacing is the distance between the lines of wrapped or
+	// multi-line text as a multiple of the font's line height; 0 means 1.
+	LineSpacing float64 `json:"lineSpacing"`
+	// AutoFit ignores Scale, TextScale and FontSize for the text and draws it at the largest size
+	// whose lines fit inside the Width x Height box.
+	AutoFit bool `json:"autoFit"`
+	// Fit shrinks the text from its usual size, FontSize or else 12 points
+	// times Scale, until its lines fit the box, Width x Height times Scale
+	// (only as wide when Height is 0). Unlike AutoFit it never grows the
+	// text, and text still too big at MinFontSize is cut short with an
+	// ellipsis instead of failing. As with AutoFit, the last line is at Y.
+	Fit bool `json:"fit"`
+	// MinFontSize is the smallest size Fit shrinks text to; 0 means 4
+	// points. Setting it to FontSize truncates the text without shrinking.
+	MinFontSize int `json:"minFontSize"`
+	// Units is what X, Y, Width and Height are measured in: "pt" (PDF
+	// points, the default; "points" also works), "in", "mm" or "percent".
+	// With "percent", X and Width are percentages of the page width and Y
+	// and Height of the page height. Font sizes, border widths, dashes and
+	// Ops are always in points.
+	Units string `json:"units"`
+	// Redact removes the text, images and form XObjects under the rectangle
+	// from the page content before covering it, instead of only covering it.
+	// The area is the Width x Height box, or the image, even with a
+	// FillColor of "none"; a line has none to redact.
+	Redact bool `json:"redact"`
+	// Rotation turns the whole overlay counterclockwise by this many degrees
+	// (pdfcpu's direction) around its anchor point, which for the default
+	// "bl" anchor is the box's bottom-left corner at X, Y.
+	Rotation float64 `json:"rotation"`
+	// Origin is "bl" (default) to measure Y up from the bottom of the page to
+	// the bottom of the box, or "tl" to measure it down from the top of the
+	// page to the top of the box. "tl" needs the default "bl" anchor.
+	Origin string `json:"origin"`
+	// FindText places the overlay relative to text the PDF shows instead of
+	// at a fixed spot, so one overlay list fits versions of a template that
+	// move things about: X and Y are offsets from the bottom-left corner of
+	// the first match on the overlay's Pages (or, with origin "tl", from its
+	// top-left corner down to the top of the box), and it is drawn on the
+	// page of the match only. Text is matched within the runs Inspect lists.
+	FindText string `json:"findText"`
+	// FindOccurrence picks the match of FindText to place the overlay at,
+	// counting from 1 in page and content order; 0 means 1.
+	FindOccurrence int `json:"findOccurrence"`
+}
+
+// validAnchors lists the position anchors pdfcpu accepts for watermarks.
+var validAnchors = []string{"tl", "tc", "tr", "l", "c", "r", "bl", "bc", "br"}
+
+// fromTopFor reports whether ov's Y is measured from the top of the page.
+func fromTopFor(ov OverlayRectText, anchor string) (bool, error) {
+	switch ov.Origin {
+	case "", "bl":
+		return false, nil
+	case "tl":
+		if anchor != "bl" {
+			return false, fmt.Errorf("origin tl cannot be combined with anchor %q", anchor)
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid origin %q (valid: bl, tl)", ov.Origin)
+}
+
+// anchorFor returns the pdfcpu anchor for ov, defaulting to bottom-left.
+func anchorFor(ov OverlayRectText) (string, error) {
+	if ov.Anchor == "" {
+		return "bl", nil
+	}
+	for _, a := range validAnchors {
+		if ov.Anchor == a {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("invalid anchor %q (valid: %s)", ov.Anchor, strings.Join(validAnchors, ", "))
+}
+
+// anchorPad returns the offset that places a part of an overlay pad points
+// larger on every side than its box around the box, for the position
+// anchor of the overlay.
+func anchorPad(anchor string, pad float64) (dx, dy float64) {
+	switch anchor {
+	case "tl", "l", "bl":
+		dx = -pad
+	case "tr", "r", "br":
+		dx = pad
+	}
+	switch anchor {
+	case "bl", "bc", "br":
+		dy = -pad
+	case "tl", "tc", "tr":
+		dy = pad
+	}
+	return dx, dy
+}
+
+// LoadTemplate reads the PDF template at name from fsys. Taking an fs.FS lets
+// callers supply embed.FS, in-memory filesystems or test fixtures.
+func LoadTemplate(fsys fs.FS, name string) ([]byte, error) {
+	return fs.ReadFile(fsys, name)
+}
+
+// ApplyOverlays reads a PDF from in, applies each overlay to it in memory and
+// writes the resulting PDF to out. Rectangles are drawn as vector content
+// and nothing touches the disk apart from reading the image and font
+// files overlays name (and installing such fonts for pdfcpu).
+func ApplyOverlays(in io.Reader, out io.Writer, overlays []OverlayRectText) error {
+	pdf, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	result, err := applyOverlays(pdf, overlays)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(result)
+	return err
+}
+
+// overlayPlan holds the pdfcpu watermark descriptions for one overlay: an
+// optional rectangle, optional raw ops and optional text, drawn in that order.
+// The watermarks themselves are built per page by watermarks because pdfcpu
+// consumes their image and PDF readers when it applies them, and because a
+// top-left origin depends on each page's height.
+type overlayPlan struct {
+	anchor string
+
+	x, y     float64
+	boxH     float64 // box height, used to flip Y for a top-left origin
+	fromTop  bool
+	rotation float64
+
+	rectImage  []byte  // encoded image, for an ImagePath or ImageData
+	rectOps    string  // vector drawing of a filled rectangle
+	rectBoxW   float64 // unscaled size of the rectOps page
+	rectBoxH   float64
+	rectParams string
+	rectW      float64 // rectangle or image size as drawn, in points
+	rectH      float64
+
+	redactW, redactH float64 // the Width x Height box as drawn, filled or not; 0 for a line
+
+	ops          string // empty when there are no raw ops
+	opsParams    string
+	opsW, opsH   float64
+	opsScale     float64
+	opsDX, opsDY float64 // offset of the ops page from the box, for a line
+
+	lines           []string // empty when there is no text
+	lineHeight      float64  // distance between stacked lines
+	firstLineOffset float64  // first line's height above the overlay's Y
+	align, valign   string   // text alignment in the alignW x alignH box
+	alignW, alignH  float64
+	textFont        string  // font used to measure the text
+	textSize        int     // font size in points; 0 means relative to the page width
+	textRelScale    float64 // pdfcpu relative text scale, used when textSize is 0
+	textParams      string
+}
+
+// planOverlay validates ov and prepares everything needed to draw it.
+func planOverlay(ov OverlayRectText) (overlayPlan, error) {
+	var p overlayPlan
+
+	anchor, err := anchorFor(ov)
+	if err != nil {
+		return p, err
+	}
+	p.anchor = anchor
+	p.fromTop, err = fromTopFor(ov, anchor)
+	if err != nil {
+		return p, err
+	}
+	p.x, p.y, p.boxH = ov.X, ov.Y, max(ov.Height, 0)
+	p.rotation = ov.Rotation
+	opacity, err := opacityFor(ov)
+	if err != nil {
+		return p, err
+	}
+	if ov.Type == "line" || ov.Type == "rect" {
+		// Lines and outlined rectangles are stroked in black by default.
+		if ov.BorderColor == "" {
+			ov.BorderColor = "black"
+		}
+		if ov.FillColor == "" {
+			ov.FillColor = "none"
+		}
+	}
+
+	// -----------------------------------------------------
+	// Image (if any), drawn in place of the rectangle
+	// -----------------------------------------------------
+	if hasImage(ov) {
+		p.rectImage, err = imageFor(ov)
+		if err != nil {
+			return p, err
+		}
+		w, h, scale, err := imageScale(p.rectImage, ov)
+		if err != nil {
+			return p, fmt.Errorf("%s: %v", imageName(ov), err)
+		}
+		p.rectParams = fmt.Sprintf("scale:%f abs, mode:0, op:%f", scale, opacity)
+		p.rectW, p.rectH = float64(w)*scale, float64(h)*scale
+	}
+
+	// -----------------------------------------------------
+	// Filled rectangle (if width/height > 0)
+	// -----------------------------------------------------
+	if ov.Type != "line" && ov.Width > 0 && ov.Height > 0 {
+		p.redactW, p.redactH = ov.Width*ov.Scale, ov.Height*ov.Scale
+	}
+	if !hasImage(ov) && ov.Type != "line" && ov.Width > 0 && ov.Height > 0 {
+		// Draw the rectangle as vector content: fill and border operators
+		// stamped like raw ops below, as a one-page PDF of exactly Width x
+		// Height points, so no image is embedded.
+		fill, err := fillColorFor(ov)
+		if err != nil {
+			return p, fmt.Errorf("fillColor: %v", err)
+		}
+		border, borderWidth, err := borderFor(ov)
+		if err != nil {
+			return p, fmt.Errorf("borderColor: %v", err)
+		}
+		p.rectOps = rectOps(ov.Width, ov.Height, fill, border, borderWidth, ov.Dash)
+	}
+	if p.rectOps != "" {
+		// Build the parameter string for the PDF watermark; watermarks
+		// prepends pos, offset and rot.
+		// scale:<Scale> abs => Scale times the Width x Height box in PDF points
+		// op:<opacity> => 1 is opaque
+		p.rectParams = fmt.Sprintf("scale:%f abs, op:%f", ov.Scale, opacity)
+		p.rectBoxW, p.rectBoxH = ov.Width, ov.Height
+		p.rectW, p.rectH = ov.Width*ov.Scale, ov.Height*ov.Scale
+	}
+
+	// -----------------------------------------------------
+	// Raw content-stream operators (if any)
+	// -----------------------------------------------------
+	if ov.Ops != "" {
+		if ov.Width <= 0 || ov.Height <= 0 {
+			return p, fmt.Errorf("ops need a positive width and height")
+		}
+		p.ops, err = sanitizeOps(ov.Ops)
+		if err != nil {
+			return p, fmt.Errorf("invalid ops: %v", err)
+		}
+		// The ops are stamped as a one-page PDF of exactly Width x Height
+		// points, placed like the rectangle above.
+		p.opsParams = fmt.Sprintf("scale:%f abs, op:%f", ov.Scale, opacity)
+		p.opsW, p.opsH, p.opsScale = ov.Width, ov.Height, ov.Scale
+	}
+
+	// -----------------------------------------------------
+	// Barcode or QR code (if any), drawn under the raw ops
+	// -----------------------------------------------------
+	code, err := codeOps(ov)
+	if err != nil {
+		return p, err
+	}
+	if code != "" {
+		p.ops = strings.TrimSpace(code + " " + p.ops)
+		p.opsParams = fmt.Sprintf("scale:%f abs, op:%f", ov.Scale, opacity)
+		p.opsW, p.opsH, p.opsScale = ov.Width, ov.Height, ov.Scale
+	}
+
+	// -----------------------------------------------------
+	// Line (for type "line"), drawn under the raw ops
+	// -----------------------------------------------------
+	if ov.Type == "line" {
+		stroke, width, err := borderFor(ov)
+		if err != nil {
+			return p, fmt.Errorf("borderColor: %v", err)
+		}
+		// The line's ends and sides stick out of the box by half its
+		// width, so it is drawn on a page that much larger on every side.
+		pad := width / 2
+		ops := lineOps(ov.Width, ov.Height, stroke, width, ov.Dash)
+		if p.ops != "" {
+			// Keep the raw ops in the coordinates of the box.
+			ops += fmt.Sprintf(" q 1 0 0 1 %f %f cm %s Q", pad, pad, p.ops)
+		}
+		p.ops = ops
+		p.opsParams = fmt.Sprintf("scale:%f abs, op:%f", ov.Scale, opacity)
+		p.opsW, p.opsH, p.opsScale = ov.Width+width, ov.Height+width, ov.Scale
+		p.opsDX, p.opsDY = anchorPad(anchor, pad*ov.Scale)
+	}
+
+	// -----------------------------------------------------
+	// Text (skipped when there is nothing to print)
+	// -----------------------------------------------------
+	if strings.TrimSpace(ov.Text) != "" {
+		textColor, err := textColorFor(ov)
+		if err != nil {
+			return p, fmt.Errorf("textColor: %v", err)
+		}
+		fontName, err := fontFor(ov)
+		if err != nil {
+			return p, err
+		}
+		if ov.LineSpacing < 0 {
+			return p, fmt.Errorf("invalid lineSpacing %g", ov.LineSpacing)
+		}
+		if ov.Wrap && ov.Width <= 0 {
+			return p, fmt.Errorf("wrap needs a positive width")
+		}
+		if err := checkAlign(ov); err != nil {
+			return p, err
+		}
+		p.align, p.valign = ov.Align, ov.VAlign
+		boxScale := ov.Scale
+		if boxScale <= 0 {
+			boxScale = 1
+		}
+		p.alignW, p.alignH = ov.Width*boxScale, ov.Height*boxScale
+		wrapWidth := 0.0
+		if ov.Wrap {
+			wrapWidth = ov.Width
+		}
+		switch {
+		case ov.Fit:
+			// Shrink to fit the drawn box, raising the first line so the
+			// whole block sits inside it.
+			if ov.Width <= 0 {
+				return p, fmt.Errorf("fit needs a positive width")
+			}
+			minSize := ov.MinFontSize
+			if minSize == 0 {
+				minSize = minAutoFitFontSize
+			}
+			size, lines := fitText(ov.Text, metricsFont(fontName), multiLineFontSizeFor(ov), minSize, p.alignW, p.alignH, wrapWidth, lineSpacingFor(ov))
+			p.lines = lines
+			p.lineHeight = font.LineHeight(metricsFont(fontName), size) * lineSpacingFor(ov)
+			p.firstLineOffset = float64(len(lines)-1) * p.lineHeight
+			p.textSize = size
+		case ov.AutoFit:
+			// Pick the largest size that fits the box and raise the first
+			// line so the whole block sits inside it.
+			if ov.Width <= 0 || ov.Height <= 0 {
+				return p, fmt.Errorf("autoFit needs a positive width and height")
+			}
+			size, lines, err := autoFitFontSize(ov.Text, metricsFont(fontName), ov.Width, ov.Height, wrapWidth, lineSpacingFor(ov))
+			if err != nil {
+				return p, err
+			}
+			p.lines = lines
+			p.lineHeight = font.LineHeight(metricsFont(fontName), size) * lineSpacingFor(ov)
+			p.firstLineOffset = float64(len(lines)-1) * p.lineHeight
+			p.textSize = size
+		case !ov.Wrap && !strings.Contains(ov.Text, "\n"):
+			// A single line is drawn at FontSize, or else spans its text
+			// scale of the page width.
+			p.lines = []string{ov.Text}
+			p.textSize = max(ov.FontSize, 0)
+			p.textRelScale = textScaleFor(ov)
+		default:
+			// Several lines: draw each at a fixed size so they match, and
+			// stack them by the font's line height times the line spacing.
+			size := multiLineFontSizeFor(ov)
+			p.lines = wrapLines(ov.Text, metricsFont(fontName), size, wrapWidth)
+			p.lineHeight = font.LineHeight(metricsFont(fontName), size) * lineSpacingFor(ov)
+			p.textSize = size
+		}
+		p.textFont = metricsFont(fontName)
+		p.textParams = fmt.Sprintf("scale:1 abs, fillc:%s, mode:0, op:%f", textColor, opacity)
+		if fontName != "" {
+			p.textParams += ", font:" + fontName
+		}
+	}
+
+	return p, nil
+}
+
+// offsetY returns the overlay's Y offset from its anchor on a page that is
+// pageH points tall.
+func (p overlayPlan) offsetY(pageH float64) float64 {
+	if p.fromTop {
+		return pageH - p.y - p.boxH
+	}
+	return p.y
+}
+
+// watermarks builds fresh pdfcpu watermarks for p, for use on a single page
+// whose visible area is pageW x pageH points.
+func (p overlayPlan) watermarks(pageW, pageH float64) ([]*model.Watermark, error) {
+	var wms []*model.Watermark
+
+	y := p.offsetY(pageH)
+	// posFor returns the pos, offset and rot parameters for a w x h part of
+	// the overlay lying dx right of its X and dy above its Y.
+	posFor := func(w, h, dx, dy float64) string {
+		if p.rotation == 0 {
+			// pos:<anchor> => anchor point on the page (bottom-left by default)
+			// offset:X Y => shift by (X, Y) relative to that anchor
+			return fmt.Sprintf("pos:%s, offset:%f %f, rot:0, ", p.anchor, p.x+dx, y+dy)
+		}
+		cx, cy := rotatedCenter(p.anchor, pageW, pageH, w, h, p.x, y, dx, dy, p.rotation)
+		return fmt.Sprintf("pos:c, offset:%f %f, rot:%f, ", cx-pageW/2, cy-pageH/2, p.rotation)
+	}
+
+	if len(p.rectImage) > 0 {
+		pos := posFor(p.rectW, p.rectH, 0, 0)
+		wm, err := api.ImageWatermarkForReader(bytes.NewReader(p.rectImage), pos+p.rectParams, true, false, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image watermark details: %v", err)
+		}
+		wms = append(wms, wm)
+	}
+
+	if p.rectOps != "" {
+		src := bytes.NewReader(opsPDF(p.rectBoxW, p.rectBoxH, p.rectOps))
+		pos := posFor(p.rectW, p.rectH, 0, 0)
+		wm, err := api.PDFWatermarkForReadSeeker(src, 1, pos+p.rectParams, true, false, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rectangle watermark details: %v", err)
+		}
+		wms = append(wms, wm)
+	}
+
+	if p.ops != "" {
+		src := bytes.NewReader(opsPDF(p.opsW, p.opsH, p.ops))
+		pos := posFor(p.opsW*p.opsScale, p.opsH*p.opsScale, p.opsDX, p.opsDY)
+		wm, err := api.PDFWatermarkForReadSeeker(src, 1, pos+p.opsParams, true, false, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ops watermark details: %v", err)
+		}
+		wms = append(wms, wm)
+	}
+
+	for i, line := range p.lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		size := p.textSize
+		if size == 0 {
+			size = relativeFontSize(line, p.textFont, p.textRelScale, pageW)
+		}
+		// Lines stack downward from the first one.
+		w, h := font.TextWidth(line, p.textFont, size), font.LineHeight(p.textFont, size)
+		dx, dy := p.lineOffset(i, w, h, pageW, pageH)
+		pos := posFor(w, h, dx, dy)
+		params := fmt.Sprintf("%spoints:%d, %s", pos, size, p.textParams)
+		wm, err := pdfcpu.ParseTextWatermarkDetails(line, params, true, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("error creating text watermark: %v", err)
+		}
+		wms = append(wms, wm)
+	}
+
+	return wms, nil
+}
+
+// planKey identifies the plan of one overlay for one page size. Overlays in
+// points are planned once, so their key has a zero page size.
+type planKey struct {
+	index        int
+	pageW, pageH float64
+}
+
+// overlayPlanner plans overlays on demand, once per overlay and page size.
+type overlayPlanner struct {
+	overlays []OverlayRectText
+	plans    map[planKey]overlayPlan
+	quiet    bool // don't log each overlay as it is planned
+}
+
+// plan returns the plan for overlay i on a pageW x pageH page.
+func (pl *overlayPlanner) plan(i int, pageW, pageH float64) (overlayPlan, error) {
+	ov := pl.overlays[i]
+	key := planKey{index: i}
+	percent, err := percentUnits(ov)
+	if err != nil {
+		return overlayPlan{}, &OverlayError{i, err}
+	}
+	if percent {
+		key.pageW, key.pageH = pageW, pageH
+	}
+	ov = inPoints(ov, pageW, pageH)
+	if plan, ok := pl.plans[key]; ok {
+		return plan, nil
+	}
+
+	plan, err := planOverlay(ov)
+	if err != nil {
+		return overlayPlan{}, &OverlayError{i, err}
+	}
+	if !pl.quiet {
+		slog.Debug("Processing overlay", "index", i, "text", ov.Text, "anchor", plan.anchor,
+			"x", ov.X, "y", ov.Y, "width", ov.Width, "height", ov.Height, "scale", ov.Scale)
+	}
+	pl.plans[key] = plan
+	return plan, nil
+}
+
+// applyOverlays applies each overlay to pdf in memory and returns the
+// resulting PDF bytes. All watermarks of all overlays are collected up front
+// and applied in a single pdfcpu pass, so the PDF is parsed and written once
+// no matter how many overlays there are.
+func applyOverlays(pdf []byte, overlays []OverlayRectText) ([]byte, error) {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return nil, err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}}
+
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.ADDWATERMARKS
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	// pdfcpu applies a single opacity to all watermarks it adds in one go,
+	// so consecutive overlays sharing an opacity are drawn in one pass and a
+	// new pass starts whenever the opacity changes. This keeps the drawing
+	// order and is still a single pass in the common case.
+	pageSets := make([]types.IntSet, len(overlays))
+	passOf := make([]int, len(overlays))
+	pass, prevOpacity := -1, 0.0
+	for i, ov := range overlays {
+		pageSets[i], err = pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		opacity, err := opacityFor(ov)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		if pass < 0 || opacity != prevOpacity {
+			pass++
+		}
+		passOf[i], prevOpacity = pass, opacity
+	}
+
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	// Per pass: page number => watermarks for that page, in drawing order.
+	passes := make([]map[int][]*model.Watermark, pass+1)
+	for i := range passes {
+		passes[i] = map[int][]*model.Watermark{}
+	}
+	drawn := false
+	for page := 1; page <= ctx.PageCount; page++ {
+		// pdfcpu positions watermarks within the crop box, as the reader
+		// sees it on a rotated page.
+		view := viewOf(boundaries[page-1])
+		pageW, pageH := view.size()
+		var redactAreas []*types.Rectangle
+		for i, ov := range overlays {
+			if !pageSets[i][page] {
+				continue
+			}
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return nil, err
+			}
+			if area := plan.redactArea(pageW, pageH); ov.Redact && area != nil {
+				redactAreas = append(redactAreas, view.toUser(area))
+			}
+			pageWMs, err := plan.watermarks(pageW, pageH)
+			if err != nil {
+				return nil, &OverlayError{i, err}
+			}
+			if len(pageWMs) > 0 {
+				wms := passes[passOf[i]]
+				wms[page] = append(wms[page], pageWMs...)
+				drawn = true
+			}
+		}
+		if len(redactAreas) > 0 {
+			n, err := redactPage(ctx.XRefTable, page, redactAreas)
+			if err != nil {
+				return nil, fmt.Errorf("redacting page %d: %v", page, err)
+			}
+			slog.Debug("Redacted glyphs and objects", "page", page, "count", n)
+			drawn = drawn || n > 0
+		}
+	}
+	if !drawn {
+		return pdf, nil
+	}
+
+	for _, wms := range passes {
+		if len(wms) == 0 {
+			continue
+		}
+		if err := pdfcpu.AddWatermarksSliceMap(ctx, wms); err != nil {
+			return nil, fmt.Errorf("failed adding overlays: %v", err)
+		}
+	}
+	// Optimize again so identical rectangle images and fonts added by the
+	// overlays are stored once.
+	if err := api.OptimizeContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed optimizing PDF: %v", err)
+	}
+
+	outBuf := new(bytes.Buffer)
+	if err := api.Write(ctx, outBuf, conf); err != nil {
+		return nil, fmt.Errorf("failed writing PDF: %v", err)
+	}
+	return outBuf.Bytes(), nil
+}
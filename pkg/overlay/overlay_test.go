@@ -0,0 +1,64 @@
+package overlay
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestEmptyOverlays checks that overlays with empty text or a zero-sized
+// rectangle apply cleanly, drawing the rectangle only when it has a size
+// and the text only when there is something to print.
+func TestEmptyOverlays(t *testing.T) {
+	tests := []struct {
+		name               string
+		text               string
+		width, height      float64
+		wantRect, wantText bool // whether the rectangle and text passes happen
+	}{
+		{name: "empty text with a size", text: "", width: 100, height: 20, wantRect: true},
+		{name: "whitespace-only text with a size", text: " \t\n ", width: 100, height: 20, wantRect: true},
+		{name: "whitespace-only text without a size", text: "   "},
+		{name: "text with zero width and height", text: "Hello", wantText: true},
+		{name: "text with zero width", text: "Hello", height: 20, wantText: true},
+		{name: "text with zero height", text: "Hello", width: 100, wantText: true},
+		{name: "text with a size", text: "Hello", width: 100, height: 20, wantRect: true, wantText: true},
+		{name: "both empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ov := OverlayRectText{Text: tt.text, X: 72, Y: 72, Width: tt.width, Height: tt.height, Scale: 1}
+			p, err := planOverlay(ov)
+			if err != nil {
+				t.Fatalf("planOverlay: %v", err)
+			}
+			if got := p.rectOps != ""; got != tt.wantRect {
+				t.Errorf("rectangle pass = %v, want %v", got, tt.wantRect)
+			}
+			if got := len(p.lines) > 0; got != tt.wantText {
+				t.Errorf("text pass = %v, want %v", got, tt.wantText)
+			}
+			wms, err := p.watermarks(612, 792)
+			if err != nil {
+				t.Fatalf("watermarks: %v", err)
+			}
+			want := 0
+			if tt.wantRect {
+				want++
+			}
+			if tt.wantText {
+				want++
+			}
+			if len(wms) != want {
+				t.Errorf("%d watermarks, want %d", len(wms), want)
+			}
+
+			var out bytes.Buffer
+			if err := ApplyOverlays(bytes.NewReader(BlankPDF(612, 792)), &out, []OverlayRectText{ov}); err != nil {
+				t.Fatalf("ApplyOverlays: %v", err)
+			}
+			if out.Len() == 0 {
+				t.Error("ApplyOverlays wrote nothing")
+			}
+		})
+	}
+}
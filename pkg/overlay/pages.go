@@ -0,0 +1,45 @@
+package overlay
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pageNumberRE matches the page numbers within a pdfcpu page selection,
+// along with a preceding "l-", which makes the number an offset from the
+// last page instead.
+var pageNumberRE = regexp.MustCompile(`(l-)?\d+`)
+
+// lastRE matches "last" as a word, which pagesFor accepts for pdfcpu's "l".
+var lastRE = regexp.MustCompile(`\blast\b`)
+
+// pagesFor resolves a pdfcpu page selection (e.g. "1", "2-3", "even",
+// "1,3-", "l" or "last" for the last page, "l-1" for the one before it)
+// against a document of pageCount pages. An empty selection means every
+// page. Unlike pdfcpu, which silently ignores pages past the end of the
+// document, it fails if the selection names a page that does not exist.
+func pagesFor(selection string, pageCount int) (types.IntSet, error) {
+	if selection == "" {
+		return api.PagesForPageSelection(pageCount, nil, true, false)
+	}
+	original := selection
+	selection = lastRE.ReplaceAllString(selection, "l")
+	for _, m := range pageNumberRE.FindAllString(selection, -1) {
+		if strings.HasPrefix(m, "l-") {
+			continue
+		}
+		if n, err := strconv.Atoi(m); err != nil || n > pageCount {
+			return nil, fmt.Errorf("pages %q: page %s is beyond the document's %d pages", original, m, pageCount)
+		}
+	}
+	sel, err := api.ParsePageSelection(selection)
+	if err != nil {
+		return nil, fmt.Errorf("pages %q: %v", original, err)
+	}
+	return api.PagesForPageSelection(pageCount, sel, true, false)
+}
@@ -0,0 +1,182 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// previewScale is the number of preview pixels per PDF point.
+const previewScale = 2
+
+// previewFillAlpha is how opaque the fill of each overlay's box is in a
+// preview, out of 255; previewOpacity is the same for PreviewOverlays.
+const (
+	previewFillAlpha = 0x40
+	previewOpacity   = 0.25
+)
+
+var (
+	previewContent = color.RGBA{0xc8, 0xc8, 0xc8, 0xff}
+	previewPalette = []color.RGBA{
+		{0xe6, 0x19, 0x4b, 0xff}, // red
+		{0x43, 0x63, 0xd8, 0xff}, // blue
+		{0x3c, 0xb4, 0x4b, 0xff}, // green
+		{0xf5, 0x82, 0x31, 0xff}, // orange
+		{0x91, 0x1e, 0xb4, 0xff}, // purple
+		{0x46, 0x99, 0x90, 0xff}, // teal
+	}
+)
+
+// Preview writes a PNG of page pageNr of pdf to out with a coloured outline,
+// labelled with its index, where each overlay on that page would be drawn.
+// There is no PDF renderer to hand, so the page itself is shown as a
+// wireframe: a grey box for every glyph, image and form its content stream
+// draws. pdf is only read.
+func Preview(pdf []byte, overlays []OverlayRectText, pageNr int, out io.Writer) error {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return readError(err)
+	}
+	if pageNr < 1 || pageNr > ctx.PageCount {
+		return fmt.Errorf("page %d is beyond the document's %d pages", pageNr, ctx.PageCount)
+	}
+	boundaries, err := ctx.PageBoundaries(types.IntSet{pageNr: true})
+	if err != nil {
+		return fmt.Errorf("failed reading page sizes: %v", err)
+	}
+	// The page is drawn as the reader sees it, turned by its /Rotate.
+	view := viewOf(boundaries[pageNr-1])
+	pageW, pageH := view.size()
+
+	img := image.NewRGBA(image.Rect(0, 0, int(pageW*previewScale), int(pageH*previewScale)))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	// toPixels converts r, relative to the view's lower-left corner, to
+	// image pixels, whose y axis points down.
+	toPixels := func(r *types.Rectangle) image.Rectangle {
+		return image.Rect(int(r.LL.X*previewScale), int((pageH-r.UR.Y)*previewScale),
+			int(r.UR.X*previewScale), int((pageH-r.LL.Y)*previewScale))
+	}
+
+	// The page: everything its content stream draws inside the crop box.
+	r, _, _, _, err := scanPage(ctx.XRefTable, pageNr, []*types.Rectangle{view.crop}, formsSkipped)
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		for _, b := range r.boxes {
+			outline(img, toPixels(view.fromUser(b)), previewContent, 1)
+		}
+	}
+
+	// The overlays, in drawing order.
+	for i, ov := range overlays {
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return &OverlayError{i, err}
+		}
+		if !pages[pageNr] {
+			continue
+		}
+		plan, err := planner.plan(i, pageW, pageH)
+		if err != nil {
+			return err
+		}
+		b := plan.bounds(pageW, pageH)
+		if b == nil {
+			continue
+		}
+		c := previewPalette[i%len(previewPalette)]
+		box := toPixels(b)
+		fill := color.NRGBA{c.R, c.G, c.B, previewFillAlpha}
+		draw.Draw(img, box.Intersect(img.Bounds()), image.NewUniform(fill), image.Point{}, draw.Over)
+		outline(img, box, c, 2)
+		label(img, box.Min, strconv.Itoa(i), c)
+	}
+
+	return png.Encode(out, img)
+}
+
+// PreviewOverlays returns overlays that show where overlays would be drawn
+// without hiding or removing anything: each rectangle becomes a
+// see-through box outlined in a colour of its own and labelled with the
+// overlay's index in its top-left corner, and text and images keep their
+// place, in the same colour. Nothing is redacted. Applied to the original
+// PDF, they are a dry run that can be checked against its content.
+func PreviewOverlays(overlays []OverlayRectText) []OverlayRectText {
+	var preview []OverlayRectText
+	for i, ov := range overlays {
+		c := previewPalette[i%len(previewPalette)]
+		hex := fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+		ov.Redact, ov.Field, ov.Label = false, "", ""
+		if ov.Width <= 0 || ov.Height <= 0 {
+			ov.TextColor = hex
+			preview = append(preview, ov)
+			continue
+		}
+		box := ov
+		box.Text, box.ImagePath, box.ImageData, box.Ops, box.Type = "", "", "", "", ""
+		box.FillColor, box.BorderColor, box.BorderWidth = hex, hex, 1
+		opacity := previewOpacity
+		box.Opacity = &opacity
+		label := box
+		label.Text, label.TextColor = strconv.Itoa(i), hex
+		label.FillColor, label.BorderColor, label.Opacity = "none", "", nil
+		label.FontSize, label.Bold, label.Font, label.FontFile = 8, true, "", ""
+		label.Wrap, label.AutoFit, label.Fit, label.Align, label.VAlign = false, false, false, "left", "top"
+		preview = append(preview, box, label)
+		if ov.Text != "" {
+			ov.FillColor, ov.BorderColor, ov.ImagePath, ov.ImageData, ov.Ops, ov.Type = "none", "", "", "", "", ""
+			ov.TextColor = hex
+			preview = append(preview, ov)
+		}
+	}
+	return preview
+}
+
+// outline draws the edges of r, width pixels wide, in c.
+func outline(img *image.RGBA, r image.Rectangle, c color.Color, width int) {
+	src := image.NewUniform(c)
+	for _, edge := range []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+width),
+		image.Rect(r.Min.X, r.Max.Y-width, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y, r.Min.X+width, r.Max.Y),
+		image.Rect(r.Max.X-width, r.Min.Y, r.Max.X, r.Max.Y),
+	} {
+		draw.Draw(img, edge.Intersect(img.Bounds()), src, image.Point{}, draw.Over)
+	}
+}
+
+// label draws text in white on a c background with its top-left corner at at.
+func label(img *image.RGBA, at image.Point, text string, c color.Color) {
+	face := basicfont.Face7x13
+	w := font.MeasureString(face, text).Ceil()
+	bg := image.Rect(at.X, at.Y, at.X+w+4, at.Y+face.Height+2)
+	draw.Draw(img, bg.Intersect(img.Bounds()), image.NewUniform(c), image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(at.X+2, at.Y+1+face.Ascent),
+	}
+	d.DrawString(text)
+}
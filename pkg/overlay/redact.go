@@ -0,0 +1,741 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Redaction removes what lies under an overlay's rectangle from the page
+// content instead of only covering it: glyphs whose boxes touch a redaction
+// area are dropped from their text-showing operators (the text after them
+// keeps its position), and images and form XObjects lying entirely inside
+// one are removed. A form XObject only partly inside is redacted the same
+// way, in a copy that replaces it on the page, so it is left as it was
+// wherever else it is drawn. Vector paths are left alone; the cover
+// rectangle still hides them.
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m x n, i.e. m applied first and then n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// bounds returns the bounding box of the rectangle (llx, lly)-(urx, ury)
+// transformed by m.
+func (m matrix) bounds(llx, lly, urx, ury float64) *types.Rectangle {
+	r := types.NewRectangle(math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1))
+	for _, p := range [][2]float64{{llx, lly}, {urx, lly}, {llx, ury}, {urx, ury}} {
+		x := p[0]*m[0] + p[1]*m[2] + m[4]
+		y := p[0]*m[1] + p[1]*m[3] + m[5]
+		r.LL.X, r.LL.Y = min(r.LL.X, x), min(r.LL.Y, y)
+		r.UR.X, r.UR.Y = max(r.UR.X, x), max(r.UR.Y, y)
+	}
+	return r
+}
+
+// overlaps reports whether r and any of areas share some interior.
+func overlaps(r *types.Rectangle, areas []*types.Rectangle) bool {
+	for _, a := range areas {
+		if r.LL.X < a.UR.X && a.LL.X < r.UR.X && r.LL.Y < a.UR.Y && a.LL.Y < r.UR.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether r lies entirely inside one of areas.
+func within(r *types.Rectangle, areas []*types.Rectangle) bool {
+	for _, a := range areas {
+		if r.LL.X >= a.LL.X && r.UR.X <= a.UR.X && r.LL.Y >= a.LL.Y && r.UR.Y <= a.UR.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// contentFont holds the metrics needed to lay out a font's glyphs. Widths,
+// ascent and descent are in text space units per unit of font size.
+type contentFont struct {
+	twoByte         bool // composite font with 2-byte codes
+	widths          map[int]float64
+	defaultWidth    float64
+	ascent, descent float64
+}
+
+// loadContentFont reads the metrics of font dict d.
+func loadContentFont(xRefTable *model.XRefTable, d types.Dict) *contentFont {
+	f := &contentFont{widths: map[int]float64{}, defaultWidth: 0.5, ascent: 0.8, descent: -0.2}
+	if d == nil {
+		return f
+	}
+	num := func(o types.Object) float64 {
+		n, _ := xRefTable.DereferenceNumber(o)
+		return n
+	}
+	subtype := ""
+	if st := d.Subtype(); st != nil {
+		subtype = *st
+	}
+
+	if fd, _ := xRefTable.DereferenceDict(d["FontDescriptor"]); fd != nil {
+		if a := num(fd["Ascent"]); a > 0 {
+			f.ascent = a / 1000
+		}
+		if dsc := num(fd["Descent"]); dsc < 0 {
+			f.descent = dsc / 1000
+		}
+		if mw := num(fd["MissingWidth"]); mw > 0 {
+			f.defaultWidth = mw / 1000
+		}
+	}
+
+	switch subtype {
+	case "Type0":
+		f.twoByte = true
+		f.defaultWidth = 1
+		fonts, _ := xRefTable.DereferenceArray(d["DescendantFonts"])
+		if len(fonts) == 0 {
+			return f
+		}
+		cid, _ := xRefTable.DereferenceDict(fonts[0])
+		if cid == nil {
+			return f
+		}
+		if fd, _ := xRefTable.DereferenceDict(cid["FontDescriptor"]); fd != nil {
+			if a := num(fd["Ascent"]); a > 0 {
+				f.ascent = a / 1000
+			}
+			if dsc := num(fd["Descent"]); dsc < 0 {
+				f.descent = dsc / 1000
+			}
+		}
+		if dw, ok := cid["DW"]; ok {
+			f.defaultWidth = num(dw) / 1000
+		}
+		w, _ := xRefTable.DereferenceArray(cid["W"])
+		for i := 0; i+1 < len(w); {
+			first := int(num(w[i]))
+			if ws, _ := xRefTable.DereferenceArray(w[i+1]); ws != nil {
+				for j, o := range ws {
+					f.widths[first+j] = num(o) / 1000
+				}
+				i += 2
+				continue
+			}
+			if i+2 >= len(w) {
+				break
+			}
+			last, width := int(num(w[i+1])), num(w[i+2])/1000
+			for c := first; c <= last && c-first < 0x10000; c++ {
+				f.widths[c] = width
+			}
+			i += 3
+		}
+		return f
+	case "Type3":
+		scale := 0.001
+		if fm, _ := xRefTable.DereferenceArray(d["FontMatrix"]); len(fm) == 6 {
+			scale = num(fm[0])
+			if bb, _ := xRefTable.DereferenceArray(d["FontBBox"]); len(bb) == 4 {
+				yScale := num(fm[3])
+				f.descent, f.ascent = min(num(bb[1])*yScale, 0), max(num(bb[3])*yScale, 0)
+			}
+		}
+		first := int(num(d["FirstChar"]))
+		ws, _ := xRefTable.DereferenceArray(d["Widths"])
+		for i, o := range ws {
+			f.widths[first+i] = num(o) * scale
+		}
+		return f
+	}
+
+	first := int(num(d["FirstChar"]))
+	ws, _ := xRefTable.DereferenceArray(d["Widths"])
+	for i, o := range ws {
+		f.widths[first+i] = num(o) / 1000
+	}
+	if len(ws) == 0 {
+		// The standard 14 fonts may omit their widths.
+		if base, ok := d["BaseFont"].(types.Name); ok && font.IsCoreFont(string(base)) {
+			for c := 0; c < 256; c++ {
+				f.widths[c] = float64(font.CharWidth(string(base), rune(c))) / 1000
+			}
+		}
+	}
+	return f
+}
+
+// width returns the width of the glyph for code.
+func (f *contentFont) width(code int) float64 {
+	if w, ok := f.widths[code]; ok {
+		return w
+	}
+	return f.defaultWidth
+}
+
+// textState is the part of the graphics state that affects text layout.
+type textState struct {
+	font                 *contentFont
+	size                 float64
+	charSpace, wordSpace float64
+	hScale               float64
+	leading, rise        float64
+}
+
+// graphicsState is what q saves and Q restores.
+type graphicsState struct {
+	ctm matrix
+	textState
+}
+
+// pageRedactor walks one content stream and rewrites the operations that
+// show something inside the redaction areas.
+type pageRedactor struct {
+	xRefTable *model.XRefTable
+	resources types.Dict
+	areas     []*types.Rectangle
+	fonts     map[string]*contentFont
+
+	gs       graphicsState
+	stack    []graphicsState
+	tm, tlm  matrix
+	removed  int // glyphs and objects removed
+	replaced map[int][]byte
+	found    []byte             // the removed glyphs as text, where the codes are ASCII
+	boxes    []*types.Rectangle // bounds of the removed glyphs and objects
+	runs     []textRun          // the removed glyphs of each text-showing operation
+
+	forms     formMode
+	formDepth int
+	xobjects  map[string]types.IndirectRef // redacted copies of forms, by the names the content now uses
+	err       error                        // from storing a redacted form
+}
+
+// formMode is what a pageRedactor does with the form XObjects the content
+// draws, beyond removing those entirely inside the redaction areas.
+type formMode int
+
+const (
+	// formsSkipped leaves the rest of them alone.
+	formsSkipped formMode = iota
+	// formsRead reads their text too, as if it were part of the content.
+	// Only what it finds counts; operations inside forms are never
+	// replaced.
+	formsRead
+	// formsRedacted redacts those partly inside the areas like the
+	// content, each into a copy that the content draws instead.
+	formsRedacted
+)
+
+// maxFormDepth limits how deeply forms drawing forms are read.
+const maxFormDepth = 8
+
+// textRun is the text one text-showing operation (Tj, TJ, ' or ") removed,
+// with the bounds of its glyphs in default user space.
+type textRun struct {
+	text string
+	box  *types.Rectangle
+}
+
+// resource returns the resource named name in category (e.g. "Font").
+func (r *pageRedactor) resource(category, name string) types.Dict {
+	cat, _ := r.xRefTable.DereferenceDict(r.resources[category])
+	if cat == nil {
+		return nil
+	}
+	d, _ := r.xRefTable.DereferenceDict(cat[name])
+	if d == nil {
+		// XObjects are streams.
+		sd, _, _ := r.xRefTable.DereferenceStreamDict(cat[name])
+		if sd != nil {
+			return sd.Dict
+		}
+	}
+	return d
+}
+
+// setFont selects the font resource name.
+func (r *pageRedactor) setFont(name string) {
+	f, ok := r.fonts[name]
+	if !ok {
+		f = loadContentFont(r.xRefTable, r.resource("Font", name))
+		r.fonts[name] = f
+	}
+	r.gs.font = f
+}
+
+// nextLine moves to the start of the next line offset by (tx, ty).
+func (r *pageRedactor) nextLine(tx, ty float64) {
+	r.tlm = matrix{1, 0, 0, 1, tx, ty}.mul(r.tlm)
+	r.tm = r.tlm
+}
+
+// show lays out the strings and kerning numbers of a text-showing operation
+// and returns a TJ array without the glyphs that fall inside the redaction
+// areas, or nil if nothing was removed.
+func (r *pageRedactor) show(parts []contentObj) []byte {
+	ts := r.gs.textState
+	if ts.font == nil {
+		ts.font = loadContentFont(r.xRefTable, nil)
+	}
+	var out bytes.Buffer
+	var kept []byte
+	var kern float64 // pending adjustment, in thousandths of text space
+	removed := false
+	found, boxes := len(r.found), len(r.boxes)
+	defer func() {
+		if len(r.boxes) == boxes {
+			return
+		}
+		run := textRun{text: string(r.found[found:])}
+		for _, b := range r.boxes[boxes:] {
+			run.box = union(run.box, b)
+		}
+		r.runs = append(r.runs, run)
+	}()
+	flush := func() {
+		if len(kept) > 0 {
+			fmt.Fprintf(&out, "<%x>", kept)
+			kept = kept[:0]
+		}
+		if kern != 0 {
+			out.WriteString(" " + strconv.FormatFloat(kern, 'f', -1, 64) + " ")
+			kern = 0
+		}
+	}
+
+	for _, part := range parts {
+		if part.kind == 'n' {
+			flush()
+			kern = part.num
+			r.tm = matrix{1, 0, 0, 1, -part.num / 1000 * ts.size * ts.hScale, 0}.mul(r.tm)
+			continue
+		}
+		if part.kind != 's' {
+			continue
+		}
+		step := 1
+		if ts.font.twoByte {
+			step = 2
+		}
+		for i := 0; i+step <= len(part.str); i += step {
+			code := int(part.str[i])
+			if step == 2 {
+				code = code<<8 | int(part.str[i+1])
+			}
+			w0 := ts.font.width(code)
+			adv := w0*ts.size + ts.charSpace // before horizontal scaling
+			if step == 1 && code == ' ' {
+				adv += ts.wordSpace
+			}
+			tx := adv * ts.hScale
+
+			trm := matrix{ts.size * ts.hScale, 0, 0, ts.size, 0, ts.rise}.mul(r.tm).mul(r.gs.ctm)
+			if glyph := trm.bounds(0, ts.font.descent, w0, ts.font.ascent); ts.size != 0 && overlaps(glyph, r.areas) {
+				// Drop the glyph but keep its advance.
+				if len(kept) > 0 {
+					flush()
+				}
+				// Tz scales TJ adjustments like the advance, so hScale,
+				// which may be 0, cancels out; size isn't 0 here.
+				kern -= adv / ts.size * 1000
+				removed = true
+				r.removed++
+				r.boxes = append(r.boxes, glyph)
+				if step == 1 && code >= ' ' && code <= '~' {
+					r.found = append(r.found, byte(code))
+				} else {
+					r.found = append(r.found, '?')
+				}
+			} else {
+				if kern != 0 {
+					flush()
+				}
+				kept = append(kept, part.str[i:i+step]...)
+			}
+			r.tm = matrix{1, 0, 0, 1, tx, 0}.mul(r.tm)
+		}
+	}
+	if !removed {
+		return nil
+	}
+	flush()
+	return []byte("[" + out.String() + "] TJ")
+}
+
+// objectBounds returns the bounding box of XObject d drawn with the current
+// transformation matrix.
+func (r *pageRedactor) objectBounds(d types.Dict) *types.Rectangle {
+	if d != nil && d.Subtype() != nil && *d.Subtype() == "Form" {
+		bb, _ := r.xRefTable.DereferenceArray(d["BBox"])
+		if len(bb) == 4 {
+			n := make([]float64, 4)
+			for i := range n {
+				n[i], _ = r.xRefTable.DereferenceNumber(bb[i])
+			}
+			m := identity
+			if fm, _ := r.xRefTable.DereferenceArray(d["Matrix"]); len(fm) == 6 {
+				for i := range m {
+					m[i], _ = r.xRefTable.DereferenceNumber(fm[i])
+				}
+			}
+			return m.mul(r.gs.ctm).bounds(n[0], n[1], n[2], n[3])
+		}
+	}
+	return r.gs.ctm.bounds(0, 0, 1, 1)
+}
+
+// nums returns the numeric operands of op, or nil if there are fewer than n.
+func nums(op contentOp, n int) []float64 {
+	if len(op.operands) < n {
+		return nil
+	}
+	out := make([]float64, n)
+	for i, o := range op.operands[len(op.operands)-n:] {
+		if o.kind != 'n' {
+			return nil
+		}
+		out[i] = o.num
+	}
+	return out
+}
+
+// run interprets ops and records the replacement of every operation that
+// shows something inside the redaction areas.
+func (r *pageRedactor) run(ops []contentOp) {
+	for i, op := range ops {
+		switch op.op {
+		case "q":
+			r.stack = append(r.stack, r.gs)
+		case "Q":
+			if len(r.stack) > 0 {
+				r.gs = r.stack[len(r.stack)-1]
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+		case "cm":
+			if n := nums(op, 6); n != nil {
+				r.gs.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.mul(r.gs.ctm)
+			}
+		case "BT":
+			r.tm, r.tlm = identity, identity
+		case "Tf":
+			if len(op.operands) == 2 && op.operands[0].kind == '/' && op.operands[1].kind == 'n' {
+				r.setFont(op.operands[0].name)
+				r.gs.size = op.operands[1].num
+			}
+		case "Tc":
+			if n := nums(op, 1); n != nil {
+				r.gs.charSpace = n[0]
+			}
+		case "Tw":
+			if n := nums(op, 1); n != nil {
+				r.gs.wordSpace = n[0]
+			}
+		case "Tz":
+			if n := nums(op, 1); n != nil {
+				r.gs.hScale = n[0] / 100
+			}
+		case "TL":
+			if n := nums(op, 1); n != nil {
+				r.gs.leading = n[0]
+			}
+		case "Ts":
+			if n := nums(op, 1); n != nil {
+				r.gs.rise = n[0]
+			}
+		case "Td":
+			if n := nums(op, 2); n != nil {
+				r.nextLine(n[0], n[1])
+			}
+		case "TD":
+			if n := nums(op, 2); n != nil {
+				r.gs.leading = -n[1]
+				r.nextLine(n[0], n[1])
+			}
+		case "Tm":
+			if n := nums(op, 6); n != nil {
+				r.tlm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+				r.tm = r.tlm
+			}
+		case "T*":
+			r.nextLine(0, -r.gs.leading)
+		case "Tj":
+			if len(op.operands) == 1 {
+				if tj := r.show(op.operands); tj != nil {
+					r.replaced[i] = tj
+				}
+			}
+		case "TJ":
+			if len(op.operands) == 1 && op.operands[0].kind == 'a' {
+				if tj := r.show(op.operands[0].elems); tj != nil {
+					r.replaced[i] = tj
+				}
+			}
+		case "'":
+			r.nextLine(0, -r.gs.leading)
+			if len(op.operands) == 1 {
+				if tj := r.show(op.operands); tj != nil {
+					r.replaced[i] = append([]byte("T* "), tj...)
+				}
+			}
+		case "\"":
+			if len(op.operands) == 3 && op.operands[0].kind == 'n' && op.operands[1].kind == 'n' {
+				aw, ac := op.operands[0].num, op.operands[1].num
+				r.gs.wordSpace, r.gs.charSpace = aw, ac
+				r.nextLine(0, -r.gs.leading)
+				if tj := r.show(op.operands[2:]); tj != nil {
+					r.replaced[i] = append([]byte(fmt.Sprintf("%g Tw %g Tc T* ", aw, ac)), tj...)
+				}
+			}
+		case "Do":
+			if len(op.operands) == 1 && op.operands[0].kind == '/' {
+				if r.forms == formsRead && r.runForm(i, op.operands[0].name) {
+					break
+				}
+				b := r.objectBounds(r.resource("XObject", op.operands[0].name))
+				switch {
+				case within(b, r.areas):
+					r.replaced[i] = nil
+					r.removed++
+					r.boxes = append(r.boxes, b)
+				case r.forms == formsRedacted && overlaps(b, r.areas):
+					r.runForm(i, op.operands[0].name)
+				}
+			}
+		case "BI":
+			if b := r.gs.ctm.bounds(0, 0, 1, 1); within(b, r.areas) {
+				r.replaced[i] = nil
+				r.removed++
+				r.boxes = append(r.boxes, b)
+			}
+		}
+	}
+}
+
+// runForm runs the form XObject resource name, drawn by operation i, as
+// part of the content and reports whether it is a form. When redacting
+// forms, operation i is replaced to draw a redacted copy of the form if
+// anything inside it is removed.
+func (r *pageRedactor) runForm(i int, name string) bool {
+	cat, _ := r.xRefTable.DereferenceDict(r.resources["XObject"])
+	if cat == nil || r.formDepth >= maxFormDepth {
+		return false
+	}
+	sd, _, err := r.xRefTable.DereferenceStreamDict(cat[name])
+	if err != nil || sd == nil || sd.Dict.Subtype() == nil || *sd.Dict.Subtype() != "Form" {
+		return false
+	}
+	if err := sd.Decode(); err != nil {
+		return true
+	}
+	ops, err := parseContent(sd.Content)
+	if err != nil {
+		return true
+	}
+
+	f := &pageRedactor{
+		xRefTable: r.xRefTable,
+		resources: r.resources,
+		areas:     r.areas,
+		fonts:     r.fonts,
+		gs:        r.gs,
+		tm:        r.tm,
+		tlm:       r.tlm,
+		replaced:  map[int][]byte{},
+		forms:     r.forms,
+		formDepth: r.formDepth + 1,
+	}
+	if res, _ := r.xRefTable.DereferenceDict(sd.Dict["Resources"]); res != nil {
+		f.resources, f.fonts = res, map[string]*contentFont{}
+	}
+	if fm, _ := r.xRefTable.DereferenceArray(sd.Dict["Matrix"]); len(fm) == 6 {
+		var m matrix
+		for i := range m {
+			m[i], _ = r.xRefTable.DereferenceNumber(fm[i])
+		}
+		f.gs.ctm = m.mul(f.gs.ctm)
+	}
+	f.run(ops)
+	r.removed += f.removed
+	r.found = append(r.found, f.found...)
+	r.boxes = append(r.boxes, f.boxes...)
+	r.runs = append(r.runs, f.runs...)
+	if f.err != nil {
+		r.err = f.err
+	}
+	if r.forms != formsRedacted || len(f.replaced) == 0 || r.err != nil {
+		return true
+	}
+
+	// The form may be drawn elsewhere too, so it is copied rather than
+	// changed.
+	form, err := r.xRefTable.NewStreamDictForBuf(f.rewrite(sd.Content, ops))
+	if err == nil {
+		for k, v := range sd.Dict {
+			if k != "Length" && k != "Filter" && k != "DecodeParms" {
+				form.Dict[k] = v
+			}
+		}
+		if len(f.xobjects) > 0 {
+			form.Dict["Resources"] = f.newResources()
+		}
+		err = form.Encode()
+	}
+	var ir *types.IndirectRef
+	if err == nil {
+		ir, err = r.xRefTable.IndRefForNewObject(*form)
+	}
+	if err != nil {
+		r.err = fmt.Errorf("redacting form %s: %v", name, err)
+		return true
+	}
+	r.replaced[i] = []byte("/" + r.addXObject(*ir) + " Do")
+	return true
+}
+
+// addXObject adds XObject ir to the resources r's content will use under a
+// new name, which it returns.
+func (r *pageRedactor) addXObject(ir types.IndirectRef) string {
+	cat, _ := r.xRefTable.DereferenceDict(r.resources["XObject"])
+	if r.xobjects == nil {
+		r.xobjects = map[string]types.IndirectRef{}
+	}
+	for n := len(r.xobjects) + 1; ; n++ {
+		name := fmt.Sprintf("Redacted%d", n)
+		_, inCat := cat[name]
+		if _, added := r.xobjects[name]; !inCat && !added {
+			r.xobjects[name] = ir
+			return name
+		}
+	}
+}
+
+// newResources returns a copy of r's resources with the XObjects it added.
+func (r *pageRedactor) newResources() types.Dict {
+	res := types.Dict{}
+	for k, v := range r.resources {
+		res[k] = v
+	}
+	xobjects := types.Dict{}
+	if cat, _ := r.xRefTable.DereferenceDict(r.resources["XObject"]); cat != nil {
+		for k, v := range cat {
+			xobjects[k] = v
+		}
+	}
+	for k, v := range r.xobjects {
+		xobjects[k] = v
+	}
+	res["XObject"] = xobjects
+	return res
+}
+
+// rewrite returns content, whose operations are ops, with the operations r
+// replaced.
+func (r *pageRedactor) rewrite(content []byte, ops []contentOp) []byte {
+	var out bytes.Buffer
+	prev := 0
+	for i, op := range ops {
+		repl, ok := r.replaced[i]
+		if !ok {
+			continue
+		}
+		out.Write(content[prev:op.start])
+		out.Write(repl)
+		prev = op.end
+	}
+	out.Write(content[prev:])
+	return out.Bytes()
+}
+
+// scanPage runs a pageRedactor for areas (in default user space) over the
+// content of page pageNr, treating the forms it draws as forms says. It
+// returns the redactor, nil if the page has no content, along with the page
+// dictionary, content and operations.
+func scanPage(xRefTable *model.XRefTable, pageNr int, areas []*types.Rectangle, forms formMode) (*pageRedactor, types.Dict, []byte, []contentOp, error) {
+	d, _, inhPAttrs, err := xRefTable.PageDict(pageNr, false)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	content, err := xRefTable.PageContent(d)
+	if err != nil || len(content) == 0 {
+		return nil, nil, nil, nil, err
+	}
+	ops, err := parseContent(content)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("page %d: parsing content: %v", pageNr, err)
+	}
+
+	r := &pageRedactor{
+		xRefTable: xRefTable,
+		resources: inhPAttrs.Resources,
+		areas:     areas,
+		fonts:     map[string]*contentFont{},
+		gs:        graphicsState{ctm: identity, textState: textState{hScale: 1}},
+		replaced:  map[int][]byte{},
+		forms:     forms,
+	}
+	r.run(ops)
+	return r, d, content, ops, nil
+}
+
+// redactPage removes the glyphs, images and form XObjects under areas (in
+// default user space) from the content of page pageNr, and of the forms it
+// draws, and returns how many it removed.
+func redactPage(xRefTable *model.XRefTable, pageNr int, areas []*types.Rectangle) (int, error) {
+	r, d, content, ops, err := scanPage(xRefTable, pageNr, areas, formsRedacted)
+	if err == nil && r != nil {
+		err = r.err
+	}
+	if err != nil || r == nil || len(r.replaced) == 0 {
+		return 0, err
+	}
+
+	ir, err := newContentStream(xRefTable, r.rewrite(content, ops))
+	if err != nil {
+		return 0, err
+	}
+	d.Update("Contents", *ir)
+	if len(r.xobjects) > 0 {
+		d.Update("Resources", r.newResources())
+	}
+	return r.removed, nil
+}
+
+// rectArea returns the area p's rectangle covers on a pageW x pageH page,
+// relative to the page's lower-left corner, or nil if p has no rectangle.
+func (p overlayPlan) rectArea(pageW, pageH float64) *types.Rectangle {
+	if len(p.rectImage) == 0 && p.rectOps == "" {
+		return nil
+	}
+	return p.partArea(pageW, pageH, p.rectW, p.rectH, 0, 0)
+}
+
+// redactArea returns the area Redact clears on a pageW x pageH page: that
+// of p's rectangle or image, or else of its box, drawn or not. It is nil
+// if p has neither, as for a line.
+func (p overlayPlan) redactArea(pageW, pageH float64) *types.Rectangle {
+	if r := p.rectArea(pageW, pageH); r != nil {
+		return r
+	}
+	if p.redactW <= 0 || p.redactH <= 0 {
+		return nil
+	}
+	return p.partArea(pageW, pageH, p.redactW, p.redactH, 0, 0)
+}
@@ -0,0 +1,116 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// formPDF builds a single-page US Letter PDF whose page content is content
+// and which has the Helvetica font F1 and the form XObject Fm1, covering the
+// page, whose content is form.
+func formPDF(content, form string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict, s string) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(s), s)
+	}
+
+	buf.WriteString("%PDF-1.7\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	obj("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> /XObject << /Fm1 6 0 R >> >> /Contents 4 0 R >>")
+	obj(stream("", content))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	obj(stream("/Type /XObject /Subtype /Form /BBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> >>", form))
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// TestRedactRemovesText checks that after redacting an area, no text is left
+// in it, whether the page shows it or a form XObject does, and that the
+// text outside it is kept.
+func TestRedactRemovesText(t *testing.T) {
+	const (
+		text     = "BT /F1 12 Tf 72 700 Td (PAGE SECRET) Tj ET BT /F1 12 Tf 72 100 Td (PAGE KEPT) Tj ET "
+		formText = "BT /F1 12 Tf 300 700 Td (FORM SECRET) Tj ET BT /F1 12 Tf 300 60 Td (FORM KEPT) Tj ET"
+		// The form drawn as it is, and again 400 points lower.
+		drawForm      = "q /Fm1 Do Q "
+		drawFormLower = "q 1 0 0 1 0 -400 cm /Fm1 Do Q "
+	)
+	tests := []struct {
+		name          string
+		content, form string
+		wantKept      []string // text runs left outside the area
+	}{
+		{name: "page text", content: text, wantKept: []string{"PAGE KEPT"}},
+		{name: "form text", content: drawForm, form: formText, wantKept: []string{"FORM KEPT"}},
+		{
+			name:    "form drawn twice",
+			content: drawForm + drawFormLower, form: formText,
+			wantKept: []string{"FORM KEPT", "FORM SECRET"},
+		},
+		{
+			name:    "page and form text",
+			content: text + drawForm, form: formText,
+			wantKept: []string{"PAGE KEPT", "FORM KEPT"},
+		},
+		{name: "zero horizontal scaling", content: "BT /F1 12 Tf 0 Tz 72 700 Td (SQUASHED) Tj ET"},
+		{name: "zero font size", content: "BT /F1 0 Tf 72 700 Td (TINY) Tj ET"},
+	}
+	area := OverlayRectText{X: 50, Y: 680, Width: 510, Height: 50, Scale: 1, Redact: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := ApplyOverlays(bytes.NewReader(formPDF(tt.content, tt.form)), &out, []OverlayRectText{area})
+			if err != nil {
+				t.Fatalf("ApplyOverlays: %v", err)
+			}
+			runs, err := textRuns(out.Bytes(), "", true)
+			if err != nil {
+				t.Fatalf("reading the text: %v", err)
+			}
+			var kept []string
+			for _, run := range runs {
+				if run.X < area.X+area.Width && area.X < run.X+run.Width &&
+					run.Y < area.Y+area.Height && area.Y < run.Y+run.Height {
+					t.Errorf("%q is left in the area at %g, %g", run.Text, run.X, run.Y)
+				}
+				kept = append(kept, run.Text)
+			}
+			if got, want := strings.Join(kept, "|"), strings.Join(tt.wantKept, "|"); got != want {
+				t.Errorf("text left = %q, want %q", got, want)
+			}
+
+			ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("reading the result: %v", err)
+			}
+			d, _, _, err := ctx.PageDict(1, false)
+			if err != nil {
+				t.Fatalf("reading page 1: %v", err)
+			}
+			content, err := ctx.PageContent(d)
+			if err != nil {
+				t.Fatalf("reading the page content: %v", err)
+			}
+			if bytes.Contains(content, []byte("NaN")) || bytes.Contains(content, []byte("Inf")) {
+				t.Errorf("content has a non-finite number: %s", content)
+			}
+		})
+	}
+}
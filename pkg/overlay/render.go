@@ -0,0 +1,57 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"strings"
+)
+
+// ImageFormats are the formats RenderImages writes, by name.
+var ImageFormats = []string{"png", "jpeg", "tiff"}
+
+// renderJPEGQuality is the quality of the JPEG pages of RenderImages.
+const renderJPEGQuality = 90
+
+// RenderImages rasterizes every page of pdf at dpi dots per inch with
+// rasterize and encodes the pages in format, one of ImageFormats. PNG and
+// JPEG give one image per page; TIFF gives a single multi-page image.
+func RenderImages(pdf []byte, format string, dpi int, rasterize Rasterizer) ([][]byte, error) {
+	if !slices.Contains(ImageFormats, format) {
+		return nil, fmt.Errorf("unknown image format %q (valid: %s)", format, strings.Join(ImageFormats, ", "))
+	}
+	if dpi < 36 || dpi > 1200 {
+		return nil, fmt.Errorf("dpi %d out of range (36 to 1200)", dpi)
+	}
+	pages, err := rasterize(pdf, dpi)
+	if err != nil {
+		return nil, fmt.Errorf("rasterizing: %v", err)
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("rasterizing: no pages")
+	}
+
+	if format == "tiff" {
+		var buf bytes.Buffer
+		if err := encodeTIFF(&buf, pages, dpi); err != nil {
+			return nil, fmt.Errorf("encoding TIFF: %v", err)
+		}
+		return [][]byte{buf.Bytes()}, nil
+	}
+	images := make([][]byte, len(pages))
+	for i, page := range pages {
+		var buf bytes.Buffer
+		if format == "png" {
+			err = png.Encode(&buf, page)
+		} else {
+			err = jpeg.Encode(&buf, page, &jpeg.Options{Quality: renderJPEGQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		images[i] = buf.Bytes()
+	}
+	return images, nil
+}
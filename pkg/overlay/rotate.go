@@ -0,0 +1,85 @@
+package overlay
+
+import (
+	"math"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// rotatedCenter returns where the center of a w x h part of an overlay ends
+// up once the overlay is rotated by deg degrees counterclockwise around its
+// anchor point. The part sits dx right of and dy above the overlay's offset
+// (x, y) from anchor on a pageW x pageH page. pdfcpu rotates each watermark around its
+// own center, so placing every part's center here keeps the parts of a
+// rotated overlay aligned.
+func rotatedCenter(anchor string, pageW, pageH, w, h, x, y, dx, dy, deg float64) (float64, float64) {
+	a, err := types.ParsePositionAnchor(anchor)
+	if err != nil {
+		a = types.BottomLeft
+	}
+	page := types.RectForDim(pageW, pageH)
+
+	pivot := model.LowerLeftCorner(page, 0, 0, a)
+	px, py := pivot.X+x, pivot.Y+y
+
+	ll := model.LowerLeftCorner(page, w, h, a)
+	cx, cy := ll.X+x+dx+w/2-px, ll.Y+y+dy+h/2-py
+
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+	return px + cx*cos - cy*sin, py + cx*sin + cy*cos
+}
+
+// pageView is the visible area of a page as overlays are placed on it: its
+// crop box turned by the page's /Rotate, with the origin at the lower-left
+// corner the reader sees. pdfcpu places watermarks on rotated pages the same
+// way.
+type pageView struct {
+	crop *types.Rectangle // in user space
+	rot  int              // clockwise, as in /Rotate: 0, 90, 180 or 270
+}
+
+// viewOf returns the view of a page with boundaries b.
+func viewOf(b model.PageBoundaries) pageView {
+	return pageView{crop: b.CropBox(), rot: ((b.Rot % 360) + 360) % 360}
+}
+
+// size returns the width and height of v as the reader sees them.
+func (v pageView) size() (float64, float64) {
+	if v.rot == 90 || v.rot == 270 {
+		return v.crop.Height(), v.crop.Width()
+	}
+	return v.crop.Width(), v.crop.Height()
+}
+
+// toUser converts r, relative to v's lower-left corner, to user space, where
+// the page's content is drawn.
+func (v pageView) toUser(r *types.Rectangle) *types.Rectangle {
+	w, h := v.crop.Width(), v.crop.Height()
+	llx, lly, urx, ury := r.LL.X, r.LL.Y, r.UR.X, r.UR.Y
+	switch v.rot {
+	case 90:
+		llx, lly, urx, ury = w-r.UR.Y, r.LL.X, w-r.LL.Y, r.UR.X
+	case 180:
+		llx, lly, urx, ury = w-r.UR.X, h-r.UR.Y, w-r.LL.X, h-r.LL.Y
+	case 270:
+		llx, lly, urx, ury = r.LL.Y, h-r.UR.X, r.UR.Y, h-r.LL.X
+	}
+	return types.NewRectangle(v.crop.LL.X+llx, v.crop.LL.Y+lly, v.crop.LL.X+urx, v.crop.LL.Y+ury)
+}
+
+// fromUser converts r, in user space, to coordinates relative to v's
+// lower-left corner. It undoes toUser.
+func (v pageView) fromUser(r *types.Rectangle) *types.Rectangle {
+	w, h := v.crop.Width(), v.crop.Height()
+	llx, lly, urx, ury := r.LL.X-v.crop.LL.X, r.LL.Y-v.crop.LL.Y, r.UR.X-v.crop.LL.X, r.UR.Y-v.crop.LL.Y
+	switch v.rot {
+	case 90:
+		return types.NewRectangle(lly, w-urx, ury, w-llx)
+	case 180:
+		return types.NewRectangle(w-urx, h-ury, w-llx, h-lly)
+	case 270:
+		return types.NewRectangle(h-ury, llx, h-lly, urx)
+	}
+	return types.NewRectangle(llx, lly, urx, ury)
+}
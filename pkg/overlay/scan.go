@@ -0,0 +1,226 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ScanOptions says how SimulateScan degrades a PDF. Each amount is the most
+// a page gets; the actual amount is drawn per page.
+type ScanOptions struct {
+	DPI         int     `json:"dpi"`         // resolution pages are rasterized at
+	Seed        uint64  `json:"seed"`        // seeds the degradation
+	Noise       float64 `json:"noise"`       // standard deviation of pixel noise, 0 to 1
+	Rotation    float64 `json:"rotation"`    // degrees, either way
+	Skew        float64 `json:"skew"`        // horizontal shear, as a fraction of the height
+	Contrast    float64 `json:"contrast"`    // contrast change, as a fraction, either way
+	Shadow      float64 `json:"shadow"`      // darkening at the shadowed edge, 0 to 1
+	JPEGQuality int     `json:"jpegQuality"` // 1 to 100
+}
+
+// ScanPresets are ready-made ScanOptions by name.
+var ScanPresets = map[string]ScanOptions{
+	"flatbed": {DPI: 200, Noise: 0.03, Rotation: 0.8, Skew: 0.003, Contrast: 0.1, Shadow: 0.08, JPEGQuality: 75},
+	"fax":     {DPI: 100, Noise: 0.08, Rotation: 1.5, Skew: 0.01, Contrast: 0.3, Shadow: 0, JPEGQuality: 40},
+	"phone":   {DPI: 150, Noise: 0.05, Rotation: 3, Skew: 0.02, Contrast: 0.2, Shadow: 0.35, JPEGQuality: 60},
+}
+
+// validate reports the first out-of-range option of o.
+func (o ScanOptions) validate() error {
+	switch {
+	case o.DPI < 36 || o.DPI > 600:
+		return fmt.Errorf("scan dpi %d out of range (36 to 600)", o.DPI)
+	case o.JPEGQuality < 1 || o.JPEGQuality > 100:
+		return fmt.Errorf("scan jpegQuality %d out of range (1 to 100)", o.JPEGQuality)
+	case o.Noise < 0 || o.Noise > 1:
+		return fmt.Errorf("scan noise %g out of range (0 to 1)", o.Noise)
+	case o.Shadow < 0 || o.Shadow > 1:
+		return fmt.Errorf("scan shadow %g out of range (0 to 1)", o.Shadow)
+	case o.Contrast < 0 || o.Contrast > 1:
+		return fmt.Errorf("scan contrast %g out of range (0 to 1)", o.Contrast)
+	case math.Abs(o.Rotation) > 45 || math.Abs(o.Skew) > 1:
+		return fmt.Errorf("scan rotation must be within 45 degrees and skew within 1")
+	}
+	return nil
+}
+
+// A Rasterizer renders every page of pdf, as the reader sees it, to an image
+// at dpi dots per inch.
+type Rasterizer func(pdf []byte, dpi int) ([]image.Image, error)
+
+// Pdftoppm returns a Rasterizer that runs poppler's pdftoppm, found at cmd
+// or on the PATH.
+func Pdftoppm(cmd string) Rasterizer {
+	return func(pdf []byte, dpi int) ([]image.Image, error) {
+		dir, err := os.MkdirTemp("", "scan")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		in := filepath.Join(dir, "in.pdf")
+		if err := os.WriteFile(in, pdf, 0600); err != nil {
+			return nil, err
+		}
+		out, err := exec.Command(cmd, "-r", fmt.Sprint(dpi), "-png", in, filepath.Join(dir, "page")).CombinedOutput()
+		if out = bytes.TrimSpace(out); err != nil && len(out) > 0 {
+			return nil, fmt.Errorf("%s: %v: %s", cmd, err, out)
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %v", cmd, err)
+		}
+
+		// pdftoppm pads page numbers to the same width, so the names sort
+		// in page order.
+		paths, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+		images := make([]image.Image, len(paths))
+		for i, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			images[i], err = png.Decode(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("page %d: %v", i+1, err)
+			}
+		}
+		return images, nil
+	}
+}
+
+// SimulateScan makes pdf look scanned: it rasterizes every page with
+// rasterize, degrades the page images according to opts and returns a PDF of
+// the JPEG-compressed images, with the pages at their original size. The
+// degradation depends only on opts and pdf, so a given PDF always comes out
+// the same while different PDFs degrade differently. The result has no text
+// layer left.
+func SimulateScan(pdf []byte, opts ScanOptions, rasterize Rasterizer) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := rasterize(pdf, opts.DPI)
+	if err != nil {
+		return nil, fmt.Errorf("rasterizing: %v", err)
+	}
+	if len(pages) != len(boundaries) {
+		return nil, fmt.Errorf("rasterizing: got %d page images for %d pages", len(pages), len(boundaries))
+	}
+
+	sum := fnv.New64a()
+	sum.Write(pdf)
+	rng := rand.New(rand.NewPCG(opts.Seed, sum.Sum64()))
+
+	var out []byte
+	for i, page := range pages {
+		var img bytes.Buffer
+		if err := jpeg.Encode(&img, degrade(page, opts, rng), &jpeg.Options{Quality: opts.JPEGQuality}); err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		w, h := viewOf(boundaries[i]).size()
+		imp := pdfcpu.DefaultImportConfig()
+		imp.PageDim = &types.Dim{Width: w, Height: h}
+		imp.Pos, imp.Scale = types.Center, 1
+
+		// Each page is appended to the PDF built so far, so every page
+		// keeps its own size.
+		var rs io.ReadSeeker
+		if out != nil {
+			rs = bytes.NewReader(out)
+		}
+		var buf bytes.Buffer
+		if err := api.ImportImages(rs, &buf, []io.Reader{&img}, imp, nil); err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		out = buf.Bytes()
+	}
+	return out, nil
+}
+
+// degrade returns a grayscale copy of page turned, sheared, recontrasted,
+// shadowed and speckled by amounts drawn from rng up to the maximums of opts.
+func degrade(page image.Image, opts ScanOptions, rng *rand.Rand) *image.Gray {
+	// spread draws a value between -max and max.
+	spread := func(max float64) float64 { return (2*rng.Float64() - 1) * max }
+
+	b := page.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Rect, page, b.Min, draw.Src)
+
+	sin, cos := math.Sincos(spread(opts.Rotation) * math.Pi / 180)
+	shear := spread(opts.Skew)
+	contrast := 1 + spread(opts.Contrast)
+	shadow := opts.Shadow * rng.Float64()
+	// The shadow falls from a random direction, darkest at that edge.
+	sx, sy := math.Sincos(rng.Float64() * 2 * math.Pi)
+
+	cx, cy := float64(w)/2, float64(h)/2
+	dst := image.NewGray(src.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Map each output pixel back onto the page: undo the rotation
+			// around the center, then the shear.
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			px := dx*cos + dy*sin
+			py := -dx*sin + dy*cos
+			px -= shear * py
+			v := sample(src, px+cx, py+cy)
+
+			v = (v-128)*contrast + 128
+			// t is 0 at the far side of the page and 1 at the shadowed edge.
+			t := (float64(x)-cx)/float64(w)*sx + (float64(y)-cy)/float64(h)*sy + 0.5
+			v *= 1 - shadow*math.Pow(math.Min(math.Max(t, 0), 1), 2)
+			v += rng.NormFloat64() * opts.Noise * 255
+			dst.Pix[y*dst.Stride+x] = uint8(math.Min(math.Max(math.Round(v), 0), 255))
+		}
+	}
+	return dst
+}
+
+// sample returns the bilinearly interpolated gray level of img at (x, y),
+// counting pixels outside img as white paper.
+func sample(img *image.Gray, x, y float64) float64 {
+	x, y = x-0.5, y-0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	at := func(x, y int) float64 {
+		if !(image.Point{x, y}.In(img.Rect)) {
+			return 255
+		}
+		return float64(img.Pix[y*img.Stride+x])
+	}
+	ix, iy := int(x0), int(y0)
+	top := at(ix, iy)*(1-fx) + at(ix+1, iy)*fx
+	bottom := at(ix, iy+1)*(1-fx) + at(ix+1, iy+1)*fx
+	return top*(1-fy) + bottom*fy
+}
@@ -0,0 +1,386 @@
+package overlay
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// signatureSize is the room left in a signature dictionary for the CMS
+// signature, in bytes: enough for a 4096-bit RSA key and a short chain.
+const signatureSize = 8192
+
+// byteRangePlaceholder stands in for the ByteRange of a signature until
+// the offsets it covers are known; the real one is padded to its length.
+var byteRangePlaceholder = types.Array{types.Integer(0), types.Integer(9999999999), types.Integer(9999999999), types.Integer(9999999999)}
+
+// Signer is a certificate and its private key that Sign signs PDFs with,
+// such as a self-signed test certificate.
+type Signer struct {
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate // intermediates, embedded in the signature too
+	Key         crypto.Signer       // an RSA or ECDSA key
+	Reason      string              // why the document is signed, if given
+	// Time, if not zero, is the signing time, so the same input can be
+	// signed the same way again; else it is the time of signing. With an
+	// RSA key the signature then comes out the same too.
+	Time time.Time
+}
+
+// LoadSigner returns the Signer of the first certificate of certPEM, with
+// the other certificates as its chain, and the private key of keyPEM, in
+// PKCS #1, PKCS #8 or SEC 1 form. Both may be the same PEM file.
+func LoadSigner(certPEM, keyPEM []byte) (*Signer, error) {
+	s := &Signer{}
+	for rest := certPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificate: %v", err)
+		}
+		if s.Certificate == nil {
+			s.Certificate = cert
+		} else {
+			s.Chain = append(s.Chain, cert)
+		}
+	}
+	if s.Certificate == nil {
+		return nil, errors.New("no PEM certificate found")
+	}
+	for rest := keyPEM; s.Key == nil; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, errors.New("no PEM private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		key, err := parsePrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("private key: %v", err)
+		}
+		s.Key = key
+	}
+	pub, ok := s.Key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(s.Certificate.PublicKey) {
+		return nil, errors.New("the private key is not that of the certificate")
+	}
+	return s, nil
+}
+
+// parsePrivateKey parses an RSA or ECDSA private key in any of the DER forms
+// PEM files hold them in.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.New("not a PKCS #1, PKCS #8 or SEC 1 key")
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T: want RSA or ECDSA", key)
+}
+
+// Sign returns pdf signed by s with an invisible signature field on its
+// first page: a detached CMS signature (adbe.pkcs7.detached) over the whole
+// file but the signature itself, which any PDF reader can check. The
+// document information and file identifier are kept as they were, so Sign
+// can come after SetMetadata.
+//
+// Like SetMetadata, Sign writes the PDF without object streams, then
+// patches the byte range and signature into the signature dictionary of
+// the written file.
+func Sign(pdf []byte, s Signer) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, readError(err)
+	}
+	if ctx.Encrypt != nil {
+		return nil, errors.New("cannot sign an encrypted PDF")
+	}
+	when := s.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+	if err := addSignatureField(ctx, s, when); err != nil {
+		return nil, err
+	}
+
+	// pdfcpu stamps its producer, the time and a new file identifier into
+	// what it writes; the old ones are put back.
+	var info types.Dict
+	if ctx.Info != nil {
+		d, err := ctx.XRefTable.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return nil, err
+		}
+		info = d.Clone().(types.Dict)
+	}
+	var id types.Array
+	if ctx.ID != nil {
+		id = ctx.ID.Clone().(types.Array)
+	}
+	var buf bytes.Buffer
+	if err := api.Write(ctx, &buf, conf); err != nil {
+		return nil, fmt.Errorf("writing PDF: %v", err)
+	}
+	written, err := splitXRef(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		written.replace(*ctx.Info, info)
+	}
+	if id != nil {
+		written.trailer = idPattern.ReplaceAll(written.trailer, []byte("/ID"+id.PDFString()))
+	}
+	out := written.bytes()
+
+	// The signature covers everything but its own hex string.
+	placeholder := []byte(byteRangePlaceholder.PDFString())
+	contents := []byte(types.HexLiteral(strings.Repeat("00", signatureSize)).PDFString())
+	at, from := bytes.Index(out, placeholder), bytes.Index(out, contents)
+	if at < 0 || from < 0 {
+		return nil, errors.New("signature dictionary not found in the written PDF")
+	}
+	to := from + len(contents)
+	byteRange := fmt.Sprintf("[0 %d %d %d", from, to, len(out)-to)
+	copy(out[at:], byteRange+strings.Repeat(" ", len(placeholder)-len(byteRange)-1)+"]")
+
+	h := sha256.New()
+	h.Write(out[:from])
+	h.Write(out[to:])
+	sig, err := signCMS(s, h.Sum(nil), when)
+	if err != nil {
+		return nil, fmt.Errorf("CMS signature: %v", err)
+	}
+	if len(sig) > signatureSize {
+		return nil, fmt.Errorf("the signature takes %d bytes, more than the %d left for it", len(sig), signatureSize)
+	}
+	hex.Encode(out[from+1:], sig)
+	return out, nil
+}
+
+// addSignatureField adds a signature field, with a signature dictionary
+// whose byte range and contents are placeholders, to the AcroForm of ctx,
+// and its widget to the first page.
+func addSignatureField(ctx *model.Context, s Signer, when time.Time) error {
+	root, err := ctx.XRefTable.Catalog()
+	if err != nil {
+		return err
+	}
+	page, pageRef, _, err := ctx.XRefTable.PageDict(1, false)
+	if err != nil {
+		return err
+	}
+	sig := types.Dict{
+		"Type":      types.Name("Sig"),
+		"Filter":    types.Name("Adobe.PPKLite"),
+		"SubFilter": types.Name("adbe.pkcs7.detached"),
+		"ByteRange": byteRangePlaceholder,
+		"Contents":  types.HexLiteral(strings.Repeat("00", signatureSize)),
+		"M":         types.StringLiteral(types.DateString(when)),
+	}
+	for key, value := range map[string]string{"Name": s.Certificate.Subject.CommonName, "Reason": s.Reason} {
+		if value != "" {
+			str, err := infoString(value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			sig[key] = str
+		}
+	}
+	sigRef, err := ctx.XRefTable.IndRefForNewObject(sig)
+	if err != nil {
+		return err
+	}
+
+	// The widget is invisible, printed and locked.
+	widget := types.Dict{
+		"Type":    types.Name("Annot"),
+		"Subtype": types.Name("Widget"),
+		"FT":      types.Name("Sig"),
+		"T":       types.StringLiteral(fmt.Sprintf("Signature%d", sigRef.ObjectNumber.Value())),
+		"V":       *sigRef,
+		"Rect":    types.NewNumberArray(0, 0, 0, 0),
+		"F":       types.Integer(132),
+		"P":       *pageRef,
+	}
+	widgetRef, err := ctx.XRefTable.IndRefForNewObject(widget)
+	if err != nil {
+		return err
+	}
+	annots, err := ctx.XRefTable.DereferenceArray(page["Annots"])
+	if err != nil {
+		return fmt.Errorf("page 1 annotations: %v", err)
+	}
+	page["Annots"] = append(annots, *widgetRef)
+
+	form, err := ctx.XRefTable.DereferenceDict(root["AcroForm"])
+	if err != nil {
+		return fmt.Errorf("AcroForm: %v", err)
+	}
+	if form == nil {
+		form = types.Dict{}
+		root["AcroForm"] = form
+	}
+	fields, err := ctx.XRefTable.DereferenceArray(form["Fields"])
+	if err != nil {
+		return fmt.Errorf("AcroForm fields: %v", err)
+	}
+	form["Fields"] = append(fields, *widgetRef)
+	// The document has signatures, and is to be changed only by appending.
+	form["SigFlags"] = types.Integer(3)
+	return nil
+}
+
+// Object identifiers of the CMS signature.
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// The ASN.1 structures of a CMS signature (RFC 5652).
+type (
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue // [0] EXPLICIT
+	}
+	signedData struct {
+		Version          int
+		DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+		EncapContentInfo struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue // [0] IMPLICIT SET OF Certificate
+		SignerInfos      []signerInfo  `asn1:"set"`
+	}
+	signerInfo struct {
+		Version            int
+		SID                issuerAndSerial
+		DigestAlgorithm    algorithmIdentifier
+		SignedAttrs        asn1.RawValue // [0] IMPLICIT SET OF Attribute
+		SignatureAlgorithm algorithmIdentifier
+		Signature          []byte
+	}
+	issuerAndSerial struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}
+	algorithmIdentifier struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue `asn1:"optional"`
+	}
+	attribute struct {
+		Type   asn1.ObjectIdentifier
+		Values asn1.RawValue // SET
+	}
+)
+
+// signCMS returns the DER of a detached CMS SignedData of s over the
+// content of SHA-256 digest, signed at when.
+func signCMS(s Signer, digest []byte, when time.Time) ([]byte, error) {
+	var attrs [][]byte
+	for _, a := range []struct {
+		oid   asn1.ObjectIdentifier
+		value any
+	}{{oidContentType, oidData}, {oidSigningTime, when.UTC()}, {oidMessageDigest, digest}} {
+		value, err := asn1.Marshal(a.value)
+		if err != nil {
+			return nil, err
+		}
+		der, err := asn1.Marshal(attribute{a.oid, asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: value}})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, der)
+	}
+	// DER sorts the members of a SET OF by their encoding.
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	signed := bytes.Join(attrs, nil)
+
+	// The signature is over the attributes as a SET, not as the [0] they
+	// are embedded as.
+	set, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: signed})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(set)
+	var algorithm algorithmIdentifier
+	switch s.Key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = algorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}
+	case *ecdsa.PrivateKey:
+		algorithm = algorithmIdentifier{Algorithm: oidECDSASHA256}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T: want RSA or ECDSA", s.Key)
+	}
+	signature, err := s.Key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []byte
+	for _, c := range append([]*x509.Certificate{s.Certificate}, s.Chain...) {
+		certs = append(certs, c.Raw...)
+	}
+	sha256ID := algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithmIdentifier{sha256ID},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                issuerAndSerial{asn1.RawValue{FullBytes: s.Certificate.RawIssuer}, s.Certificate.SerialNumber},
+			DigestAlgorithm:    sha256ID,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+			SignatureAlgorithm: algorithm,
+			Signature:          signature,
+		}},
+	}
+	sd.EncapContentInfo.ContentType = oidData
+	body, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: body}})
+}
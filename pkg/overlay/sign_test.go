@@ -0,0 +1,175 @@
+package overlay
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// testSigner returns a Signer with a new self-signed certificate for key.
+func testSigner(t *testing.T, key crypto.Signer) Signer {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Test Payroll"},
+		NotBefore:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("creating the certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing the certificate: %v", err)
+	}
+	return Signer{Certificate: cert, Key: key, Reason: "Testing", Time: time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)}
+}
+
+var byteRangePattern = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+
+// verifySignature checks that signed has one signature, whose byte range
+// covers the whole file but its Contents, and whose CMS signature by
+// signer verifies over the bytes of that range.
+func verifySignature(signed []byte, signer Signer) error {
+	ranges := byteRangePattern.FindAllSubmatch(signed, -1)
+	if len(ranges) != 1 {
+		return fmt.Errorf("%d byte ranges, want 1", len(ranges))
+	}
+	var r [4]int
+	for i := range r {
+		r[i], _ = strconv.Atoi(string(ranges[0][i+1]))
+	}
+	from, to := r[1], r[2]
+	if r[0] != 0 || from <= 0 || to <= from || to+r[3] != len(signed) {
+		return fmt.Errorf("byte range %v doesn't cover the %d bytes of the file from start to end", r, len(signed))
+	}
+	// The gap is exactly the hex string of Contents.
+	if !bytes.HasSuffix(bytes.TrimRight(signed[:from], " \r\n"), []byte("/Contents")) {
+		return fmt.Errorf("the byte range gap at %d doesn't follow /Contents", from)
+	}
+	if signed[from] != '<' || signed[to-1] != '>' {
+		return fmt.Errorf("the byte range gap is %q...%q, not a hex string", signed[from], signed[to-1])
+	}
+	sig := make([]byte, (to-from-2)/2)
+	if _, err := hex.Decode(sig, signed[from+1:to-1]); err != nil {
+		return fmt.Errorf("Contents: %v", err)
+	}
+	h := sha256.New()
+	h.Write(signed[:from])
+	h.Write(signed[to:])
+	digest := h.Sum(nil)
+
+	// The Contents are a CMS SignedData, padded with zeros.
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(sig, &ci); err != nil {
+		return fmt.Errorf("parsing the CMS signature: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return fmt.Errorf("content type %v, want SignedData", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return fmt.Errorf("parsing the SignedData: %v", err)
+	}
+	if !bytes.Equal(sd.Certificates.Bytes, signer.Certificate.Raw) {
+		return errors.New("the signature doesn't hold the signer's certificate")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return fmt.Errorf("%d signer infos, want 1", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	if si.SID.Serial.Cmp(signer.Certificate.SerialNumber) != 0 || !bytes.Equal(si.SID.Issuer.FullBytes, signer.Certificate.RawIssuer) {
+		return errors.New("the signer info doesn't name the signer's certificate")
+	}
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(si.SignedAttrs.FullBytes, &attrs, "set,tag:0"); err != nil {
+		return fmt.Errorf("parsing the signed attributes: %v", err)
+	}
+	var messageDigest []byte
+	for _, a := range attrs {
+		if a.Type.Equal(oidMessageDigest) {
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &messageDigest); err != nil {
+				return fmt.Errorf("parsing the message digest: %v", err)
+			}
+		}
+	}
+	if !bytes.Equal(messageDigest, digest) {
+		return fmt.Errorf("message digest %x, but the byte range hashes to %x", messageDigest, digest)
+	}
+
+	// The signature is over the DER of the attributes as a SET.
+	set := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	algorithm := x509.SHA256WithRSA
+	if _, ok := signer.Key.(*ecdsa.PrivateKey); ok {
+		algorithm = x509.ECDSAWithSHA256
+	}
+	if err := signer.Certificate.CheckSignature(algorithm, set, si.Signature); err != nil {
+		return fmt.Errorf("the signature doesn't verify: %v", err)
+	}
+	return nil
+}
+
+// TestSign checks that Sign's signature covers the whole file but itself
+// and verifies, with either kind of key, and that changing the file breaks
+// it.
+func TestSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stamped bytes.Buffer
+	err = ApplyOverlays(bytes.NewReader(BlankPDF(612, 792)), &stamped, []OverlayRectText{{Text: "NET PAY 1,234.56", X: 72, Y: 700}})
+	if err != nil {
+		t.Fatalf("ApplyOverlays: %v", err)
+	}
+
+	for name, key := range map[string]crypto.Signer{"RSA": rsaKey, "ECDSA": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			signer := testSigner(t, key)
+			signed, err := Sign(stamped.Bytes(), signer)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := verifySignature(signed, signer); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := api.ReadValidateAndOptimize(bytes.NewReader(signed), model.NewDefaultConfiguration()); err != nil {
+				t.Errorf("the signed PDF doesn't read back: %v", err)
+			}
+
+			// A byte changed in either part of the range breaks it.
+			for _, at := range []int{10, len(signed) - 2} {
+				tampered := bytes.Clone(signed)
+				tampered[at] ^= 1
+				if err := verifySignature(tampered, signer); err == nil || !strings.Contains(err.Error(), "message digest") {
+					t.Errorf("after changing byte %d, verifySignature = %v, want a digest mismatch", at, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// largestAssetsReported is how many streams reportLargestAssets logs.
+const largestAssetsReported = 5
+
+// EnforceMaxSize returns pdf unchanged if it fits within maxSize bytes.
+// Otherwise it runs Optimize and rechecks, failing if the optimized
+// PDF is still too large. A maxSize of 0 disables the check.
+func EnforceMaxSize(pdf []byte, maxSize int64, debug bool) ([]byte, error) {
+	if maxSize <= 0 || int64(len(pdf)) <= maxSize {
+		return pdf, nil
+	}
+
+	slog.Info("Output is over the size limit; optimizing", "bytes", len(pdf), "limit", maxSize)
+	optimized, err := Optimize(pdf)
+	if err != nil {
+		return nil, fmt.Errorf("optimize failed: %v", err)
+	}
+	if int64(len(optimized)) <= maxSize {
+		return optimized, nil
+	}
+
+	if debug {
+		reportLargestAssets(optimized)
+	}
+	return nil, fmt.Errorf("%w: %d bytes after optimizing, over the %d byte limit", ErrTooLarge, len(optimized), maxSize)
+}
+
+// CheckMaxSize returns an ErrTooLarge error if pdf is over maxSize bytes.
+// It is the check of an output that can't be optimized any more, such as
+// one signed or encrypted after EnforceMaxSize. A maxSize of 0 disables it.
+func CheckMaxSize(pdf []byte, maxSize int64) error {
+	if maxSize > 0 && int64(len(pdf)) > maxSize {
+		return fmt.Errorf("%w: %d bytes as written, over the %d byte limit", ErrTooLarge, len(pdf), maxSize)
+	}
+	return nil
+}
+
+// reportLargestAssets logs the biggest streams in pdf (images, fonts, content)
+// so it is clear what is pushing the output over its size limit.
+func reportLargestAssets(pdf []byte) {
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), nil)
+	if err != nil {
+		slog.Debug("Could not read output to report assets", "err", err)
+		return
+	}
+
+	type asset struct {
+		objNr int
+		kind  string
+		size  int
+	}
+	var assets []asset
+	for objNr, entry := range ctx.XRefTable.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok {
+			continue
+		}
+		kind := "stream"
+		if st := sd.Subtype(); st != nil {
+			kind = *st
+		} else if t := sd.Type(); t != nil {
+			kind = *t
+		} else if sd.IsPageContent {
+			kind = "content"
+		}
+		assets = append(assets, asset{objNr, kind, len(sd.Raw)})
+	}
+
+	sort.Slice(assets, func(i, j int) bool { return assets[i].size > assets[j].size })
+	for i, a := range assets {
+		if i == largestAssetsReported {
+			break
+		}
+		slog.Debug("Large asset", "object", a.objNr, "kind", a.kind, "bytes", a.size)
+	}
+}
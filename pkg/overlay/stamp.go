@@ -0,0 +1,56 @@
+package overlay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// DefaultHashStampStyle places the hash stamp centred in the page footer.
+// Its Text is a format string receiving the short hex digest; see
+// CheckHashStampStyle.
+var DefaultHashStampStyle = OverlayRectText{
+	Text:   "sha256:%s",
+	Y:      10,
+	Scale:  0.6,
+	Anchor: "bc",
+}
+
+// CheckHashStampStyle checks that the Text of a hash stamp style is a format
+// string with exactly one %s, for the digest, and no other verbs; %% stands
+// for a literal percent sign.
+func CheckHashStampStyle(style OverlayRectText) error {
+	verbs := 0
+	for i := 0; i < len(style.Text); i++ {
+		if style.Text[i] != '%' {
+			continue
+		}
+		i++
+		switch {
+		case i == len(style.Text):
+			return errors.New("hash stamp text ends in a lone %; write %% for a percent sign")
+		case style.Text[i] == 's':
+			verbs++
+		case style.Text[i] != '%':
+			return fmt.Errorf("hash stamp text may only use %%s, for the digest, and %%%%, got %%%c", style.Text[i])
+		}
+	}
+	if verbs != 1 {
+		return fmt.Errorf("hash stamp text needs exactly one %%s for the digest, got %d", verbs)
+	}
+	return nil
+}
+
+// HashStampOverlay returns an overlay that stamps a short SHA-256 of sources,
+// positioned and formatted according to style, which CheckHashStampStyle
+// accepts.
+func HashStampOverlay(style OverlayRectText, sources ...[]byte) OverlayRectText {
+	h := sha256.New()
+	for _, src := range sources {
+		h.Write(src)
+	}
+	ov := style
+	ov.Text = fmt.Sprintf(style.Text, hex.EncodeToString(h.Sum(nil))[:12])
+	return ov
+}
@@ -0,0 +1,47 @@
+package overlay
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheckHashStampStyle checks that only stamp texts with one %s and no
+// other verbs are accepted, and that the accepted ones format the digest.
+func TestCheckHashStampStyle(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string // "" if the text is accepted
+	}{
+		{text: "sha256:%s"},
+		{text: "%s"},
+		{text: "100%% checked: %s"},
+		{text: "no digest", wantErr: "exactly one %s"},
+		{text: "%s and %s", wantErr: "exactly one %s"},
+		{text: "%d", wantErr: "got %d"},
+		{text: "%s %v", wantErr: "got %v"},
+		{text: "%x%s", wantErr: "got %x"},
+		{text: "%-10s", wantErr: "got %-"},
+		{text: "%!s(MISSING)", wantErr: "got %!"},
+		{text: "%s 50%", wantErr: "lone %"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			style := DefaultHashStampStyle
+			style.Text = tt.text
+			err := CheckHashStampStyle(style)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CheckHashStampStyle = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckHashStampStyle: %v", err)
+			}
+			got := HashStampOverlay(style, []byte("source")).Text
+			if strings.Contains(got, "%!") || strings.Count(got, "%") != strings.Count(tt.text, "%%") {
+				t.Errorf("stamp text = %q, badly formatted", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package overlay
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ExpandText returns a copy of overlays with the Text and Content of each
+// one run as a text/template against data, so placeholders such as
+// {{.NetPay}} take their values from it. funcs are the extra functions the
+// templates can call. Text without "{{" is kept as it is, and a field data
+// lacks is an error.
+func ExpandText(overlays []OverlayRectText, data any, funcs template.FuncMap) ([]OverlayRectText, error) {
+	out := make([]OverlayRectText, len(overlays))
+	copy(out, overlays)
+	for i := range out {
+		for _, f := range []struct {
+			name string
+			text *string
+		}{{"text", &out[i].Text}, {"content", &out[i].Content}} {
+			if !strings.Contains(*f.text, "{{") {
+				continue
+			}
+			t, err := template.New(fmt.Sprintf("overlay %d", i)).Funcs(funcs).Option("missingkey=error").Parse(*f.text)
+			if err != nil {
+				return nil, &OverlayError{i, fmt.Errorf("%s template: %v", f.name, err)}
+			}
+			var b strings.Builder
+			if err := t.Execute(&b, data); err != nil {
+				return nil, &OverlayError{i, fmt.Errorf("%s template: %v", f.name, err)}
+			}
+			*f.text = b.String()
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,257 @@
+package overlay
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// defaultFontName is the font pdfcpu uses for text watermarks when none is
+// given; text is measured with it when an overlay has no Font.
+const defaultFontName = "Helvetica"
+
+// watermarkFontSize is pdfcpu's default text watermark font size, which its
+// relative scaling starts from.
+const watermarkFontSize = 24
+
+// multiLineFontSize is the font size in points, at Scale 1, of text drawn
+// over several lines. Single-line text keeps pdfcpu's relative scaling.
+const multiLineFontSize = 12
+
+// scalePerTextScale is how many units of Scale make one unit of text scale:
+// without a TextScale, text is drawn at text scale Scale/4.
+const scalePerTextScale = 4
+
+// minAutoFitFontSize is the smallest font size AutoFit will shrink text to.
+const minAutoFitFontSize = 4
+
+// metricsFont returns the font name to measure text drawn with fontName.
+func metricsFont(fontName string) string {
+	if fontName == "" {
+		return defaultFontName
+	}
+	return fontName
+}
+
+// relativeFontSize returns the font size pdfcpu's relative scaling gives text
+// on a page pageWidth points wide: the size at which it spans scale times the
+// page width.
+func relativeFontSize(text, fontName string, scale, pageWidth float64) int {
+	w := font.TextWidth(text, fontName, watermarkFontSize)
+	if w == 0 {
+		return watermarkFontSize
+	}
+	return max(int(pageWidth*min(scale, 1)*watermarkFontSize/w), 1)
+}
+
+// textScaleFor returns the text scale of ov: its TextScale, or Scale/4 when
+// that is 0.
+func textScaleFor(ov OverlayRectText) float64 {
+	if ov.TextScale > 0 {
+		return ov.TextScale
+	}
+	return ov.Scale / scalePerTextScale
+}
+
+// multiLineFontSizeFor returns the font size for the multi-line text of ov.
+func multiLineFontSizeFor(ov OverlayRectText) int {
+	if ov.FontSize > 0 {
+		return ov.FontSize
+	}
+	return max(int(math.Round(multiLineFontSize*scalePerTextScale*textScaleFor(ov))), 1)
+}
+
+// wrapLines splits text into lines at its newlines and, when width > 0,
+// breaks each line between words so it is at most width points wide at
+// fontSize. A single word wider than width gets a line of its own.
+func wrapLines(text, fontName string, fontSize int, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		if width <= 0 {
+			lines = append(lines, para)
+			continue
+		}
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if font.TextWidth(line+" "+w, fontName, fontSize) > width {
+				lines = append(lines, line)
+				line = w
+				continue
+			}
+			line += " " + w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// lineSpacingFor returns the line spacing of ov, where 0 (unset) means 1.
+func lineSpacingFor(ov OverlayRectText) float64 {
+	if ov.LineSpacing > 0 {
+		return ov.LineSpacing
+	}
+	return 1
+}
+
+// autoFitFontSize returns the largest font size at which text, wrapped to
+// wrapWidth when it is positive and with its lines spacing line heights
+// apart, fits in a width x height box, together with its lines at that size.
+func autoFitFontSize(text, fontName string, width, height, wrapWidth, spacing float64) (int, []string, error) {
+	for size := font.SizeForLineHeight(fontName, height); size >= minAutoFitFontSize; size-- {
+		lines := wrapLines(text, fontName, size, wrapWidth)
+		lh := font.LineHeight(fontName, size)
+		if lh+float64(len(lines)-1)*lh*spacing > height {
+			continue
+		}
+		fits := true
+		for _, line := range lines {
+			if font.TextWidth(line, fontName, size) > width {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return size, lines, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("text does not fit in %gx%g points even at %d points", width, height, minAutoFitFontSize)
+}
+
+// ellipsis ends text Fit cuts short.
+const ellipsis = "…"
+
+// fitText returns the largest font size from size down to minSize at which
+// text, wrapped to wrapWidth when it is positive and with its lines spacing
+// line heights apart, fits in a width x height box, or in width alone when
+// height is 0, together with its lines at that size. If it doesn't fit even
+// at minSize, lines too wide are cut short with an ellipsis, as is the last
+// line that fits the height.
+func fitText(text, fontName string, size, minSize int, width, height, wrapWidth, spacing float64) (int, []string) {
+	size = max(size, minSize)
+	for ; size >= minSize; size-- {
+		lines := wrapLines(text, fontName, size, wrapWidth)
+		if fitLines(lines, fontName, size, height, spacing) == len(lines) && widest(lines, fontName, size) <= width {
+			return size, lines
+		}
+	}
+	size = minSize
+	lines := wrapLines(text, fontName, size, wrapWidth)
+	if n := max(fitLines(lines, fontName, size, height, spacing), 1); n < len(lines) {
+		lines = append(lines[:n-1:n-1], lines[n-1]+ellipsis)
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, fontName, size, width)
+	}
+	return size, lines
+}
+
+// fitLines returns how many of lines fit in height at size, spaced spacing
+// line heights apart: all of them when height is 0.
+func fitLines(lines []string, fontName string, size int, height, spacing float64) int {
+	if height <= 0 {
+		return len(lines)
+	}
+	lh := font.LineHeight(fontName, size)
+	if lh > height {
+		return 0
+	}
+	return min(1+int((height-lh)/(lh*spacing)), len(lines))
+}
+
+// widest returns the width of the widest of lines at size.
+func widest(lines []string, fontName string, size int) float64 {
+	w := 0.0
+	for _, line := range lines {
+		w = max(w, font.TextWidth(line, fontName, size))
+	}
+	return w
+}
+
+// truncate returns line, or as much of it as fits in width at size followed
+// by an ellipsis. A line ending in an ellipsis keeps it.
+func truncate(line, fontName string, size int, width float64) string {
+	if font.TextWidth(line, fontName, size) <= width {
+		return line
+	}
+	runes := []rune(strings.TrimSuffix(line, ellipsis))
+	for n := len(runes); n > 0; n-- {
+		s := strings.TrimRight(string(runes[:n]), " ") + ellipsis
+		if font.TextWidth(s, fontName, size) <= width {
+			return s
+		}
+	}
+	return ellipsis
+}
+
+// checkAlign checks the Align and VAlign of ov and that it has the box they
+// align the text in.
+func checkAlign(ov OverlayRectText) error {
+	switch ov.Align {
+	case "":
+	case "left", "center", "right":
+		if ov.Width <= 0 {
+			return fmt.Errorf("align needs a positive width")
+		}
+	default:
+		return fmt.Errorf("invalid align %q (valid: left, center, right)", ov.Align)
+	}
+	switch ov.VAlign {
+	case "":
+	case "top", "middle", "bottom":
+		if ov.Height <= 0 {
+			return fmt.Errorf("valign needs a positive height")
+		}
+	default:
+		return fmt.Errorf("invalid valign %q (valid: top, middle, bottom)", ov.VAlign)
+	}
+	return nil
+}
+
+// lineOffset returns how far right of the overlay's X and above its Y to
+// draw line i of p's text, which is w x h points, on a pageW x pageH page.
+// Aligned lines are measured from the box's lower-left corner, wherever the
+// anchor puts it, since the anchor also shifts each part by its own size.
+func (p overlayPlan) lineOffset(i int, w, h, pageW, pageH float64) (dx, dy float64) {
+	dy = p.firstLineOffset - float64(i)*p.lineHeight
+	if p.align == "" && p.valign == "" {
+		return 0, dy
+	}
+	a, err := types.ParsePositionAnchor(p.anchor)
+	if err != nil {
+		a = types.BottomLeft
+	}
+	page := types.RectForDim(pageW, pageH)
+	box := model.LowerLeftCorner(page, p.alignW, p.alignH, a)
+	part := model.LowerLeftCorner(page, w, h, a)
+
+	switch p.align {
+	case "left":
+		dx = box.X - part.X
+	case "center":
+		dx = box.X - part.X + (p.alignW-w)/2
+	case "right":
+		dx = box.X - part.X + p.alignW - w
+	}
+
+	// above is how far line i sits above the last line.
+	above := float64(len(p.lines)-1-i) * p.lineHeight
+	block := h + float64(len(p.lines)-1)*p.lineHeight
+	switch p.valign {
+	case "top":
+		dy = box.Y - part.Y + p.alignH - h - float64(i)*p.lineHeight
+	case "middle":
+		dy = box.Y - part.Y + (p.alignH-block)/2 + above
+	case "bottom":
+		dy = box.Y - part.Y + above
+	}
+	return dx, dy
+}
@@ -0,0 +1,140 @@
+package overlay
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// TIFF tags, field types and values used by encodeTIFF.
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffPhotometric     = 262
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffXResolution     = 282
+	tiffYResolution     = 283
+	tiffResolutionUnit  = 296
+
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+
+	tiffDeflate     = 8 // Adobe deflate: zlib-compressed strips
+	tiffBlackIsZero = 1
+	tiffRGB         = 2
+	tiffInch        = 2
+)
+
+// tiffEntry is one IFD entry whose value fits in its 4-byte value field.
+type tiffEntry struct {
+	tag, typ uint16
+	value    uint32
+}
+
+// encodeTIFF writes pages to w as one multi-page TIFF at dpi dots per inch,
+// each page a single deflated strip. Gray pages stay 8-bit gray; all others
+// are written as 8-bit RGB. The standard library and x/image only write
+// single-page TIFFs.
+func encodeTIFF(w io.Writer, pages []image.Image, dpi int) error {
+	le := binary.LittleEndian
+	buf := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	// next is where the offset of the next IFD goes: the header first, then
+	// the end of each IFD.
+	next := 4
+	for _, page := range pages {
+		pix, samples, photometric := tiffPixels(page)
+		b := page.Bounds()
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(pix); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		stripOffset := len(buf)
+		buf = append(buf, z.Bytes()...)
+		buf = append(buf, make([]byte, len(buf)%2)...) // IFDs start on a word boundary
+
+		// The resolution rationals and RGB's three BitsPerSample values
+		// don't fit in an entry, so they go just before the IFD.
+		resOffset := len(buf)
+		buf = le.AppendUint32(buf, uint32(dpi))
+		buf = le.AppendUint32(buf, 1)
+		bitsPerSample := uint32(8)
+		if samples > 1 {
+			bitsPerSample = uint32(len(buf))
+			for range samples {
+				buf = le.AppendUint16(buf, 8)
+			}
+		}
+
+		entries := []tiffEntry{
+			{tiffImageWidth, tiffLong, uint32(b.Dx())},
+			{tiffImageLength, tiffLong, uint32(b.Dy())},
+			{tiffBitsPerSample, tiffShort, bitsPerSample},
+			{tiffCompression, tiffShort, tiffDeflate},
+			{tiffPhotometric, tiffShort, photometric},
+			{tiffStripOffsets, tiffLong, uint32(stripOffset)},
+			{tiffSamplesPerPixel, tiffShort, uint32(samples)},
+			{tiffRowsPerStrip, tiffLong, uint32(b.Dy())},
+			{tiffStripByteCounts, tiffLong, uint32(z.Len())},
+			{tiffXResolution, tiffRational, uint32(resOffset)},
+			{tiffYResolution, tiffRational, uint32(resOffset)},
+			{tiffResolutionUnit, tiffShort, tiffInch},
+		}
+		le.PutUint32(buf[next:], uint32(len(buf)))
+		buf = le.AppendUint16(buf, uint16(len(entries)))
+		for _, e := range entries {
+			count := uint32(1)
+			if e.tag == tiffBitsPerSample {
+				count = uint32(samples)
+			}
+			buf = le.AppendUint16(buf, e.tag)
+			buf = le.AppendUint16(buf, e.typ)
+			buf = le.AppendUint32(buf, count)
+			if e.typ == tiffShort && count == 1 {
+				// A lone SHORT sits in the first two bytes of the field.
+				buf = le.AppendUint16(buf, uint16(e.value))
+				buf = le.AppendUint16(buf, 0)
+			} else {
+				buf = le.AppendUint32(buf, e.value)
+			}
+		}
+		next = len(buf)
+		buf = le.AppendUint32(buf, 0)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// tiffPixels returns the rows of img as 8-bit samples, with the number of
+// samples per pixel and the photometric interpretation they have.
+func tiffPixels(img image.Image) ([]byte, int, uint32) {
+	b := img.Bounds()
+	if g, ok := img.(*image.Gray); ok {
+		pix := make([]byte, 0, b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := g.PixOffset(b.Min.X, y)
+			pix = append(pix, g.Pix[i:i+b.Dx()]...)
+		}
+		return pix, 1, tiffBlackIsZero
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	pix := make([]byte, 0, 3*b.Dx()*b.Dy())
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		pix = append(pix, rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2])
+	}
+	return pix, 3, tiffRGB
+}
@@ -0,0 +1,468 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DecodeOverlaysTOML reads overlays written in TOML, with the same field
+// names as the JSON form, and checks them like DecodeOverlays. The overlays
+// are the document's overlays array of tables; other keys are ignored:
+//
+//	# Employee name, top left.
+//	[[overlays]]
+//	text = "Alice Smith"
+//	x = 50
+//	y = 700
+//
+// Dates and times, which no overlay field takes, are not supported.
+func DecodeOverlaysTOML(data []byte) ([]OverlayRectText, error) {
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, invalidf("reading TOML: %v", err)
+	}
+	list, ok := doc["overlays"]
+	if !ok {
+		return nil, invalidf("TOML document has no overlays array")
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeOverlays(bytes.NewReader(b))
+}
+
+// tomlParser reads a TOML document into maps, slices and scalars that
+// marshal as the equivalent JSON.
+type tomlParser struct {
+	data []byte
+	pos  int
+	// headers holds the [table] headers read so far, their keys joined
+	// by NULs, so a table can't be defined twice. Those under an array of
+	// tables start again with its next table.
+	headers map[string]bool
+}
+
+// parseTOML returns the root table of the TOML document data.
+func parseTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{data: data, headers: map[string]bool{}}
+	root := map[string]any{}
+	table := root
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return root, nil
+		}
+		var err error
+		if p.data[p.pos] == '[' {
+			table, err = p.header(root)
+		} else {
+			err = p.keyValue(table)
+		}
+		if err == nil {
+			err = p.endOfLine()
+		}
+		if err != nil {
+			line := bytes.Count(p.data[:p.pos], []byte("\n")) + 1
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+}
+
+// header reads a [table] or [[array of tables]] header and returns the
+// table the keys after it go in.
+func (p *tomlParser) header(root map[string]any) (map[string]any, error) {
+	p.pos++
+	array := p.consume('[')
+	p.skipBlank()
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if !p.consume(']') || array && !p.consume(']') {
+		return nil, fmt.Errorf("unterminated table header")
+	}
+	parent, err := tableAt(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	path := strings.Join(keys, "\x00")
+	if array {
+		for h := range p.headers {
+			if strings.HasPrefix(h, path+"\x00") {
+				delete(p.headers, h)
+			}
+		}
+	} else if p.headers[path] {
+		return nil, fmt.Errorf("%q is already defined", strings.Join(keys, "."))
+	} else {
+		p.headers[path] = true
+	}
+	switch v := parent[last].(type) {
+	case nil:
+		t := map[string]any{}
+		if array {
+			parent[last] = []any{t}
+		} else {
+			parent[last] = t
+		}
+		return t, nil
+	case []any:
+		if array {
+			t := map[string]any{}
+			parent[last] = append(v, t)
+			return t, nil
+		}
+	case map[string]any:
+		if !array {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is already defined", strings.Join(keys, "."))
+}
+
+// tableAt returns the table that the dotted key keys names within t,
+// creating the tables that don't exist yet. A key naming an array of tables
+// means its latest table.
+func tableAt(t map[string]any, keys []string) (map[string]any, error) {
+	for i, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			t = v
+		case []any:
+			next, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%q is not a table", strings.Join(keys[:i+1], "."))
+			}
+			t = next
+		default:
+			return nil, fmt.Errorf("%q is not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+// keyValue reads a key = value pair into t.
+func (p *tomlParser) keyValue(t map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank()
+	if !p.consume('=') {
+		return fmt.Errorf("expected = after %q", strings.Join(keys, "."))
+	}
+	p.skipBlank()
+	v, err := p.value()
+	if err != nil {
+		return fmt.Errorf("%s: %v", strings.Join(keys, "."), err)
+	}
+	parent, err := tableAt(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, dup := parent[last]; dup {
+		return fmt.Errorf("%q is defined twice", strings.Join(keys, "."))
+	}
+	parent[last] = v
+	return nil
+}
+
+// key reads a possibly dotted key, bare or quoted, into its parts.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		var k string
+		var err error
+		switch c := p.peek(); {
+		case c == '"':
+			k, err = p.basicString()
+		case c == '\'':
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && isBareKeyByte(p.data[p.pos]) {
+				p.pos++
+			}
+			if k = string(p.data[start:p.pos]); k == "" {
+				return nil, fmt.Errorf("expected a key, found %q", p.rest())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		p.skipBlank()
+		if !p.consume('.') {
+			return keys, nil
+		}
+		p.skipBlank()
+	}
+}
+
+func isBareKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value reads a string, number, boolean, array or inline table.
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		return p.basicString()
+	case c == '\'':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	}
+	start := p.pos
+	for p.pos < len(p.data) && (isBareKeyByte(p.data[p.pos]) || strings.IndexByte("+.:", p.data[p.pos]) >= 0) {
+		p.pos++
+	}
+	tok := string(p.data[start:p.pos])
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, fmt.Errorf("expected a value, found %q", p.rest())
+	}
+	num := strings.ReplaceAll(tok, "_", "")
+	if lower := strings.ToLower(num); strings.Contains(lower, "inf") || strings.Contains(lower, "nan") {
+		return nil, fmt.Errorf("%s has no JSON equivalent", tok)
+	}
+	if digits := strings.TrimLeft(num, "+-"); len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return nil, fmt.Errorf("leading zeros are not allowed, found %s", tok)
+	}
+	if n, err := strconv.ParseInt(num, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil {
+		return f, nil
+	}
+	if strings.ContainsAny(tok, ":") || len(tok) >= 10 && tok[4] == '-' {
+		return nil, fmt.Errorf("dates and times are not supported, found %s", tok)
+	}
+	return nil, fmt.Errorf("invalid value %s", tok)
+}
+
+// array reads an array, which may span lines and end in a comma.
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++
+	list := []any{}
+	for {
+		p.skipSpace()
+		if p.consume(']') {
+			return list, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		p.skipSpace()
+		if p.consume(']') {
+			return list, nil
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("expected , or ] in array, found %q", p.rest())
+		}
+	}
+}
+
+// inlineTable reads a one-line {key = value, ...} table.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++
+	t := map[string]any{}
+	p.skipBlank()
+	if p.consume('}') {
+		return t, nil
+	}
+	for {
+		p.skipBlank()
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipBlank()
+		if p.consume('}') {
+			return t, nil
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("expected , or } in inline table, found %q", p.rest())
+		}
+	}
+}
+
+// basicString reads a "string" or """multi-line string""" with escapes.
+func (p *tomlParser) basicString() (string, error) {
+	multi := p.openString(`"""`)
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch {
+		case multi && bytes.HasPrefix(p.data[p.pos:], []byte(`"""`)):
+			p.pos += 3
+			return sb.String(), nil
+		case !multi && c == '"':
+			p.pos++
+			return sb.String(), nil
+		case !multi && c == '\n':
+			return "", fmt.Errorf("newline in string")
+		case c == '\\':
+			if err := p.escape(&sb, multi); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+}
+
+// escape reads the escape sequence at p.pos into sb. In a multi-line string
+// a backslash at the end of a line drops the line break and the whitespace
+// after it.
+func (p *tomlParser) escape(sb *strings.Builder, multi bool) error {
+	p.pos++
+	if multi {
+		rest := p.data[p.pos:]
+		if trimmed := bytes.TrimLeft(rest, " \t\r"); len(trimmed) > 0 && trimmed[0] == '\n' {
+			p.pos += len(rest) - len(bytes.TrimLeft(rest, " \t\r\n"))
+			return nil
+		}
+	}
+	if p.pos >= len(p.data) {
+		return fmt.Errorf("unterminated string")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	if r, ok := map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\'}[c]; ok {
+		sb.WriteByte(r)
+		return nil
+	}
+	digits := map[byte]int{'u': 4, 'U': 8}[c]
+	if digits == 0 || p.pos+digits > len(p.data) {
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+digits]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return fmt.Errorf("invalid escape \\%c%s", c, p.data[p.pos:p.pos+digits])
+	}
+	p.pos += digits
+	sb.WriteRune(rune(code))
+	return nil
+}
+
+// literalString reads a single- or triple-quoted literal string as written.
+func (p *tomlParser) literalString() (string, error) {
+	if p.openString(`'''`) {
+		end := bytes.Index(p.data[p.pos:], []byte(`'''`))
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		s := string(p.data[p.pos : p.pos+end])
+		p.pos += end + 3
+		return s, nil
+	}
+	end := bytes.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] == '\n' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := string(p.data[p.pos : p.pos+end])
+	p.pos += end + 1
+	return s, nil
+}
+
+// openString skips the opening quote of a string and reports whether it
+// is the triple quote delim of a multi-line string, whose first line break
+// it also skips.
+func (p *tomlParser) openString(delim string) bool {
+	if !bytes.HasPrefix(p.data[p.pos:], []byte(delim)) {
+		p.pos++
+		return false
+	}
+	p.pos += 3
+	if bytes.HasPrefix(p.data[p.pos:], []byte("\r\n")) {
+		p.pos += 2
+	} else {
+		p.consume('\n')
+	}
+	return true
+}
+
+// endOfLine skips to the start of the next line, allowing only a comment.
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank()
+	if p.peek() == '#' {
+		for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	p.consume('\r')
+	if p.pos < len(p.data) && !p.consume('\n') {
+		return fmt.Errorf("expected end of line, found %q", p.rest())
+	}
+	return nil
+}
+
+// skipBlank skips spaces and tabs.
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipSpace skips whitespace, line breaks and comments.
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the byte at p.pos, or 0 at the end.
+func (p *tomlParser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// consume skips c if it is next and reports whether it was.
+func (p *tomlParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+// rest returns what follows p.pos on its line, for error messages.
+func (p *tomlParser) rest() string {
+	line, _, _ := bytes.Cut(p.data[p.pos:], []byte("\n"))
+	return string(line)
+}
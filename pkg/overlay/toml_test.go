@@ -0,0 +1,149 @@
+package overlay
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestParseTOML checks documents parseTOML reads against the JSON they are
+// equivalent to.
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{name: "empty", doc: "", want: `{}`},
+		{name: "comments and blank lines", doc: "# a comment\n\n  a = 1 # another\n\r\n", want: `{"a":1}`},
+		{name: "scalars", doc: "i = -42\nf = 1.5e3\nu = 1_000\nh = 0xff\nt = true\nn = false", want: `{"f":1500,"h":255,"i":-42,"n":false,"t":true,"u":1000}`},
+		{
+			name: "basic string escapes",
+			doc:  `s = "tab\there \"quoted\" back\\slash\nnew \u00e9 \U0001F600"`,
+			want: `{"s":"tab\there \"quoted\" back\\slash\nnew é 😀"}`,
+		},
+		{
+			name: "literal strings keep backslashes",
+			doc:  "s = 'C:\\path\\n \"as is\"'\nm = '''\nfirst\\n\nsecond'''",
+			want: `{"m":"first\\n\nsecond","s":"C:\\path\\n \"as is\""}`,
+		},
+		{
+			name: "multi-line basic string",
+			doc:  "s = \"\"\"\nline one\nline \\\n    two\"\"\"",
+			want: `{"s":"line one\nline two"}`,
+		},
+		{name: "quoted and dotted keys", doc: "\"a b\" = 1\n'c.d' = 2\ne . f = 3", want: `{"a b":1,"c.d":2,"e":{"f":3}}`},
+		{name: "array across lines", doc: "a = [\n  1, # one\n  2,\n]\nb = [ ]\nc = [[1, 2], ['x']]", want: `{"a":[1,2],"b":[],"c":[[1,2],["x"]]}`},
+		{
+			name: "inline tables",
+			doc:  "p = { x = 1, y = 'two', z.w = true }\ne = {}\nl = [{ a = 1 }, { a = 2 }]",
+			want: `{"e":{},"l":[{"a":1},{"a":2}],"p":{"x":1,"y":"two","z":{"w":true}}}`,
+		},
+		{
+			name: "tables",
+			doc:  "top = 1\n[a]\nx = 1\n[a.b]\ny = 2\n[c . 'd e']\nz = 3",
+			want: `{"a":{"b":{"y":2},"x":1},"c":{"d e":{"z":3}},"top":1}`,
+		},
+		{
+			name: "arrays of tables",
+			doc:  "[[overlays]]\ntext = 'one'\n\n[[overlays]]\ntext = 'two'\n[overlays.style]\nbold = true\n\n[[overlays]]\n",
+			want: `{"overlays":[{"text":"one"},{"style":{"bold":true},"text":"two"},{}]}`,
+		},
+		{
+			name: "nested arrays of tables",
+			doc:  "[[a]]\n[[a.b]]\nn = 1\n[[a.b]]\nn = 2\n[[a]]\n[[a.b]]\nn = 3",
+			want: `{"a":[{"b":[{"n":1},{"n":2}]},{"b":[{"n":3}]}]}`,
+		},
+		{
+			name: "same table in each of an array of tables",
+			doc:  "[[a]]\n[a.t]\nx = 1\n[[a]]\n[a.t]\nx = 2",
+			want: `{"a":[{"t":{"x":1}},{"t":{"x":2}}]}`,
+		},
+		{name: "CRLF line ends", doc: "a = 1\r\n[t]\r\nb = '''\r\nx'''\r\n", want: `{"a":1,"t":{"b":"x"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseTOML([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("parseTOML: %v", err)
+			}
+			got, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("parseTOML = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseTOMLErrors checks that parseTOML rejects invalid documents with
+// the line at fault.
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, wantErr string
+	}{
+		{name: "duplicate key", doc: "a = 1\nb = 2\na = 3", wantErr: `line 3: "a" is defined twice`},
+		{name: "duplicate dotted key", doc: "a.b = 1\na.b = 2", wantErr: `line 2: "a.b" is defined twice`},
+		{name: "duplicate key in a table", doc: "[t]\nx = 1\n\nx = 2", wantErr: `line 4: "x" is defined twice`},
+		{name: "duplicate key in an inline table", doc: "p = { x = 1, x = 2 }", wantErr: `line 1: p: "x" is defined twice`},
+		{name: "duplicate table", doc: "[t]\nx = 1\n[t]\ny = 2", wantErr: `line 3: "t" is already defined`},
+		{name: "duplicate table in an array of tables", doc: "[[a]]\n[a.t]\n[a.t]", wantErr: `line 3: "a.t" is already defined`},
+		{name: "table after array of tables", doc: "[[t]]\n[t]", wantErr: `line 2: "t" is already defined`},
+		{name: "array of tables after table", doc: "[t]\n[[t]]", wantErr: `line 2: "t" is already defined`},
+		{name: "table over a value", doc: "t = 1\n[t.u]", wantErr: `line 2: "t" is not a table`},
+		{name: "unterminated header", doc: "\n[t\nx = 1", wantErr: "line 2: unterminated table header"},
+		{name: "missing =", doc: "a 1", wantErr: `line 1: expected = after "a"`},
+		{name: "missing value", doc: "a = \nb = 1", wantErr: `line 1: a: expected a value`},
+		{name: "unterminated string", doc: "a = 1\nb = \"open", wantErr: "line 2: b: unterminated string"},
+		{name: "newline in string", doc: "s = \"one\ntwo\"", wantErr: "line 1: s: newline in string"},
+		{name: "unterminated literal string", doc: "s = 'one\n'", wantErr: "line 1: s: unterminated string"},
+		{name: "invalid escape", doc: "\n\ns = \"\\q\"", wantErr: `line 3: s: invalid escape \q`},
+		{name: "invalid unicode escape", doc: `s = "\uD800"`, wantErr: `line 1: s: invalid escape \uD800`},
+		{name: "bad array separator", doc: "a = [\n1,\n2 3]", wantErr: "line 3: a: expected , or ] in array"},
+		{name: "bad inline table separator", doc: "p = { x = 1 y = 2 }", wantErr: "line 1: p: expected , or } in inline table"},
+		{name: "trailing text", doc: "a = 1 2", wantErr: `line 1: expected end of line, found "2"`},
+		{name: "leading zero", doc: "a = 007", wantErr: "line 1: a: leading zeros are not allowed"},
+		{name: "infinity", doc: "a = inf", wantErr: "line 1: a: inf has no JSON equivalent"},
+		{name: "date", doc: "d = 2025-01-01", wantErr: "line 1: d: dates and times are not supported"},
+		{name: "invalid number", doc: "a = 1.2.3", wantErr: "line 1: a: invalid value 1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseTOML([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parseTOML = %v, %v, want an error containing %q", doc, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDecodeOverlaysTOML checks that the overlays array of tables decodes
+// like the same overlays in JSON, with their errors.
+func TestDecodeOverlaysTOML(t *testing.T) {
+	overlays, err := DecodeOverlaysTOML([]byte("title = 'ignored'\n\n[[overlays]]\ntext = \"Alice \\\"Al\\\" Smith\"\nx = 50\ny = 700\n\n[[overlays]]\nx = 10\ny = 20\nwidth = 100.5\nheight = 20\nscale = 1\ndash = [3, 2]\n"))
+	if err != nil {
+		t.Fatalf("DecodeOverlaysTOML: %v", err)
+	}
+	if len(overlays) != 2 {
+		t.Fatalf("%d overlays, want 2", len(overlays))
+	}
+	if got := overlays[0].Text; got != `Alice "Al" Smith` {
+		t.Errorf("overlay 0 text = %q", got)
+	}
+	if got := overlays[1]; got.Width != 100.5 || got.Height != 20 || len(got.Dash) != 2 {
+		t.Errorf("overlay 1 = %+v", got)
+	}
+
+	for doc, wantErr := range map[string]string{
+		"a = 1":                          "no overlays array",
+		"[[overlays]]\ntxt = 'x'":        `unknown field "txt"`,
+		"[[overlays]]\nx = 'one'":        `field "x": expected a number`,
+		"[[overlays]]\ntext = 'x'\n[[":   "line 3",
+		"[[overlays]]\nwidth = 10":       "overlay 0: nothing to draw",
+		"overlays = [{ text = 'x' }, 1]": "overlay 1",
+	} {
+		if _, err := DecodeOverlaysTOML([]byte(doc)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("DecodeOverlaysTOML(%q) = %v, want an error containing %q", doc, err, wantErr)
+		}
+	}
+}
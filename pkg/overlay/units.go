@@ -0,0 +1,41 @@
+package overlay
+
+import "fmt"
+
+// pointsPerUnit is the size in PDF points of one of each absolute unit
+// Units accepts.
+var pointsPerUnit = map[string]float64{
+	"":       1,
+	"points": 1,
+	"pt":     1,
+	"in":     72,
+	"mm":     72 / 25.4,
+}
+
+// percentUnits reports whether ov's coordinates are percentages of the page
+// size rather than absolute lengths.
+func percentUnits(ov OverlayRectText) (bool, error) {
+	if ov.Units == "percent" {
+		return true, nil
+	}
+	if _, ok := pointsPerUnit[ov.Units]; ok {
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid units %q (valid: pt, in, mm, percent)", ov.Units)
+}
+
+// inPoints returns a copy of ov with X, Y, Width and Height converted from
+// its units to PDF points for a pageW x pageH page.
+func inPoints(ov OverlayRectText, pageW, pageH float64) OverlayRectText {
+	sx, sy := pageW/100, pageH/100
+	if ov.Units != "percent" {
+		sx = pointsPerUnit[ov.Units]
+		sy = sx
+	}
+	ov.X *= sx
+	ov.Y *= sy
+	ov.Width *= sx
+	ov.Height *= sy
+	ov.Units = "points"
+	return ov
+}
@@ -0,0 +1,203 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// TextCheck is the validation result of the text one overlay draws on one
+// page: the text expected, its box in the coordinates of TextRun and the
+// text found there.
+type TextCheck struct {
+	Index  int     `json:"index"`
+	Label  string  `json:"label,omitempty"`
+	Field  string  `json:"field,omitempty"`
+	Page   int     `json:"page"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Want   string  `json:"want"`
+	Found  string  `json:"found"`
+	Pass   bool    `json:"pass"`
+}
+
+// ForbiddenMatch is a forbidden string found in a PDF, with the box of the
+// text runs it was found in.
+type ForbiddenMatch struct {
+	Text   string  `json:"text"`
+	Page   int     `json:"page"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Validation is the result of Validate.
+type Validation struct {
+	Texts     []TextCheck      `json:"texts"`
+	Forbidden []ForbiddenMatch `json:"forbidden"`
+	// Pass is true when every text check passes and no forbidden string
+	// was found.
+	Pass bool `json:"pass"`
+}
+
+// Validate reads the text of pdf, the result of applying overlays, back
+// and checks that the text of each overlay is shown where the overlay draws
+// it, give or take tolerance points, and that none of the forbidden strings
+// is shown anywhere. Text inside form XObjects counts, as overlays are drawn
+// in them. Text is compared without its white space, and glyphs read as
+// "?", as Inspect reads glyphs whose codes aren't ASCII, match any
+// character of an overlay's text.
+func Validate(pdf []byte, overlays []OverlayRectText, forbidden []string, tolerance float64) (Validation, error) {
+	overlays, err := LocateText(pdf, overlays)
+	if err != nil {
+		return Validation{}, err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return Validation{}, readError(err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return Validation{}, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+	runs, err := textRuns(pdf, "", true)
+	if err != nil {
+		return Validation{}, err
+	}
+	pageRuns := map[int][]TextRun{}
+	for _, r := range runs {
+		pageRuns[r.Page] = append(pageRuns[r.Page], r)
+	}
+
+	v := Validation{Texts: []TextCheck{}, Forbidden: []ForbiddenMatch{}, Pass: true}
+	for i, ov := range overlays {
+		pages, err := pagesFor(ov.Pages, ctx.PageCount)
+		if err != nil {
+			return Validation{}, &OverlayError{i, err}
+		}
+		for page := 1; page <= ctx.PageCount; page++ {
+			if !pages[page] {
+				continue
+			}
+			pageW, pageH := viewOf(boundaries[page-1]).size()
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return Validation{}, err
+			}
+			b := plan.textBounds(pageW, pageH)
+			if b == nil {
+				continue
+			}
+			// Runs count when their center is within tolerance of the box.
+			var found []string
+			for _, r := range pageRuns[page] {
+				cx, cy := r.X+r.Width/2, r.Y+r.Height/2
+				if cx >= b.LL.X-tolerance && cx <= b.UR.X+tolerance && cy >= b.LL.Y-tolerance && cy <= b.UR.Y+tolerance {
+					found = append(found, r.Text)
+				}
+			}
+			check := TextCheck{
+				Index: i, Label: ov.Label, Field: ov.Field, Page: page,
+				X: round2(b.LL.X), Y: round2(b.LL.Y), Width: round2(b.Width()), Height: round2(b.Height()),
+				Want: strings.Join(plan.lines, "\n"), Found: strings.Join(found, " "),
+			}
+			check.Pass = containsText(check.Found, check.Want)
+			v.Pass = v.Pass && check.Pass
+			v.Texts = append(v.Texts, check)
+		}
+	}
+
+	for page := 1; page <= ctx.PageCount; page++ {
+		for _, f := range forbidden {
+			v.Forbidden = append(v.Forbidden, findForbidden(pageRuns[page], f)...)
+		}
+	}
+	v.Pass = v.Pass && len(v.Forbidden) == 0
+	return v, nil
+}
+
+// findForbidden returns every occurrence of text in runs, which are read
+// as one string without white space, so text split over several runs is
+// found too.
+func findForbidden(runs []TextRun, text string) []ForbiddenMatch {
+	want := withoutSpace(text)
+	if want == "" {
+		return nil
+	}
+	// all is the text of runs; owner holds the run each byte comes from.
+	var all []byte
+	var owner []int
+	for i, r := range runs {
+		s := withoutSpace(r.Text)
+		all = append(all, s...)
+		for range s {
+			owner = append(owner, i)
+		}
+	}
+	var matches []ForbiddenMatch
+	for start := 0; ; {
+		at := bytes.Index(all[start:], []byte(want))
+		if at < 0 {
+			return matches
+		}
+		at += start
+		var box *types.Rectangle
+		for i := owner[at]; i <= owner[at+len(want)-1]; i++ {
+			r := runs[i]
+			box = union(box, types.NewRectangle(r.X, r.Y, r.X+r.Width, r.Y+r.Height))
+		}
+		matches = append(matches, ForbiddenMatch{
+			Text: text, Page: runs[owner[at]].Page,
+			X: round2(box.LL.X), Y: round2(box.LL.Y), Width: round2(box.Width()), Height: round2(box.Height()),
+		})
+		start = at + len(want)
+	}
+}
+
+// containsText reports whether want occurs in found, both without white
+// space, where a "?" in found matches any character.
+func containsText(found, want string) bool {
+	f := withoutSpace(found)
+	var w []byte
+	for _, c := range withoutSpace(want) {
+		// Characters outside ASCII are read back as "?".
+		if c > '~' {
+			c = '?'
+		}
+		w = append(w, byte(c))
+	}
+	for i := 0; i+len(w) <= len(f); i++ {
+		match := true
+		for j, c := range w {
+			if f[i+j] != c && f[i+j] != '?' {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return len(w) == 0
+}
+
+// withoutSpace returns s with all white space removed.
+func withoutSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,110 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// OverlayCheck is the verification result of one overlay: the text of the
+// original PDF under its rectangle and what is left of it in the result.
+// Text is extracted glyph by glyph from the pages' own content streams;
+// glyphs whose codes aren't ASCII show as "?".
+type OverlayCheck struct {
+	Index         int    `json:"index"`
+	Label         string `json:"label,omitempty"`
+	Field         string `json:"field,omitempty"`
+	Pages         []int  `json:"pages"`
+	OriginalText  string `json:"originalText"`
+	RemainingText string `json:"remainingText"`
+	// Pass is true when none of the original text is left under the
+	// overlay, so it can no longer be extracted from the result.
+	Pass bool `json:"pass"`
+}
+
+// VerifyOverlays re-reads original and the result of applying overlays to it
+// and checks, for each overlay, whether any of the original text is still
+// present under its rectangle (or, without one, under its text).
+func VerifyOverlays(original, result []byte, overlays []OverlayRectText) ([]OverlayCheck, error) {
+	overlays, err := LocateText(original, overlays)
+	if err != nil {
+		return nil, err
+	}
+	planner := &overlayPlanner{overlays: overlays, plans: map[planKey]overlayPlan{}, quiet: true}
+
+	conf := model.NewDefaultConfiguration()
+	before, err := api.ReadValidateAndOptimize(bytes.NewReader(original), conf)
+	if err != nil {
+		return nil, fmt.Errorf("original: %w", readError(err))
+	}
+	after, err := api.ReadValidateAndOptimize(bytes.NewReader(result), conf)
+	if err != nil {
+		return nil, fmt.Errorf("result: %w", readError(err))
+	}
+	boundaries, err := after.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+	// pdfcpu turns the content of rotated pages it draws on upright, so the
+	// same area lies elsewhere in the original's user space.
+	beforeBoundaries, err := before.PageBoundaries(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading page sizes: %v", err)
+	}
+
+	checks := make([]OverlayCheck, len(overlays))
+	for i, ov := range overlays {
+		check := OverlayCheck{Index: i, Label: ov.Label, Field: ov.Field, Pages: []int{}, Pass: true}
+		pages, err := pagesFor(ov.Pages, after.PageCount)
+		if err != nil {
+			return nil, &OverlayError{i, err}
+		}
+		for page := 1; page <= after.PageCount; page++ {
+			if !pages[page] {
+				continue
+			}
+			view := viewOf(boundaries[page-1])
+			pageW, pageH := view.size()
+			plan, err := planner.plan(i, pageW, pageH)
+			if err != nil {
+				return nil, err
+			}
+			area := plan.rectArea(pageW, pageH)
+			if area == nil {
+				area = plan.bounds(pageW, pageH)
+			}
+			if area == nil {
+				continue
+			}
+			check.Pages = append(check.Pages, page)
+
+			if page <= before.PageCount {
+				text, err := textUnder(before.XRefTable, page, viewOf(beforeBoundaries[page-1]).toUser(area))
+				if err != nil {
+					return nil, fmt.Errorf("overlay %d: original: %v", i, err)
+				}
+				check.OriginalText += text
+			}
+			text, err := textUnder(after.XRefTable, page, view.toUser(area))
+			if err != nil {
+				return nil, fmt.Errorf("overlay %d: result: %v", i, err)
+			}
+			check.RemainingText += text
+		}
+		check.Pass = check.RemainingText == ""
+		checks[i] = check
+	}
+	return checks, nil
+}
+
+// textUnder returns the text page pageNr shows inside area.
+func textUnder(xRefTable *model.XRefTable, pageNr int, area *types.Rectangle) (string, error) {
+	r, _, _, _, err := scanPage(xRefTable, pageNr, []*types.Rectangle{area}, formsSkipped)
+	if r == nil {
+		return "", err
+	}
+	return string(r.found), nil
+}
@@ -0,0 +1,318 @@
+package overlay
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// xrefPDF is a PDF as pdfcpu writes it with a cross-reference table, split
+// into its objects so they can be replaced and written back in order.
+type xrefPDF struct {
+	header  []byte         // everything before the first object
+	objects map[int][]byte // each object in use, "N G obj ... endobj\n"
+	entries map[int][]byte // the cross-reference entry of each object
+	size    int            // the number of cross-reference entries
+	trailer []byte         // from "trailer" up to "startxref"
+}
+
+// idPattern matches the file identifier of a trailer as pdfcpu writes it.
+var idPattern = regexp.MustCompile(`/ID\s*\[\s*<[0-9A-Fa-f]*>\s*<[0-9A-Fa-f]*>\s*\]`)
+
+// splitXRef splits pdf, written by pdfcpu with a single cross-reference
+// table, into its objects.
+func splitXRef(pdf []byte) (*xrefPDF, error) {
+	at := bytes.LastIndex(pdf, []byte("startxref"))
+	if at < 0 {
+		return nil, fmt.Errorf("no startxref")
+	}
+	fields := bytes.Fields(pdf[at+len("startxref"):])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no startxref offset")
+	}
+	xref, err := strconv.Atoi(string(fields[0]))
+	if err != nil || xref < 0 || xref >= at || !bytes.HasPrefix(pdf[xref:], []byte("xref")) {
+		return nil, fmt.Errorf("no cross-reference table at startxref")
+	}
+	trailer := bytes.Index(pdf[xref:at], []byte("trailer"))
+	if trailer < 0 {
+		return nil, fmt.Errorf("no trailer")
+	}
+	p := &xrefPDF{header: pdf[:xref], objects: map[int][]byte{}, entries: map[int][]byte{}, trailer: pdf[xref+trailer : at]}
+
+	// Each subsection of the table is a "first count" line followed by
+	// count 20-byte entries "oooooooooo ggggg n\r\n".
+	offsets := map[int]int{}
+	table := bytes.NewBuffer(pdf[xref : xref+trailer])
+	table.ReadBytes('\n')
+	for table.Len() > 0 {
+		line, _ := table.ReadBytes('\n')
+		var first, count int
+		if _, err := fmt.Sscanf(string(line), "%d %d", &first, &count); err != nil {
+			return nil, fmt.Errorf("invalid cross-reference subsection %q", bytes.TrimSpace(line))
+		}
+		for nr := first; nr < first+count; nr++ {
+			entry := table.Next(20)
+			if len(entry) != 20 {
+				return nil, fmt.Errorf("short cross-reference table")
+			}
+			p.entries[nr] = entry
+			p.size = max(p.size, nr+1)
+			if entry[17] != 'n' {
+				continue
+			}
+			if offsets[nr], err = strconv.Atoi(string(entry[:10])); err != nil {
+				return nil, fmt.Errorf("invalid cross-reference entry %q", entry)
+			}
+		}
+	}
+
+	// Each object runs up to the next one, the last up to the table.
+	nrs := make([]int, 0, len(offsets))
+	for nr := range offsets {
+		nrs = append(nrs, nr)
+	}
+	sort.Slice(nrs, func(i, j int) bool { return offsets[nrs[i]] < offsets[nrs[j]] })
+	for i, nr := range nrs {
+		end := xref
+		if i+1 < len(nrs) {
+			end = offsets[nrs[i+1]]
+		}
+		if i == 0 {
+			p.header = pdf[:offsets[nr]]
+		}
+		if offsets[nr] > end {
+			return nil, fmt.Errorf("object %d is past the cross-reference table", nr)
+		}
+		p.objects[nr] = pdf[offsets[nr]:end]
+	}
+	return p, nil
+}
+
+// replace replaces the object of ir by o.
+func (p *xrefPDF) replace(ir types.IndirectRef, o types.Object) {
+	nr := ir.ObjectNumber.Value()
+	p.objects[nr] = []byte(fmt.Sprintf("%d %d obj\n%s\nendobj\n", nr, ir.GenerationNumber.Value(), o.PDFString()))
+}
+
+// contentID replaces the file identifier in the trailer by a hash of the
+// objects.
+func (p *xrefPDF) contentID() {
+	h := md5.New()
+	for nr := 0; nr < p.size; nr++ {
+		h.Write(p.objects[nr])
+	}
+	id := fmt.Sprintf("/ID[<%X> <%X>]", h.Sum(nil), h.Sum(nil))
+	p.trailer = idPattern.ReplaceAll(p.trailer, []byte(id))
+}
+
+// bytes returns the PDF with its objects in the order of their numbers.
+func (p *xrefPDF) bytes() []byte {
+	var out bytes.Buffer
+	out.Write(p.header)
+	offsets := make([]int, p.size)
+	for nr := 0; nr < p.size; nr++ {
+		if obj, ok := p.objects[nr]; ok {
+			offsets[nr] = out.Len()
+			out.Write(obj)
+		}
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n", p.size)
+	for nr := 0; nr < p.size; nr++ {
+		entry, ok := p.entries[nr]
+		switch {
+		case !ok:
+			out.WriteString("0000000000 65535 f \n")
+		case entry[17] == 'n':
+			fmt.Fprintf(&out, "%010d", offsets[nr])
+			out.Write(entry[10:])
+		default:
+			out.Write(entry)
+		}
+	}
+	out.Write(p.trailer)
+	fmt.Fprintf(&out, "startxref\n%d\n%%%%EOF\n", xref)
+	return out.Bytes()
+}
+
+// refPattern matches an indirect reference "N G R".
+var refPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+R`)
+
+// streamPattern matches the keyword that starts the data of a stream.
+var streamPattern = regexp.MustCompile(`(^|[\s>])stream\r?\n`)
+
+// mapRefs returns obj, the PDF syntax of an object or trailer, with each
+// indirect reference outside strings and stream data replaced by one to
+// object number f(n) and generation 0, where n is the number it refers to.
+func mapRefs(obj []byte, f func(n int) int) []byte {
+	var out []byte
+	code := func(b []byte) bool {
+		data := len(b)
+		if m := streamPattern.FindIndex(b); m != nil {
+			data = m[1]
+		}
+		last := 0
+		for _, m := range refPattern.FindAllSubmatchIndex(b[:data], -1) {
+			if m[0] > 0 && isRegular(b[m[0]-1]) || m[1] < data && isRegular(b[m[1]]) {
+				continue
+			}
+			n, _ := strconv.Atoi(string(b[m[2]:m[3]]))
+			out = append(out, b[last:m[0]]...)
+			out = fmt.Appendf(out, "%d 0 R", f(n))
+			last = m[1]
+		}
+		out = append(out, b[last:]...)
+		return data < len(b)
+	}
+
+	// Code runs up to the next string.
+	start := 0
+	for i := 0; i < len(obj); i++ {
+		c := obj[i]
+		if c != '(' && (c != '<' || i+1 < len(obj) && obj[i+1] == '<') {
+			if c == '<' {
+				i++
+			}
+			continue
+		}
+		if code(obj[start:i]) {
+			return append(out, obj[i:]...)
+		}
+		end := i + 1
+		if c == '<' {
+			for end < len(obj) && obj[end] != '>' {
+				end++
+			}
+		} else {
+			for depth := 1; end < len(obj) && depth > 0; end++ {
+				switch obj[end] {
+				case '\\':
+					end++
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			end--
+		}
+		end = min(end+1, len(obj))
+		out = append(out, obj[i:end]...)
+		start, i = end, end-1
+	}
+	code(obj[start:])
+	return out
+}
+
+// objPattern matches the "N G obj" header of an object.
+var objPattern = regexp.MustCompile(`^(\d+)\s+(\d+)\s+obj`)
+
+// sizePattern matches the size entry of a trailer.
+var sizePattern = regexp.MustCompile(`/Size\s+\d+`)
+
+// identityPattern matches the entries that tie an object to a place in the
+// document, such as a page in the page tree or a widget on its page, so
+// that a copy of it is not the same object.
+var identityPattern = regexp.MustCompile(`/(Parent|P|Kids|Annots|Type\s*/(Page|Catalog|Annot|Sig))[\s/\[<(\d]`)
+
+// dedupe points the references to each object that is a copy of an earlier
+// one, once the references in both are pointed the same way, at the
+// earlier one instead, so that renumber drops the copies; as the font
+// files under a font's descriptor go, so does the font. Objects that have
+// an identity of their own, such as pages, are left alone.
+func (p *xrefPDF) dedupe() {
+	nrs := make([]int, 0, len(p.objects))
+	for nr := range p.objects {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	same := map[int]int{} // copy => the earlier object it is a copy of
+	earliest := func(n int) int {
+		for {
+			nr, ok := same[n]
+			if !ok {
+				return n
+			}
+			n = nr
+		}
+	}
+	body := func(obj []byte) []byte {
+		header := objPattern.FindIndex(obj)
+		if header == nil {
+			return nil
+		}
+		return obj[header[1]:]
+	}
+	for found := true; found; {
+		found = false
+		first := map[string]int{}
+		for _, nr := range nrs {
+			if _, ok := same[nr]; ok {
+				continue
+			}
+			b := body(p.objects[nr])
+			dict := b
+			if m := streamPattern.FindIndex(b); m != nil {
+				dict = b[:m[0]]
+			}
+			if b == nil || identityPattern.Match(dict) {
+				continue
+			}
+			key := string(mapRefs(b, earliest))
+			if nr0, ok := first[key]; ok {
+				same[nr], found = nr0, true
+			} else {
+				first[key] = nr
+			}
+		}
+	}
+	if len(same) == 0 {
+		return
+	}
+	for nr, obj := range p.objects {
+		if b := body(obj); b != nil {
+			p.objects[nr] = append(obj[:len(obj)-len(b):len(obj)-len(b)], mapRefs(b, earliest)...)
+		}
+	}
+	p.trailer = mapRefs(p.trailer, earliest)
+}
+
+// renumber numbers the objects of p in the order they are first referred
+// to, depth first from the trailer, so the numbers don't depend on the
+// order pdfcpu created the objects in. Objects nothing refers to are dropped.
+func (p *xrefPDF) renumber() {
+	numbers := map[int]int{}
+	var visit func(n int) int
+	visit = func(n int) int {
+		if nr, ok := numbers[n]; ok {
+			return nr
+		}
+		obj, ok := p.objects[n]
+		if !ok {
+			// A reference to a missing object is to null.
+			return 0
+		}
+		numbers[n] = len(numbers) + 1
+		mapRefs(obj, visit)
+		return numbers[n]
+	}
+	mapRefs(p.trailer, visit)
+
+	objects := map[int][]byte{}
+	entries := map[int][]byte{0: []byte("0000000000 65535 f \n")}
+	for old, nr := range numbers {
+		obj := mapRefs(p.objects[old], func(n int) int { return numbers[n] })
+		header := objPattern.FindIndex(obj)
+		objects[nr] = append(fmt.Appendf(nil, "%d 0 obj", nr), obj[header[1]:]...)
+		entries[nr] = []byte("0000000000 00000 n \n")
+	}
+	p.objects, p.entries, p.size = objects, entries, len(numbers)+1
+	p.trailer = mapRefs(p.trailer, func(n int) int { return numbers[n] })
+	p.trailer = sizePattern.ReplaceAll(p.trailer, fmt.Appendf(nil, "/Size %d", p.size))
+}
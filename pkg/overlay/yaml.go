@@ -0,0 +1,61 @@
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DecodeOverlaysYAML reads overlays written in YAML, with the same field
+// names as the JSON form, and checks them like DecodeOverlays. The document
+// is either the list of overlays or a mapping whose "overlays" key holds it;
+// the mapping's other keys are free for anchors such as shared coordinates:
+//
+//	boxes:
+//	  field: &field {width: 120, height: 14, scale: 1}
+//	overlays:
+//	  - {<<: *field, text: Alice Smith, x: 50, y: 700}
+func DecodeOverlaysYAML(data []byte) ([]OverlayRectText, error) {
+	var doc yamlValue
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, invalidf("reading YAML: %v", err)
+	}
+	if m, ok := doc.v.(map[string]yamlValue); ok {
+		list, ok := m["overlays"]
+		if !ok {
+			return nil, invalidf("YAML mapping has no overlays key")
+		}
+		doc = list
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeOverlays(bytes.NewReader(b))
+}
+
+// yamlValue is any YAML value, decoded so that it can be marshalled as JSON.
+// Mapping keys are always read as strings: left to itself YAML 1.1 would
+// read the key of "y: 700" as the boolean true.
+type yamlValue struct {
+	v interface{} // map[string]yamlValue, []yamlValue or a scalar
+}
+
+func (y *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]yamlValue
+	if err := unmarshal(&m); err == nil {
+		y.v = m
+		return nil
+	}
+	var list []yamlValue
+	if err := unmarshal(&list); err == nil {
+		y.v = list
+		return nil
+	}
+	return unmarshal(&y.v)
+}
+
+func (y yamlValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(y.v)
+}
@@ -0,0 +1,307 @@
+package paystub
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+var (
+	bankNames = []string{
+		"First National Bank", "Citizens Savings Bank", "Riverside Credit Union", "Summit Federal Bank",
+		"Lakeshore Trust", "Heritage Community Bank", "Pioneer State Bank", "Union Bank & Trust",
+	}
+	merchants = []string{
+		"GROCERY OUTLET", "SHELL OIL", "TARGET", "WALMART SUPERCENTER", "STARBUCKS",
+		"AMAZON MKTPLACE", "CVS PHARMACY", "CHIPOTLE", "HOME DEPOT", "COSTCO WHSE",
+	}
+)
+
+// BankAccount is the account an employee's net pay is deposited into.
+type BankAccount struct {
+	Bank    Employer `json:"bank"`    // the bank's name and address
+	Routing string   `json:"routing"` // ABA routing number
+	Number  string   `json:"number"`  // printed as given, so mask it beforehand
+}
+
+// Transaction is one line of the ledger of a bank statement.
+type Transaction struct {
+	Date        Date   `json:"date"`
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"` // positive for a deposit, negative for a withdrawal
+}
+
+// BankStatement is the data of a monthly bank statement.
+type BankStatement struct {
+	Account        BankAccount   `json:"account"`
+	Holder         Employee      `json:"holder"`
+	PeriodStart    Date          `json:"periodStart"`
+	PeriodEnd      Date          `json:"periodEnd"`
+	OpeningBalance Money         `json:"openingBalance"`
+	Transactions   []Transaction `json:"transactions"` // in date order
+}
+
+// Deposits returns the total of the deposits of b.
+func (b BankStatement) Deposits() Money {
+	var total Money
+	for _, t := range b.Transactions {
+		total += max(t.Amount, 0)
+	}
+	return total
+}
+
+// Withdrawals returns the total of the withdrawals of b, as a positive
+// amount.
+func (b BankStatement) Withdrawals() Money {
+	var total Money
+	for _, t := range b.Transactions {
+		total -= min(t.Amount, 0)
+	}
+	return total
+}
+
+// ClosingBalance returns the balance of b after its last transaction.
+func (b BankStatement) ClosingBalance() Money {
+	return b.OpeningBalance + b.Deposits() - b.Withdrawals()
+}
+
+// DepositAdvice is the data of a direct-deposit advice: the slip telling an
+// employee their net pay was deposited, given instead of a check.
+type DepositAdvice struct {
+	Number      string      `json:"number"`
+	Employer    Employer    `json:"employer"`
+	Employee    Employee    `json:"employee"`
+	PayDate     Date        `json:"payDate"`
+	PeriodStart Date        `json:"periodStart"`
+	PeriodEnd   Date        `json:"periodEnd"`
+	Account     BankAccount `json:"account"`
+	Amount      Money       `json:"amount"`
+}
+
+// BankAccount returns a random bank account with a masked number.
+func (f *Faker) BankAccount() BankAccount {
+	// The check digit makes 3, 7 and 1 times the digits add up to a
+	// multiple of ten, as in real routing numbers.
+	routing := f.digits(8)
+	sum := 0
+	for i, c := range routing {
+		sum += int(c-'0') * []int{3, 7, 1}[i%3]
+	}
+	routing += string(rune('0' + (10-sum%10)%10))
+	return BankAccount{
+		Bank:    Employer{Name: f.pick(bankNames), Address: f.address(places[f.rng.IntN(len(places))])},
+		Routing: routing,
+		Number:  "XXXXXX" + f.digits(4),
+	}
+}
+
+// DepositAdvices returns the direct-deposit advice of each of stubs, which
+// deposits its net pay into account on its pay date, numbered on from a
+// random number.
+func (f *Faker) DepositAdvices(stubs []Paystub, account BankAccount) []DepositAdvice {
+	first := 100000 + f.rng.IntN(800000)
+	advices := make([]DepositAdvice, len(stubs))
+	for i, s := range stubs {
+		advices[i] = DepositAdvice{
+			Number:      fmt.Sprint(first + i),
+			Employer:    s.Employer,
+			Employee:    s.Employee,
+			PayDate:     s.PayDate,
+			PeriodStart: s.PeriodStart,
+			PeriodEnd:   s.PeriodEnd,
+			Account:     account,
+			Amount:      s.Net(),
+		}
+	}
+	return advices
+}
+
+// BankStatements returns the monthly statements of account, held by the
+// employee of stubs, for every calendar month from the first pay date of
+// stubs to the last. Each stub's net pay is deposited on its pay date,
+// amid random everyday spending that never overdraws the account, and each
+// statement opens with the closing balance of the one before.
+func (f *Faker) BankStatements(stubs []Paystub, account BankAccount) []BankStatement {
+	var paid []Paystub
+	for _, s := range stubs {
+		if !s.PayDate.IsZero() {
+			paid = append(paid, s)
+		}
+	}
+	if len(paid) == 0 {
+		return nil
+	}
+	first := paid[0].PayDate.Time
+	last := paid[len(paid)-1].PayDate.Time
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+
+	// Rent and card payments are a share of the average monthly net pay.
+	var net Money
+	for _, s := range paid {
+		net += s.Net()
+	}
+	monthly := float64(net) / float64(months)
+	rent := Money(math.Round(monthly*(0.25+0.1*f.rng.Float64())/100) * 100)
+	balance := f.between(500, 6000)
+
+	var statements []BankStatement
+	for ; months > 0; months-- {
+		end := month.AddDate(0, 1, -1)
+		day := func() Date { return Date{month.AddDate(0, 0, f.rng.IntN(end.Day()))} }
+		var ts []Transaction
+		for _, s := range paid {
+			if s.PayDate.Year() == month.Year() && s.PayDate.Month() == month.Month() {
+				// ACH entries carry at most 16 characters of the company name.
+				company := strings.ToUpper(s.Employer.Name)
+				company = strings.TrimSpace(company[:min(len(company), 16)])
+				ts = append(ts, Transaction{Date: s.PayDate, Description: "DIRECT DEP " + company + " PAYROLL", Amount: s.Net()})
+			}
+		}
+		debits := []Transaction{
+			{Date: Date{month}, Description: "ACH DEBIT RENT PAYMENT", Amount: -rent},
+			{Date: Date{month.AddDate(0, 0, 9)}, Description: "ACH DEBIT CITY UTILITIES", Amount: -f.between(80, 250)},
+			{Date: Date{month.AddDate(0, 0, 19)}, Description: "ONLINE TRANSFER TO CREDIT CARD", Amount: -Money(math.Round(monthly * (0.1 + 0.1*f.rng.Float64())))},
+		}
+		for range 4 + f.rng.IntN(6) {
+			debits = append(debits, Transaction{Date: day(), Description: "POS PURCHASE " + f.pick(merchants), Amount: -f.between(8, 180)})
+		}
+		ts = append(ts, debits...)
+		// Deposits come first on their day, so spending on a payday is
+		// covered by the pay.
+		sort.SliceStable(ts, func(i, j int) bool {
+			if !ts[i].Date.Equal(ts[j].Date.Time) {
+				return ts[i].Date.Before(ts[j].Date.Time)
+			}
+			return ts[i].Amount > 0 && ts[j].Amount < 0
+		})
+		b := BankStatement{Account: account, Holder: paid[0].Employee, PeriodStart: Date{month}, PeriodEnd: Date{end}, OpeningBalance: balance}
+		for _, t := range ts {
+			if balance+t.Amount < 0 {
+				continue
+			}
+			balance += t.Amount
+			b.Transactions = append(b.Transactions, t)
+		}
+		statements = append(statements, b)
+		month = month.AddDate(0, 1, 0)
+	}
+	return statements
+}
+
+var transactionColumns = []column{
+	{key: "date", heading: "Date", width: 0.12},
+	{key: "description", heading: "Description", width: 0.46},
+	{key: "deposit", heading: "Deposits", width: 0.14, right: true},
+	{key: "withdrawal", heading: "Withdrawals", width: 0.14, right: true},
+	{key: "balance", heading: "Balance", width: 0.14, right: true},
+}
+
+var summaryColumns = []column{
+	{key: "description", heading: "Account Summary", width: 0.86},
+	{key: "amount", heading: "", width: 0.14, right: true},
+}
+
+// Overlays returns the overlays that draw b in the style of layout l on
+// blank l.PageWidth x l.PageHeight pages, going on over more pages as
+// Overlays does for a long ledger. Each text overlay's Field names what it
+// shows, such as "holder.name", "summary[3].amount" or
+// "transactions[0].balance".
+func (b BankStatement) Overlays(l Layout) ([]overlay.OverlayRectText, error) {
+	d, err := newDrawer(l)
+	if err != nil {
+		return nil, err
+	}
+	d.band(b.Account.Bank.Name, []textLine{
+		{"statement.period", fmt.Sprintf("Statement Period: %s - %s", d.loc.Date(b.PeriodStart), d.loc.Date(b.PeriodEnd))},
+		{"statement.account", "Account: " + b.Account.Number},
+	})
+	holder := party("holder", b.Holder.Name, b.Holder.Address)
+	d.blocks(party("bank", b.Account.Bank.Name, b.Account.Bank.Address), holder)
+	d.table("summary", summaryColumns, [][]string{
+		{"Opening Balance", d.loc.Amount(b.OpeningBalance)},
+		{"Deposits", d.loc.Amount(b.Deposits())},
+		{"Withdrawals", d.loc.Amount(b.Withdrawals())},
+		{"Closing Balance", d.loc.Amount(b.ClosingBalance())},
+	})
+	balance := b.OpeningBalance
+	var rows [][]string
+	for _, t := range b.Transactions {
+		balance += t.Amount
+		rows = append(rows, []string{d.loc.Date(t.Date), t.Description, d.amount(max(t.Amount, 0)), d.amount(-min(t.Amount, 0)), d.loc.Amount(balance)})
+	}
+	d.table("transactions", transactionColumns, rows)
+	if d.err != nil {
+		return nil, fmt.Errorf("bank statement does not fit on a %gx%g page: %v", l.PageWidth, l.PageHeight, d.err)
+	}
+	d.finish()
+	return d.overlays, nil
+}
+
+// Generate draws b in the style of layout l on blank pages, as many as it
+// needs, and returns the PDF.
+func (b BankStatement) Generate(l Layout) ([]byte, error) {
+	overlays, err := b.Overlays(l)
+	if err != nil {
+		return nil, err
+	}
+	return draw(overlays, l.PageWidth, l.PageHeight)
+}
+
+var adviceColumns = []column{
+	{key: "number", heading: "Advice No.", width: 0.15},
+	{key: "bank", heading: "Bank", width: 0.35},
+	{key: "routing", heading: "Routing No.", width: 0.15},
+	{key: "account", heading: "Account", width: 0.15},
+	{key: "amount", heading: "Amount", width: 0.20, right: true},
+}
+
+// adviceNotice is printed across the foot of a deposit advice.
+const adviceNotice = "NON-NEGOTIABLE - THIS IS NOT A CHECK"
+
+// Overlays returns the overlays that draw a in the style of layout l on a
+// blank page l.PageWidth wide, and the height of that page: as much of
+// l.PageHeight as they need, as a deposit advice is a slip, not a full
+// page. Each text overlay's Field names what it shows, such as
+// "employee.name" or "deposits[0].amount".
+func (a DepositAdvice) Overlays(l Layout) ([]overlay.OverlayRectText, float64, error) {
+	d, err := newDrawer(l)
+	if err != nil {
+		return nil, 0, err
+	}
+	d.band("DIRECT DEPOSIT ADVICE", d.payDates(a.PayDate, a.PeriodStart, a.PeriodEnd))
+	d.blocks(party("employer", a.Employer.Name, a.Employer.Address), party("employee", a.Employee.Name, a.Employee.Address))
+	d.table("deposits", adviceColumns, [][]string{{a.Number, a.Account.Bank.Name, a.Account.Routing, a.Account.Number, d.loc.Amount(a.Amount)}})
+	d.y -= d.rowHeight()
+	w := font.TextWidth(adviceNotice, l.BoldFont, l.FontSize)
+	d.text("notice", adviceNotice, d.left()+(d.width()-w)/2, d.y, l.FontSize, true, false, false)
+	// A slip is one page.
+	if d.page > 1 || d.y < l.Margin {
+		return nil, 0, fmt.Errorf("deposit advice does not fit on a %gx%g page", l.PageWidth, l.PageHeight)
+	}
+	if l.Border {
+		d.border()
+	}
+	// Cut the page off a margin below the slip.
+	cut := d.y - l.Margin
+	for i := range d.overlays {
+		d.overlays[i].Y -= cut
+	}
+	return d.overlays, l.PageHeight - cut, nil
+}
+
+// Generate draws a in the style of layout l on a blank page as high as it
+// needs and returns the PDF.
+func (a DepositAdvice) Generate(l Layout) ([]byte, error) {
+	overlays, height, err := a.Overlays(l)
+	if err != nil {
+		return nil, err
+	}
+	return draw(overlays, l.PageWidth, height)
+}
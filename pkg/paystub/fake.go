@@ -0,0 +1,139 @@
+package paystub
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	firstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda",
+		"David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Carlos", "Karen", "Daniel", "Lisa", "Matthew", "Nancy",
+		"Anthony", "Sandra", "Mark", "Ashley", "Wei", "Maria", "Andrew", "Priya",
+		"Kevin", "Emily", "Jamal", "Donna", "Luis", "Michelle", "Ahmed", "Grace",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas",
+		"Taylor", "Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White",
+		"Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson", "Walker", "Young",
+		"Allen", "King", "Wright", "Nguyen", "Hill", "Flores", "Green", "Patel",
+	}
+	streetNames = []string{
+		"Oak", "Maple", "Cedar", "Pine", "Elm", "Washington", "Lake", "Hill",
+		"Park", "Main", "Sunset", "Lincoln", "Jefferson", "Highland", "River", "Church",
+		"Willow", "Meadow", "Forest", "Spring",
+	}
+	streetSuffixes = []string{"St", "Ave", "Rd", "Ln", "Dr", "Blvd", "Ct", "Way"}
+	companyKinds   = []string{
+		"Industries", "Logistics", "Holdings", "Manufacturing", "Health Systems",
+		"Foods", "Technologies", "Construction", "Retail Group", "Services",
+	}
+	companySuffixes = []string{"", ", Inc.", " LLC", " Corp.", " Co."}
+)
+
+// place is a city with its state and the first three digits of its ZIP codes.
+type place struct {
+	city, state, zip3 string
+}
+
+var places = []place{
+	{"Springfield", "IL", "627"}, {"Columbus", "OH", "432"}, {"Austin", "TX", "787"},
+	{"Denver", "CO", "802"}, {"Portland", "OR", "972"}, {"Raleigh", "NC", "276"},
+	{"Phoenix", "AZ", "850"}, {"Nashville", "TN", "372"}, {"Madison", "WI", "537"},
+	{"Richmond", "VA", "232"}, {"Sacramento", "CA", "958"}, {"Tampa", "FL", "336"},
+	{"Seattle", "WA", "981"}, {"Atlanta", "GA", "303"}, {"Boise", "ID", "837"},
+	{"Albany", "NY", "122"}, {"Omaha", "NE", "681"}, {"Trenton", "NJ", "086"},
+	{"Harrisburg", "PA", "171"}, {"Lansing", "MI", "489"},
+}
+
+// payPeriodsPerYear is the number of biweekly periods Faker pays in a year.
+const payPeriodsPerYear = 26
+
+// fakeYear is the year Faker dates its stubs in, so output does not depend on
+// the current date.
+const fakeYear = 2025
+
+// Faker makes up realistic paystub data from a seeded random source, so the
+// same seed always gives the same stubs.
+type Faker struct {
+	// Rates are the tax rates the stubs withhold at.
+	Rates Rates
+	rng   *rand.Rand
+}
+
+// NewFaker returns a Faker seeded with seed that withholds at DefaultRates.
+func NewFaker(seed uint64) *Faker {
+	return &Faker{Rates: DefaultRates, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func (f *Faker) pick(list []string) string {
+	return list[f.rng.IntN(len(list))]
+}
+
+// digits returns n random decimal digits.
+func (f *Faker) digits(n int) string {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = byte('0' + f.rng.IntN(10))
+	}
+	return string(s)
+}
+
+// between returns a random amount from lo to hi dollars, in whole cents.
+func (f *Faker) between(lo, hi float64) Money {
+	return Money(math.Round((lo + f.rng.Float64()*(hi-lo)) * 100))
+}
+
+// Name returns a random full name.
+func (f *Faker) Name() string {
+	name := f.pick(firstNames)
+	if f.rng.IntN(3) == 0 {
+		name += " " + string(rune('A'+f.rng.IntN(26))) + "."
+	}
+	return name + " " + f.pick(lastNames)
+}
+
+// address returns a random two- or three-line street address in p.
+func (f *Faker) address(p place) []string {
+	street := fmt.Sprintf("%d %s %s", 10+f.rng.IntN(9990), f.pick(streetNames), f.pick(streetSuffixes))
+	lines := []string{street}
+	if f.rng.IntN(4) == 0 {
+		lines = append(lines, fmt.Sprintf("Apt %d%c", 1+f.rng.IntN(20), 'A'+f.rng.IntN(4)))
+	}
+	return append(lines, fmt.Sprintf("%s, %s %s%s", p.city, p.state, p.zip3, f.digits(2)))
+}
+
+// Employer returns a random employer.
+func (f *Faker) Employer() Employer {
+	name := f.pick(lastNames) + " " + f.pick(companyKinds) + f.pick(companySuffixes)
+	return Employer{Name: name, Address: f.address(places[f.rng.IntN(len(places))])}
+}
+
+// Employee returns a random employee with a masked SSN.
+func (f *Faker) Employee() Employee {
+	return f.employee(places[f.rng.IntN(len(places))])
+}
+
+// employee returns a random employee living in p.
+func (f *Faker) employee(p place) Employee {
+	return Employee{
+		Name:    f.Name(),
+		Address: f.address(p),
+		ID:      "E" + f.digits(5),
+		SSN:     "XXX-XX-" + f.digits(4),
+	}
+}
+
+// Paystub returns a random biweekly paystub for an hourly or salaried
+// employee, with year-to-date amounts for the periods paid so far.
+func (f *Faker) Paystub() Paystub {
+	period := 1 + f.rng.IntN(payPeriodsPerYear)
+	start := time.Date(fakeYear, time.January, 6, 0, 0, 0, 0, time.UTC)
+	// The year so far is paid as a series, so its totals add up.
+	stubs, _ := f.Series(start, Biweekly, period)
+	return stubs[len(stubs)-1]
+}
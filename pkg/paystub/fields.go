@@ -0,0 +1,78 @@
+package paystub
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// FieldContext is what a FieldFunc makes a value for.
+type FieldContext struct {
+	Stub Paystub // the paystub the value is printed on
+}
+
+// FieldFunc makes up the value of a custom field for ctx, drawing anything
+// random from r, such as a union dues code or a garnishment case number.
+type FieldFunc func(r *rand.Rand, ctx FieldContext) string
+
+var (
+	fieldsMu sync.RWMutex
+	fields   = map[string]FieldFunc{}
+)
+
+// RegisterField adds the custom field name, made up by gen, to the template
+// data of every paystub, so that overlay text templates can print it as
+// {{.Fields.name}}. It is meant to be called from the init function of a
+// program embedding this package, and panics if name is already registered
+// or gen is nil.
+//
+// Each field draws from its own random source, seeded by the paystub and
+// the field's name, so the same paystub always gets the same values and
+// registering a field leaves the values of the others as they were.
+func RegisterField(name string, gen FieldFunc) {
+	fieldsMu.Lock()
+	defer fieldsMu.Unlock()
+	if gen == nil {
+		panic("paystub: RegisterField of " + name + " with a nil FieldFunc")
+	}
+	if _, dup := fields[name]; dup {
+		panic("paystub: RegisterField called twice for field " + name)
+	}
+	fields[name] = gen
+}
+
+// FieldNames returns the names of the registered custom fields, sorted.
+func FieldNames() []string {
+	fieldsMu.RLock()
+	defer fieldsMu.RUnlock()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// customFields returns the value of every registered custom field for s,
+// by name.
+func customFields(s Paystub) map[string]string {
+	fieldsMu.RLock()
+	defer fieldsMu.RUnlock()
+	values := make(map[string]string, len(fields))
+	if len(fields) == 0 {
+		return values
+	}
+	// A Paystub always marshals.
+	data, _ := json.Marshal(s)
+	h := fnv.New64a()
+	h.Write(data)
+	seed := h.Sum64()
+	for name, gen := range fields {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		values[name] = gen(rand.New(rand.NewPCG(seed, h.Sum64())), FieldContext{Stub: s})
+	}
+	return values
+}
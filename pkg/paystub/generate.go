@@ -0,0 +1,445 @@
+package paystub
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// lineSpacing is the height of a text row as a multiple of the font size.
+const lineSpacing = 1.6
+
+// column is one column of a table, sized as a fraction of the content width.
+type column struct {
+	key     string // names the column's cells in overlay fields
+	heading string
+	width   float64
+	right   bool // right-align the cells, as for amounts
+}
+
+// drawer lays out a paystub top to bottom as overlays on blank pages,
+// starting a new page when the next part doesn't fit on this one.
+type drawer struct {
+	l        Layout
+	loc      Locale  // l.Locale
+	page     int     // the page being drawn on, from 1
+	y        float64 // top of the next row, from the page bottom
+	overlays []overlay.OverlayRectText
+	err      error // of the first part too tall for a page
+}
+
+// Generate draws s with layout l on blank pages, as many as it needs, and
+// returns the PDF.
+func Generate(s Paystub, l Layout) ([]byte, error) {
+	overlays, err := Overlays(s, l)
+	if err != nil {
+		return nil, err
+	}
+	return Draw(overlays, l)
+}
+
+// Draw draws overlays, such as those of Overlays with some text changed, on
+// blank pages of layout l, as many as their Pages number, and returns the
+// PDF.
+func Draw(overlays []overlay.OverlayRectText, l Layout) ([]byte, error) {
+	return draw(overlays, l.PageWidth, l.PageHeight)
+}
+
+// draw draws overlays on blank width x height pages, as many as the Pages
+// of the overlays number, and returns the PDF.
+func draw(overlays []overlay.OverlayRectText, width, height float64) ([]byte, error) {
+	pages := 1
+	for _, ov := range overlays {
+		if n, err := strconv.Atoi(ov.Pages); err == nil {
+			pages = max(pages, n)
+		}
+	}
+	var out bytes.Buffer
+	if err := overlay.ApplyOverlays(bytes.NewReader(overlay.BlankPages(width, height, pages)), &out, overlays); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Overlays returns the overlays that draw s with layout l on blank
+// l.PageWidth x l.PageHeight pages, in PDF points from the bottom-left
+// corner. Tables too long for a page go on over the next, under their
+// heading again, and each overlay's Pages numbers its page. Each text
+// overlay's Field names what it shows, such as "employee.name",
+// "earnings[0].current" or "totals.net.ytd".
+func Overlays(s Paystub, l Layout) ([]overlay.OverlayRectText, error) {
+	d, err := newDrawer(l)
+	if err != nil {
+		return nil, err
+	}
+	for i, section := range l.Sections {
+		switch section {
+		case SectionHeader:
+			d.header(s)
+		case SectionParties:
+			d.parties(s)
+		case SectionEarnings:
+			d.earnings(s)
+		case SectionDeductions:
+			d.deductions(s)
+		case SectionTotals:
+			d.totals(s)
+			if i < len(l.Sections)-1 {
+				d.gap()
+			}
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("paystub does not fit on a %gx%g page: section %q: %v", l.PageWidth, l.PageHeight, section, d.err)
+		}
+	}
+	d.finish()
+	return d.overlays, nil
+}
+
+// newDrawer returns a drawer for layout l at its top margin.
+func newDrawer(l Layout) (*drawer, error) {
+	if err := l.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	loc, err := LocaleNamed(l.Locale)
+	if err != nil {
+		return nil, err
+	}
+	return &drawer{l: l, loc: loc, page: 1, y: l.PageHeight - l.Margin}, nil
+}
+
+func (d *drawer) left() float64  { return d.l.Margin }
+func (d *drawer) top() float64   { return d.l.PageHeight - d.l.Margin }
+func (d *drawer) width() float64 { return d.l.PageWidth - 2*d.l.Margin }
+
+// pages returns the page selection of the page being drawn on.
+func (d *drawer) pages() string {
+	return strconv.Itoa(d.page)
+}
+
+// need starts a new page unless there are h points left above the bottom
+// margin of this one, or it is empty. A part taller than a whole page is
+// drawn anyway, into the margin, and d.err says so.
+func (d *drawer) need(h float64) {
+	if d.y-h >= d.l.Margin {
+		return
+	}
+	if d.y < d.top() {
+		d.newPage()
+	}
+	if d.y-h < d.l.Margin && d.err == nil {
+		d.err = fmt.Errorf("it needs %.0f points, %.0f more than fit inside the margins", h, h-(d.top()-d.l.Margin))
+	}
+}
+
+// newPage goes on to the top of the next page, boxing this one first if
+// the layout has a border.
+func (d *drawer) newPage() {
+	if d.l.Border {
+		d.border()
+	}
+	d.page++
+	d.y = d.top()
+}
+
+// finish boxes the last page if the layout has a border and, if there is
+// more than one page, numbers each one at its foot: the field "page".
+func (d *drawer) finish() {
+	if d.l.Border {
+		d.border()
+	}
+	if d.page == 1 {
+		return
+	}
+	last := d.page
+	y := max(d.l.Margin-d.rowHeight(), 0) / 2
+	x := d.left() + d.width()
+	for d.page = 1; d.page <= last; d.page++ {
+		d.text("page", fmt.Sprintf("Page %d of %d", d.page, last), x, y, d.l.FontSize, false, true, false)
+	}
+	d.page = last
+}
+
+// rowHeight returns the height of a row of text at the body font size.
+func (d *drawer) rowHeight() float64 {
+	return float64(d.l.FontSize) * lineSpacing
+}
+
+// gap leaves a blank row between sections.
+func (d *drawer) gap() {
+	d.y -= d.rowHeight()
+}
+
+// text draws s, the value of field, at size points with its left edge at x,
+// or its right edge when right is set, on a row whose bottom is at y. Text
+// on the accent colour is drawn in the layout's AccentTextColor.
+func (d *drawer) text(field, s string, x, y float64, size int, bold, right, onAccent bool) {
+	if s == "" {
+		return
+	}
+	fontName := d.l.Font
+	if bold {
+		fontName = d.l.BoldFont
+	}
+	w := font.TextWidth(s, fontName, size)
+	if right {
+		x -= w
+	}
+	color := ""
+	if onAccent {
+		color = d.l.AccentTextColor
+	}
+	d.overlays = append(d.overlays, overlay.OverlayRectText{
+		Text:      s,
+		Field:     field,
+		X:         x,
+		Y:         y + float64(d.l.FontSize)*(lineSpacing-1)/2,
+		Scale:     1,
+		FontSize:  size,
+		Font:      fontName,
+		TextColor: color,
+		Pages:     d.pages(),
+	})
+}
+
+// fill draws a w x h rectangle with its bottom-left corner at x, y.
+func (d *drawer) fill(x, y, w, h float64, color string) {
+	d.overlays = append(d.overlays, overlay.OverlayRectText{
+		X:         x,
+		Y:         y,
+		Width:     w,
+		Height:    h,
+		Scale:     1,
+		FillColor: color,
+		Pages:     d.pages(),
+	})
+}
+
+// rule draws a thin line across the content width at the current position.
+func (d *drawer) rule() {
+	d.fill(d.left(), d.y, d.width(), 0.75, "#000000")
+}
+
+// rowRule draws a hairline across the content width at the current
+// position if the layout rules every row.
+func (d *drawer) rowRule() {
+	if d.l.RowRules {
+		d.fill(d.left(), d.y, d.width(), 0.5, "#C8C8C8")
+	}
+}
+
+// border draws a box around everything drawn so far on this page, a little
+// outside the content width and inside the page.
+func (d *drawer) border() {
+	pad := min(cellPadding(d.l.FontSize), d.l.Margin)
+	top := d.l.PageHeight - d.l.Margin + pad
+	// The gap after the last table of a page can end in its margin.
+	bottom := max(d.y, d.l.Margin)
+	d.overlays = append(d.overlays, overlay.OverlayRectText{
+		Type:   "rect",
+		X:      d.left() - pad,
+		Y:      bottom - pad,
+		Width:  d.width() + 2*pad,
+		Height: top - bottom + pad,
+		Scale:  1,
+		Pages:  d.pages(),
+	})
+}
+
+// header draws the title band with the pay date and period on its right.
+func (d *drawer) header(s Paystub) {
+	d.band(d.l.Title, d.payDates(s.PayDate, s.PeriodStart, s.PeriodEnd))
+}
+
+// payDates returns the lines of the pay date and, if it is set, the pay
+// period, for the right of the title band.
+func (d *drawer) payDates(pay, start, end Date) []textLine {
+	lines := []textLine{{"payDate", "Pay Date: " + d.loc.Date(pay)}}
+	if !start.IsZero() || !end.IsZero() {
+		lines = append(lines, textLine{"payPeriod", fmt.Sprintf("Pay Period: %s - %s", d.loc.Date(start), d.loc.Date(end))})
+	}
+	return lines
+}
+
+// textLine is one line of text and the field it shows.
+type textLine struct{ field, text string }
+
+// band draws a title band on the accent colour with title on its left and
+// up to two lines of right, right-aligned, on its right.
+func (d *drawer) band(title string, right []textLine) {
+	size := d.l.FontSize
+	h := 2 * d.rowHeight()
+	d.need(h)
+	d.y -= h
+	d.fill(d.left(), d.y, d.width(), h, d.l.AccentColor)
+	d.text("title", title, d.left()+cellPadding(size), d.y+d.rowHeight()/2, size+5, true, false, true)
+	x := d.left() + d.width() - cellPadding(size)
+	for i, l := range right {
+		d.text(l.field, l.text, x, d.y+float64(1-i)*d.rowHeight(), size, false, true, true)
+	}
+	d.gap()
+}
+
+// party returns the lines of a name and address block, the fields
+// field.name and field.address[i].
+func party(field, name string, address []string) []textLine {
+	lines := []textLine{{field + ".name", name}}
+	for i, a := range address {
+		lines = append(lines, textLine{fmt.Sprintf("%s.address[%d]", field, i), a})
+	}
+	return lines
+}
+
+// parties draws the employer block on the left and the employee on the right.
+func (d *drawer) parties(s Paystub) {
+	employee := party("employee", s.Employee.Name, s.Employee.Address)
+	if s.Employee.ID != "" {
+		employee = append(employee, textLine{"employee.id", "Employee ID: " + s.Employee.ID})
+	}
+	if s.Employee.SSN != "" {
+		employee = append(employee, textLine{"employee.ssn", "SSN: " + s.Employee.SSN})
+	}
+	d.blocks(party("employer", s.Employer.Name, s.Employer.Address), employee)
+}
+
+// blocks draws the lines of left on the left half and those of right on the
+// right half, the first line of each in bold.
+func (d *drawer) blocks(left, right []textLine) {
+	d.need(float64(max(len(left), len(right))) * d.rowHeight())
+	top := d.y
+	for i, l := range left {
+		d.text(l.field, l.text, d.left(), top-float64(i+1)*d.rowHeight(), d.l.FontSize, i == 0, false, false)
+	}
+	mid := d.left() + d.width()/2
+	for i, l := range right {
+		d.text(l.field, l.text, mid, top-float64(i+1)*d.rowHeight(), d.l.FontSize, i == 0, false, false)
+	}
+	d.y = top - float64(max(len(left), len(right)))*d.rowHeight()
+	d.gap()
+}
+
+// table draws a heading row on the accent colour and then one row per entry
+// of rows, with a rule below them. Its cells are the fields name.heading.key
+// and name[i].key. A table too long for the page goes on over the next,
+// with its heading again, the first one marked as continued if there is
+// room.
+func (d *drawer) table(name string, cols []column, rows [][]string) {
+	// Keep the heading with at least the first row.
+	d.need(float64(min(len(rows), 1)+1) * d.rowHeight())
+	d.row(name+".heading", cols, headings(cols), true, d.l.AccentColor)
+	for i, cells := range rows {
+		page := d.page
+		if d.need(d.rowHeight()); d.page != page {
+			continued := headings(cols)
+			if h := continued[0] + " (continued)"; font.TextWidth(h, d.l.BoldFont, d.l.FontSize) < cols[0].width*d.width()-2*cellPadding(d.l.FontSize) {
+				continued[0] = h
+			}
+			d.row(name+".heading", cols, continued, true, d.l.AccentColor)
+		}
+		d.row(fmt.Sprintf("%s[%d]", name, i), cols, cells, false, "")
+		if i < len(rows)-1 {
+			d.rowRule()
+		}
+	}
+	d.rule()
+	d.gap()
+}
+
+// row draws one table row below the current position, whose cells are the
+// fields field.key.
+func (d *drawer) row(field string, cols []column, cells []string, bold bool, fillColor string) {
+	d.y -= d.rowHeight()
+	if fillColor != "" {
+		d.fill(d.left(), d.y, d.width(), d.rowHeight(), fillColor)
+	}
+	pad := cellPadding(d.l.FontSize)
+	x := d.left()
+	for i, c := range cols {
+		w := c.width * d.width()
+		if i < len(cells) {
+			if c.right {
+				d.text(field+"."+c.key, cells[i], x+w-pad, d.y, d.l.FontSize, bold, true, fillColor != "")
+			} else {
+				d.text(field+"."+c.key, cells[i], x+pad, d.y, d.l.FontSize, bold, false, fillColor != "")
+			}
+		}
+		x += w
+	}
+}
+
+var earningColumns = []column{
+	{key: "description", heading: "Earnings", width: 0.40},
+	{key: "hours", heading: "Hours", width: 0.15, right: true},
+	{key: "rate", heading: "Rate", width: 0.15, right: true},
+	{key: "current", heading: "Current", width: 0.15, right: true},
+	{key: "ytd", heading: "YTD", width: 0.15, right: true},
+}
+
+var deductionColumns = []column{
+	{key: "description", heading: "Deductions", width: 0.70},
+	{key: "current", heading: "Current", width: 0.15, right: true},
+	{key: "ytd", heading: "YTD", width: 0.15, right: true},
+}
+
+// earnings draws the earnings table.
+func (d *drawer) earnings(s Paystub) {
+	var rows [][]string
+	for _, e := range s.Earnings {
+		rows = append(rows, []string{e.Description, d.hours(e.Hours), d.amount(e.Rate), d.loc.Amount(e.Current()), d.loc.Amount(e.YTD)})
+	}
+	d.table("earnings", earningColumns, rows)
+}
+
+// deductions draws the deductions table.
+func (d *drawer) deductions(s Paystub) {
+	var rows [][]string
+	for _, ded := range s.Deductions {
+		rows = append(rows, []string{ded.Description, d.loc.Amount(ded.Amount), d.loc.Amount(ded.YTD)})
+	}
+	d.table("deductions", deductionColumns, rows)
+}
+
+// totals draws gross pay, total deductions and net pay, lined up with the
+// amount columns of the deductions table.
+func (d *drawer) totals(s Paystub) {
+	d.need(3 * d.rowHeight())
+	d.row("totals.gross", deductionColumns, []string{"Gross Pay", d.loc.Amount(s.Gross()), d.loc.Amount(s.GrossYTD())}, false, "")
+	d.rowRule()
+	d.row("totals.deductions", deductionColumns, []string{"Total Deductions", d.loc.Amount(s.TotalDeductions()), d.loc.Amount(s.TotalDeductionsYTD())}, false, "")
+	d.rule()
+	d.row("totals.net", deductionColumns, []string{"Net Pay", d.loc.Amount(s.Net()), d.loc.Amount(s.NetYTD())}, true, d.l.AccentColor)
+}
+
+// headings returns the headings of cols.
+func headings(cols []column) []string {
+	var hs []string
+	for _, c := range cols {
+		hs = append(hs, c.heading)
+	}
+	return hs
+}
+
+// cellPadding returns the cell padding for text of the given size.
+func cellPadding(size int) float64 {
+	return float64(size) / 2
+}
+
+// hours formats a number of hours, or "" for none.
+func (d *drawer) hours(h float64) string {
+	if h == 0 {
+		return ""
+	}
+	return d.loc.decimal(h, 2, false)
+}
+
+// amount formats a rate or other optional amount, or "" for none.
+func (d *drawer) amount(m Money) string {
+	if m == 0 {
+		return ""
+	}
+	return d.loc.Amount(m)
+}
@@ -0,0 +1,113 @@
+package paystub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Section names, in the order DefaultLayout draws them.
+const (
+	SectionHeader     = "header"     // title band with the pay date and period
+	SectionParties    = "parties"    // employer and employee blocks
+	SectionEarnings   = "earnings"   // earnings table
+	SectionDeductions = "deductions" // deductions table
+	SectionTotals     = "totals"     // gross, deductions and net pay footer
+)
+
+var validSections = []string{SectionHeader, SectionParties, SectionEarnings, SectionDeductions, SectionTotals}
+
+// Layout is the template a Paystub is drawn with. Sections are stacked from
+// the top margin down in the order they are listed, each one full width.
+type Layout struct {
+	PageWidth  float64 `json:"pageWidth"`  // in PDF points
+	PageHeight float64 `json:"pageHeight"` // in PDF points
+	Margin     float64 `json:"margin"`     // on every side, in PDF points
+	// Font and BoldFont are pdfcpu font names for the body text and for
+	// titles, table headings and the net pay.
+	Font     string `json:"font"`
+	BoldFont string `json:"boldFont"`
+	FontSize int    `json:"fontSize"` // body text size in points
+	Title    string `json:"title"`    // printed in the header band
+	// AccentColor fills the header band and table headings, as "#RRGGBB"
+	// or a colour name.
+	AccentColor string `json:"accentColor"`
+	// AccentTextColor is the colour of the text on the accent colour, like
+	// AccentColor; empty means black.
+	AccentTextColor string `json:"accentTextColor"`
+	// RowRules draws a thin rule below every table row, not only below the
+	// last one.
+	RowRules bool `json:"rowRules"`
+	// Border draws a box around everything the sections draw.
+	Border bool `json:"border"`
+	// Locale names the locale amounts and dates are written in, one of
+	// Locales; empty means DefaultLocale.
+	Locale   string   `json:"locale"`
+	Sections []string `json:"sections"`
+}
+
+// DefaultLayout is a US Letter earnings statement with every section.
+var DefaultLayout = Layout{
+	PageWidth:   612,
+	PageHeight:  792,
+	Margin:      36,
+	Font:        "Helvetica",
+	BoldFont:    "Helvetica-Bold",
+	FontSize:    9,
+	Title:       "EARNINGS STATEMENT",
+	AccentColor: "#DDDDDD",
+	Sections:    validSections,
+}
+
+// ErrInvalidLayout is wrapped by the errors of layouts that cannot be decoded
+// or drawn.
+var ErrInvalidLayout = errors.New("invalid layout")
+
+// DecodeLayout reads a Layout from its JSON in r. Fields the JSON leaves out
+// keep their DefaultLayout values.
+func DecodeLayout(r io.Reader) (Layout, error) {
+	return DecodeLayoutFrom(r, DefaultLayout)
+}
+
+// DecodeLayoutFrom reads a Layout from its JSON in r like DecodeLayout, but
+// fields the JSON leaves out keep their values in base, such as one of
+// Layouts.
+func DecodeLayoutFrom(r io.Reader, base Layout) (Layout, error) {
+	l := base
+	l.Sections = append([]string(nil), base.Sections...)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return l, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	if err := l.validate(); err != nil {
+		return l, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	return l, nil
+}
+
+// validate checks that l leaves room to draw on and names a known locale
+// and known sections.
+func (l Layout) validate() error {
+	if l.PageWidth <= 2*l.Margin || l.PageHeight <= 2*l.Margin {
+		return fmt.Errorf("page of %gx%g points leaves no room inside a %g point margin", l.PageWidth, l.PageHeight, l.Margin)
+	}
+	if l.FontSize <= 0 {
+		return fmt.Errorf("fontSize must be positive, got %d", l.FontSize)
+	}
+	if _, err := LocaleNamed(l.Locale); err != nil {
+		return err
+	}
+	for _, s := range l.Sections {
+		known := false
+		for _, v := range validSections {
+			known = known || s == v
+		}
+		if !known {
+			return fmt.Errorf("unknown section %q (valid: %s)", s, strings.Join(validSections, ", "))
+		}
+	}
+	return nil
+}
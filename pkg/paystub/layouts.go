@@ -0,0 +1,91 @@
+package paystub
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Layouts are the built-in layouts by name. Each approximates the look of
+// the earnings statements of a kind of payroll provider, so that documents
+// of several styles can be made without designing layouts by hand.
+var Layouts = map[string]Layout{
+	// A dense, boxed statement in small type with pale green headings,
+	// like those of ADP.
+	"adp": {
+		PageWidth:   612,
+		PageHeight:  792,
+		Margin:      30,
+		Font:        "Helvetica",
+		BoldFont:    "Helvetica-Bold",
+		FontSize:    8,
+		Title:       "Earnings Statement",
+		AccentColor: "#DCE8D4",
+		Border:      true,
+		Sections:    []string{SectionHeader, SectionParties, SectionEarnings, SectionDeductions, SectionTotals},
+	},
+	// An airy statement with white text on a bright accent, ruled rows and
+	// the totals ahead of the details, like those of Gusto.
+	"gusto": {
+		PageWidth:       612,
+		PageHeight:      792,
+		Margin:          48,
+		Font:            "Helvetica",
+		BoldFont:        "Helvetica-Bold",
+		FontSize:        10,
+		Title:           "Pay Stub",
+		AccentColor:     "#F45D48",
+		AccentTextColor: "#FFFFFF",
+		RowRules:        true,
+		Sections:        []string{SectionHeader, SectionParties, SectionTotals, SectionEarnings, SectionDeductions},
+	},
+	// A boxed statement with white text on dark blue, like those of
+	// Paychex.
+	"paychex": {
+		PageWidth:       612,
+		PageHeight:      792,
+		Margin:          36,
+		Font:            "Helvetica",
+		BoldFont:        "Helvetica-Bold",
+		FontSize:        9,
+		Title:           "EARNINGS STATEMENT",
+		AccentColor:     "#1B4F8C",
+		AccentTextColor: "#FFFFFF",
+		RowRules:        true,
+		Border:          true,
+		Sections:        []string{SectionHeader, SectionParties, SectionEarnings, SectionDeductions, SectionTotals},
+	},
+	// A plain typewriter-style stub with ruled rows and no colour, like
+	// those small businesses print from accounting software.
+	"generic": {
+		PageWidth:   612,
+		PageHeight:  792,
+		Margin:      42,
+		Font:        "Courier",
+		BoldFont:    "Courier-Bold",
+		FontSize:    9,
+		Title:       "PAY STUB",
+		AccentColor: "#FFFFFF",
+		RowRules:    true,
+		Sections:    []string{SectionParties, SectionHeader, SectionEarnings, SectionDeductions, SectionTotals},
+	},
+}
+
+// LayoutNames returns the names of the built-in Layouts, sorted.
+func LayoutNames() []string {
+	names := make([]string, 0, len(Layouts))
+	for name := range Layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LayoutNamed returns the built-in layout called name.
+func LayoutNamed(name string) (Layout, error) {
+	l, ok := Layouts[name]
+	if !ok {
+		return Layout{}, fmt.Errorf("unknown layout %q (valid: %s)", name, strings.Join(LayoutNames(), ", "))
+	}
+	return l, nil
+}
@@ -0,0 +1,205 @@
+package paystub
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Locale says how amounts, percentages and dates are written in a country.
+type Locale struct {
+	DecimalMark string // decimal separator
+	GroupMark   string // thousands separator
+	Symbol      string // currency symbol
+	// SymbolAfter writes the symbol after the amount, with a space
+	// between, as in "1.234,56 €".
+	SymbolAfter bool
+	// PercentSpace puts a space before the percent sign, as in "6,2 %".
+	PercentSpace bool
+	DateLayout   string // Go time layout of short dates
+	// LongDateLayout is the Go time layout of dates with the month spelled
+	// out, "January" standing for the name in Months.
+	LongDateLayout string
+	Months         [12]string
+}
+
+var englishMonths = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+// Locales are the built-in locales by name.
+var Locales = map[string]Locale{
+	"en-US": {DecimalMark: ".", GroupMark: ",", Symbol: "$", DateLayout: "01/02/2006", LongDateLayout: "January 2, 2006", Months: englishMonths},
+	"en-GB": {DecimalMark: ".", GroupMark: ",", Symbol: "£", DateLayout: "02/01/2006", LongDateLayout: "2 January 2006", Months: englishMonths},
+	"en-CA": {DecimalMark: ".", GroupMark: ",", Symbol: "$", DateLayout: "2006-01-02", LongDateLayout: "January 2, 2006", Months: englishMonths},
+	"en-AU": {DecimalMark: ".", GroupMark: ",", Symbol: "$", DateLayout: "02/01/2006", LongDateLayout: "2 January 2006", Months: englishMonths},
+	"de-DE": {DecimalMark: ",", GroupMark: ".", Symbol: "€", SymbolAfter: true, PercentSpace: true, DateLayout: "02.01.2006", LongDateLayout: "2. January 2006",
+		Months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}},
+	"fr-FR": {DecimalMark: ",", GroupMark: " ", Symbol: "€", SymbolAfter: true, PercentSpace: true, DateLayout: "02/01/2006", LongDateLayout: "2 January 2006",
+		Months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}},
+	"es-ES": {DecimalMark: ",", GroupMark: ".", Symbol: "€", SymbolAfter: true, PercentSpace: true, DateLayout: "02/01/2006", LongDateLayout: "2 de January de 2006",
+		Months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}},
+	"it-IT": {DecimalMark: ",", GroupMark: ".", Symbol: "€", SymbolAfter: true, DateLayout: "02/01/2006", LongDateLayout: "2 January 2006",
+		Months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"}},
+}
+
+// DefaultLocale is the locale of layouts that name none.
+const DefaultLocale = "en-US"
+
+// LocaleNames returns the names of the built-in Locales, sorted.
+func LocaleNames() []string {
+	names := make([]string, 0, len(Locales))
+	for name := range Locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LocaleNamed returns the built-in locale called name. A BCP 47 language
+// tag without a region, such as "en" or "de", is the closest locale of its
+// language, en-US and de-DE; "" is DefaultLocale.
+func LocaleNamed(name string) (Locale, error) {
+	if name == "" {
+		name = DefaultLocale
+	}
+	if l, ok := Locales[name]; ok {
+		return l, nil
+	}
+	names := LocaleNames()
+	// The default comes first, so it is the match of a language it speaks
+	// that has no region.
+	tags := []language.Tag{language.MustParse(DefaultLocale)}
+	for _, n := range names {
+		tags = append(tags, language.MustParse(n))
+	}
+	// Another region would need its own currency and date order.
+	tag, err := language.Parse(name)
+	if l, ok := Locales[tag.String()]; err == nil && ok {
+		return l, nil
+	}
+	if _, region := tag.Region(); err == nil && region != language.Exact {
+		if _, i, conf := language.NewMatcher(tags).Match(tag); conf >= language.High {
+			return Locales[tags[i].String()], nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unknown locale %q (valid: %s)", name, strings.Join(names, ", "))
+}
+
+// number writes the digits of whole with thousands separators, and then
+// frac, if any, after the decimal separator.
+func (l Locale) number(whole uint64, frac string) string {
+	s := strconv.FormatUint(whole, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + l.GroupMark + s[i:]
+	}
+	if frac != "" {
+		s += l.DecimalMark + frac
+	}
+	return s
+}
+
+// Amount formats m without a currency symbol, e.g. "-1,234.56" or
+// "-1.234,56".
+func (l Locale) Amount(m Money) string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return sign + l.number(uint64(m/100), fmt.Sprintf("%02d", m%100))
+}
+
+// Currency formats m with the currency symbol, e.g. "-$1,234.56" or
+// "-1.234,56 €".
+func (l Locale) Currency(m Money) string {
+	if l.SymbolAfter {
+		return l.Amount(m) + " " + l.Symbol
+	}
+	if m < 0 {
+		return "-" + l.Symbol + l.Amount(-m)
+	}
+	return l.Symbol + l.Amount(m)
+}
+
+// decimal formats v with places decimals, dropping trailing zeros if trim
+// is set.
+func (l Locale) decimal(v float64, places int, trim bool) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', places, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	if trim {
+		frac = strings.TrimRight(frac, "0")
+	}
+	n, _ := strconv.ParseUint(whole, 10, 64)
+	sign := ""
+	if v < 0 && strings.Trim(whole+frac, "0") != "" {
+		sign = "-"
+	}
+	return sign + l.number(n, frac)
+}
+
+// Percent formats rate, a fraction such as 0.062, as a percentage with at
+// most two decimals, e.g. "6.2%" or "6,2 %".
+func (l Locale) Percent(rate float64) string {
+	s := l.decimal(rate*100, 2, true)
+	if l.PercentSpace {
+		return s + " %"
+	}
+	return s + "%"
+}
+
+// Date formats d as a short date, e.g. "01/19/2025" or "19.01.2025", or
+// "" when d is unset.
+func (l Locale) Date(d Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(l.DateLayout)
+}
+
+// LongDate formats d with the month spelled out, e.g. "January 19, 2025"
+// or "19. Januar 2025", or "" when d is unset.
+func (l Locale) LongDate(d Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return strings.Replace(d.Format(l.LongDateLayout), englishMonths[d.Month()-1], l.Months[d.Month()-1], 1)
+}
+
+// TemplateFuncs returns the functions overlay text templates can call on
+// paystub values, formatting as l does, as in {{.NetPay | currency}}.
+func (l Locale) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// currency formats an amount with the currency symbol, e.g. "$1,234.56".
+		"currency": l.Currency,
+		// amount formats an amount without it, e.g. "1,234.56".
+		"amount": l.Amount,
+		// percent formats a fraction as a percentage, e.g. {{percent 0.062}} is "6.2%".
+		"percent": l.Percent,
+		// date formats a date as a short date, e.g. {{date .PayDate}}, or
+		// with a Go time layout, e.g. {{date "Jan 2, 2006" .PayDate}}.
+		"date": func(args ...any) (string, error) {
+			switch len(args) {
+			case 1:
+				if d, ok := args[0].(Date); ok {
+					return l.Date(d), nil
+				}
+			case 2:
+				layout, ok := args[0].(string)
+				d, ok2 := args[1].(Date)
+				if ok && ok2 {
+					if d.IsZero() {
+						return "", nil
+					}
+					return d.Format(layout), nil
+				}
+			}
+			return "", fmt.Errorf("date wants a date, or a layout and a date")
+		},
+		// longdate formats a date with the month spelled out, e.g. "January 19, 2025".
+		"longdate": l.LongDate,
+		"upper":    strings.ToUpper,
+	}
+}
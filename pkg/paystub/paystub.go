@@ -0,0 +1,202 @@
+// Package paystub builds paystub PDFs from scratch: a Paystub holds the data
+// of one pay statement and a Layout says how to lay it out on the page.
+package paystub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Money is an amount in cents. In JSON it is a number of dollars, such as
+// 1234.56.
+type Money int64
+
+// Dollars returns m in whole dollars and cents as a float.
+func (m Money) Dollars() float64 {
+	return float64(m) / 100
+}
+
+// String formats m as dollars with thousands separators, e.g. "-1,234.56".
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	whole := strconv.FormatInt(int64(m/100), 10)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, whole, m%100)
+}
+
+// MarshalJSON writes m as a number of dollars.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(m.Dollars(), 'f', 2, 64)), nil
+}
+
+// UnmarshalJSON reads a number of dollars, rounding it to whole cents.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid amount %s: want a number of dollars", data)
+	}
+	*m = Money(math.Round(v * 100))
+	return nil
+}
+
+// dateLayout is how dates are written in JSON and on the stub.
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day. In JSON it is written as "YYYY-MM-DD".
+type Date struct {
+	time.Time
+}
+
+// String formats d as MM/DD/YYYY, or "" when d is unset.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format("01/02/2006")
+}
+
+// MarshalJSON writes d as "YYYY-MM-DD".
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(d.Format(dateLayout))
+}
+
+// UnmarshalJSON reads a "YYYY-MM-DD" date; "" leaves d unset.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid date %s: want \"YYYY-MM-DD\"", data)
+	}
+	if s == "" {
+		*d = Date{}
+		return nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: want \"YYYY-MM-DD\"", s)
+	}
+	*d = Date{t}
+	return nil
+}
+
+// Employer is the company issuing the stub.
+type Employer struct {
+	Name    string   `json:"name"`
+	Address []string `json:"address"` // one entry per line
+}
+
+// Employee is the person being paid.
+type Employee struct {
+	Name    string   `json:"name"`
+	Address []string `json:"address"` // one entry per line
+	ID      string   `json:"id"`
+	SSN     string   `json:"ssn"` // printed as given, so mask it beforehand
+}
+
+// Earning is one line of the earnings table.
+type Earning struct {
+	Description string  `json:"description"`
+	Hours       float64 `json:"hours"`
+	Rate        Money   `json:"rate"`
+	// Amount is the current amount; 0 means Hours times Rate.
+	Amount Money `json:"amount"`
+	YTD    Money `json:"ytd"`
+}
+
+// Current returns the current amount of e.
+func (e Earning) Current() Money {
+	if e.Amount != 0 {
+		return e.Amount
+	}
+	return Money(math.Round(e.Hours * float64(e.Rate)))
+}
+
+// Deduction is one line of the deductions table: a tax or other withholding.
+type Deduction struct {
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"`
+	YTD         Money  `json:"ytd"`
+}
+
+// Paystub is the data of one pay statement.
+type Paystub struct {
+	Employer    Employer    `json:"employer"`
+	Employee    Employee    `json:"employee"`
+	PayDate     Date        `json:"payDate"`
+	PeriodStart Date        `json:"periodStart"`
+	PeriodEnd   Date        `json:"periodEnd"`
+	Earnings    []Earning   `json:"earnings"`
+	Deductions  []Deduction `json:"deductions"`
+}
+
+// Gross returns the total of the current earnings.
+func (s Paystub) Gross() Money {
+	var total Money
+	for _, e := range s.Earnings {
+		total += e.Current()
+	}
+	return total
+}
+
+// GrossYTD returns the total of the year-to-date earnings.
+func (s Paystub) GrossYTD() Money {
+	var total Money
+	for _, e := range s.Earnings {
+		total += e.YTD
+	}
+	return total
+}
+
+// TotalDeductions returns the total of the current deductions.
+func (s Paystub) TotalDeductions() Money {
+	var total Money
+	for _, d := range s.Deductions {
+		total += d.Amount
+	}
+	return total
+}
+
+// TotalDeductionsYTD returns the total of the year-to-date deductions.
+func (s Paystub) TotalDeductionsYTD() Money {
+	var total Money
+	for _, d := range s.Deductions {
+		total += d.YTD
+	}
+	return total
+}
+
+// Net returns the current net pay: gross minus deductions.
+func (s Paystub) Net() Money {
+	return s.Gross() - s.TotalDeductions()
+}
+
+// NetYTD returns the year-to-date net pay.
+func (s Paystub) NetYTD() Money {
+	return s.GrossYTD() - s.TotalDeductionsYTD()
+}
+
+// DecodePaystub reads a Paystub from its JSON in r, rejecting unknown fields.
+func DecodePaystub(r io.Reader) (Paystub, error) {
+	var s Paystub
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return s, err
+	}
+	if strings.TrimSpace(s.Employee.Name) == "" {
+		return s, fmt.Errorf("employee name is required")
+	}
+	return s, nil
+}
@@ -0,0 +1,188 @@
+package paystub
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNet checks the totals of hand-made stubs: the net pay is the gross
+// less the taxes and other deductions, this period and for the year.
+func TestNet(t *testing.T) {
+	tests := []struct {
+		name                  string
+		earnings              []Earning
+		deductions            []Deduction
+		gross, deducted, net  Money
+		grossYTD, deductedYTD Money
+		netYTD                Money
+	}{
+		{name: "nothing"},
+		{
+			name:     "hours times rate",
+			earnings: []Earning{{Description: "Regular", Hours: 80, Rate: 2500, YTD: 2000000}},
+			deductions: []Deduction{
+				{Description: "Federal Income Tax", Amount: 24000, YTD: 240000},
+				{Description: "Social Security", Amount: 12400, YTD: 124000},
+				{Description: "Medicare", Amount: 2900, YTD: 29000},
+				{Description: "401(k)", Amount: 10000, YTD: 100000},
+			},
+			gross: 200000, deducted: 49300, net: 150700,
+			grossYTD: 2000000, deductedYTD: 493000, netYTD: 1507000,
+		},
+		{
+			// 7.5 × 20.33 is 152.475, which rounds to 152.48.
+			name:     "hours times rate rounded to the cent",
+			earnings: []Earning{{Description: "Overtime", Hours: 7.5, Rate: 2033, YTD: 15248}},
+			gross:    15248, net: 15248, grossYTD: 15248, netYTD: 15248,
+		},
+		{
+			name: "amount over hours times rate",
+			earnings: []Earning{
+				{Description: "Salary", Hours: 80, Rate: 1, Amount: 384615, YTD: 769230},
+				{Description: "Bonus", Amount: 50000, YTD: 50000},
+			},
+			deductions: []Deduction{{Description: "Health Insurance", Amount: 12000, YTD: 24000}},
+			gross:      434615, deducted: 12000, net: 422615,
+			grossYTD: 819230, deductedYTD: 24000, netYTD: 795230,
+		},
+		{
+			name: "refund and deductions over the gross",
+			earnings: []Earning{
+				{Description: "Regular", Amount: 10000, YTD: 10000},
+				{Description: "Correction", Amount: -2500, YTD: -2500},
+			},
+			deductions: []Deduction{
+				{Description: "Garnishment", Amount: 9000, YTD: 9000},
+				{Description: "Federal Income Tax", Amount: -1000, YTD: -1000},
+			},
+			gross: 7500, deducted: 8000, net: -500,
+			grossYTD: 7500, deductedYTD: 8000, netYTD: -500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Paystub{Earnings: tt.earnings, Deductions: tt.deductions}
+			for _, c := range []struct {
+				what      string
+				got, want Money
+			}{
+				{"Gross", s.Gross(), tt.gross},
+				{"TotalDeductions", s.TotalDeductions(), tt.deducted},
+				{"Net", s.Net(), tt.net},
+				{"GrossYTD", s.GrossYTD(), tt.grossYTD},
+				{"TotalDeductionsYTD", s.TotalDeductionsYTD(), tt.deductedYTD},
+				{"NetYTD", s.NetYTD(), tt.netYTD},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s = %v, want %v", c.what, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// isTax reports whether a Faker deduction described desc is a tax, as
+// Rates.Withhold names them, rather than a deduction such as a 401(k).
+func isTax(desc string) bool {
+	switch desc {
+	case "Federal Income Tax", "Social Security", "Medicare":
+		return true
+	}
+	return strings.HasSuffix(desc, " State Income Tax")
+}
+
+// TestSeriesYTD checks that in a series every stub's net pay is its gross
+// less its taxes and other deductions, and that each year-to-date amount
+// is the total of its line over the stubs paid so far in the year of its
+// pay date, starting again each January.
+func TestSeriesYTD(t *testing.T) {
+	tests := []struct {
+		name  string
+		seed  uint64
+		freq  Frequency
+		start string
+		n     int
+		years int // calendar years the pay dates, 5 days after each period, fall in
+	}{
+		{name: "biweekly year", seed: 1, freq: Biweekly, start: "2025-01-06", n: 25, years: 1},
+		{name: "biweekly paid into next year", seed: 6, freq: Biweekly, start: "2025-01-06", n: 26, years: 2},
+		{name: "weekly across new year", seed: 2, freq: Weekly, start: "2025-11-03", n: 12, years: 2},
+		{name: "semimonthly from the 16th", seed: 3, freq: Semimonthly, start: "2025-08-16", n: 12, years: 2},
+		{name: "monthly over two years", seed: 4, freq: Monthly, start: "2025-01-01", n: 24, years: 3},
+		{name: "one stub", seed: 5, freq: Biweekly, start: "2025-06-02", n: 1, years: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, err := time.Parse(dateLayout, tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Check a few employees, to see both hourly and salaried pay.
+			for seed := tt.seed; seed < tt.seed+20; seed++ {
+				stubs, err := NewFaker(seed).Series(start, tt.freq, tt.n)
+				if err != nil {
+					t.Fatalf("Series: %v", err)
+				}
+				if len(stubs) != tt.n {
+					t.Fatalf("%d stubs, want %d", len(stubs), tt.n)
+				}
+				checkSeries(t, seed, stubs, tt.years)
+			}
+		})
+	}
+}
+
+// checkSeries checks the stubs of a series of seed like TestSeriesYTD, and
+// that their pay dates fall in years calendar years.
+func checkSeries(t *testing.T, seed uint64, stubs []Paystub, years int) {
+	t.Helper()
+	year := 0
+	seen := 0
+	var paid map[string]Money // totals so far this year, by line
+	var grossYTD, netYTD Money
+	for i, s := range stubs {
+		if y := s.PayDate.Year(); y != year {
+			year, seen = y, seen+1
+			paid, grossYTD, netYTD = map[string]Money{}, 0, 0
+		}
+		if i > 0 && !s.PeriodStart.After(stubs[i-1].PeriodEnd.Time) {
+			t.Errorf("seed %d, stub %d: period starts %v, before the last ended", seed, i, s.PeriodStart)
+		}
+
+		var gross, taxes, other Money
+		for _, e := range s.Earnings {
+			gross += e.Current()
+			paid["earning "+e.Description] += e.Current()
+			if want := paid["earning "+e.Description]; e.YTD != want {
+				t.Errorf("seed %d, stub %d: %s YTD %v, want %v", seed, i, e.Description, e.YTD, want)
+			}
+		}
+		for _, d := range s.Deductions {
+			if isTax(d.Description) {
+				taxes += d.Amount
+			} else {
+				other += d.Amount
+			}
+			paid["deduction "+d.Description] += d.Amount
+			if want := paid["deduction "+d.Description]; d.YTD != want {
+				t.Errorf("seed %d, stub %d: %s YTD %v, want %v", seed, i, d.Description, d.YTD, want)
+			}
+		}
+		if taxes <= 0 {
+			t.Errorf("seed %d, stub %d: no taxes withheld from %v", seed, i, gross)
+		}
+		net := gross - taxes - other
+		if s.Net() != net || net <= 0 {
+			t.Errorf("seed %d, stub %d: net %v, want gross %v less taxes %v and deductions %v = %v", seed, i, s.Net(), gross, taxes, other, net)
+		}
+		grossYTD += gross
+		netYTD += net
+		if s.GrossYTD() != grossYTD || s.NetYTD() != netYTD {
+			t.Errorf("seed %d, stub %d: YTD gross %v and net %v, want %v and %v", seed, i, s.GrossYTD(), s.NetYTD(), grossYTD, netYTD)
+		}
+	}
+	if seen != years {
+		t.Errorf("seed %d: pay dates in %d years, want %d", seed, seen, years)
+	}
+}
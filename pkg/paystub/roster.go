@@ -0,0 +1,198 @@
+package paystub
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Roster columns. Headers are matched ignoring case and surrounding space;
+// only name is required. Addresses separate their lines with "|".
+const (
+	colName            = "name"
+	colID              = "id"
+	colSSN             = "ssn"
+	colAddress         = "address"
+	colEmployer        = "employer"
+	colEmployerAddress = "employer_address"
+	colPayDate         = "pay_date"     // YYYY-MM-DD
+	colPeriodStart     = "period_start" // YYYY-MM-DD
+	colPeriodEnd       = "period_end"   // YYYY-MM-DD
+	colRate            = "rate"         // hourly rate in dollars, paid for hours
+	colHours           = "hours"
+	colSalary          = "salary" // salary for the period in dollars
+	colPeriod          = "period" // pay periods so far this year, including this one
+)
+
+// deductionPrefix starts the header of a deduction column, as in
+// "deduction:Federal Income Tax"; the rest is the deduction's description.
+const deductionPrefix = "deduction:"
+
+var rosterColumns = []string{
+	colName, colID, colSSN, colAddress, colEmployer, colEmployerAddress,
+	colPayDate, colPeriodStart, colPeriodEnd, colRate, colHours, colSalary, colPeriod,
+}
+
+// DecodeRoster reads one Paystub per row of the CSV roster in r, whose first
+// row holds the column headers. Each employee is paid hours at rate, a
+// salary, or both. Deduction columns that are empty in a row are left out of
+// its stub. Year-to-date amounts are the current ones times period, which
+// defaults to 1.
+func DecodeRoster(r io.Reader) ([]Paystub, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("roster is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	var deductions []int
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if strings.HasPrefix(h, deductionPrefix) {
+			deductions = append(deductions, i)
+			continue
+		}
+		known := false
+		for _, c := range rosterColumns {
+			known = known || h == c
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown roster column %q (valid: %s, or %s<description>)",
+				header[i], strings.Join(rosterColumns, ", "), deductionPrefix)
+		}
+		cols[h] = i
+	}
+	if _, ok := cols[colName]; !ok {
+		return nil, fmt.Errorf("roster has no %s column", colName)
+	}
+
+	var stubs []Paystub
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s, err := rosterRow(row, cols, header, deductions)
+		if err != nil {
+			return nil, fmt.Errorf("roster line %d: %v", line, err)
+		}
+		stubs = append(stubs, s)
+	}
+	if len(stubs) == 0 {
+		return nil, fmt.Errorf("roster has no employees")
+	}
+	return stubs, nil
+}
+
+// rosterRow returns the Paystub of one roster row.
+func rosterRow(row []string, cols map[string]int, header []string, deductions []int) (Paystub, error) {
+	get := func(col string) string {
+		if i, ok := cols[col]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	lines := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		parts := strings.Split(s, "|")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts
+	}
+
+	s := Paystub{
+		Employer: Employer{Name: get(colEmployer), Address: lines(get(colEmployerAddress))},
+		Employee: Employee{Name: get(colName), Address: lines(get(colAddress)), ID: get(colID), SSN: get(colSSN)},
+	}
+	if s.Employee.Name == "" {
+		return s, fmt.Errorf("%s is required", colName)
+	}
+	for _, d := range []struct {
+		col string
+		to  *Date
+	}{{colPayDate, &s.PayDate}, {colPeriodStart, &s.PeriodStart}, {colPeriodEnd, &s.PeriodEnd}} {
+		v := get(d.col)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return s, fmt.Errorf("invalid %s %q: want YYYY-MM-DD", d.col, v)
+		}
+		*d.to = Date{t}
+	}
+
+	period := 1
+	if v := get(colPeriod); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return s, fmt.Errorf("invalid %s %q: want a whole number from 1", colPeriod, v)
+		}
+		period = n
+	}
+	ytd := func(m Money) Money { return m * Money(period) }
+
+	rate, err := parseMoney(colRate, get(colRate))
+	if err != nil {
+		return s, err
+	}
+	if rate != 0 {
+		h := get(colHours)
+		hours, err := strconv.ParseFloat(h, 64)
+		if err != nil {
+			return s, fmt.Errorf("invalid %s %q: want a number to pay %s for", colHours, h, colRate)
+		}
+		e := Earning{Description: "Regular", Hours: hours, Rate: rate}
+		e.YTD = ytd(e.Current())
+		s.Earnings = append(s.Earnings, e)
+	}
+	salary, err := parseMoney(colSalary, get(colSalary))
+	if err != nil {
+		return s, err
+	}
+	if salary != 0 {
+		s.Earnings = append(s.Earnings, Earning{Description: "Salary", Amount: salary, YTD: ytd(salary)})
+	}
+	if len(s.Earnings) == 0 {
+		return s, fmt.Errorf("needs a %s and %s or a %s", colRate, colHours, colSalary)
+	}
+
+	for _, i := range deductions {
+		desc := strings.TrimSpace(strings.TrimSpace(header[i])[len(deductionPrefix):])
+		m, err := parseMoney(header[i], strings.TrimSpace(row[i]))
+		if err != nil {
+			return s, err
+		}
+		if m != 0 {
+			s.Deductions = append(s.Deductions, Deduction{Description: desc, Amount: m, YTD: ytd(m)})
+		}
+	}
+	return s, nil
+}
+
+// parseMoney reads an amount of dollars such as "1,234.56" or "$1234.56"
+// from the named column; "" is 0.
+func parseMoney(col, v string) (Money, error) {
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: want an amount in dollars", col, v)
+	}
+	return Money(math.Round(f * 100)), nil
+}
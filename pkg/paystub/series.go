@@ -0,0 +1,238 @@
+package paystub
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Frequency is how often an employee is paid.
+type Frequency string
+
+// Pay frequencies.
+const (
+	Weekly      Frequency = "weekly"
+	Biweekly    Frequency = "biweekly"
+	Semimonthly Frequency = "semimonthly" // the 1st to the 15th and the 16th to the month's end
+	Monthly     Frequency = "monthly"
+)
+
+var validFrequencies = []string{string(Weekly), string(Biweekly), string(Semimonthly), string(Monthly)}
+
+// ParseFrequency returns the Frequency named s.
+func ParseFrequency(s string) (Frequency, error) {
+	for _, f := range validFrequencies {
+		if s == f {
+			return Frequency(s), nil
+		}
+	}
+	return "", fmt.Errorf("invalid frequency %q (valid: %s)", s, strings.Join(validFrequencies, ", "))
+}
+
+// PeriodsPerYear returns the number of pay periods f has in a year.
+func (f Frequency) PeriodsPerYear() int {
+	switch f {
+	case Weekly:
+		return 52
+	case Semimonthly:
+		return 24
+	case Monthly:
+		return 12
+	}
+	return 26
+}
+
+// period returns the first and last day of pay period i of a series
+// starting on start.
+func (f Frequency) period(start time.Time, i int) (time.Time, time.Time) {
+	switch f {
+	case Weekly:
+		s := start.AddDate(0, 0, 7*i)
+		return s, s.AddDate(0, 0, 6)
+	case Semimonthly:
+		// Start is the 1st or the 16th; count half months from there.
+		half := i
+		if start.Day() == 16 {
+			half++
+		}
+		first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, half/2, 0)
+		if half%2 == 0 {
+			return first, first.AddDate(0, 0, 14)
+		}
+		return first.AddDate(0, 0, 15), first.AddDate(0, 1, -1)
+	case Monthly:
+		s := start.AddDate(0, i, 0)
+		return s, start.AddDate(0, i+1, -1)
+	}
+	s := start.AddDate(0, 0, 14*i)
+	return s, s.AddDate(0, 0, 13)
+}
+
+// checkStart checks that a series paid at f can start on start.
+func (f Frequency) checkStart(start time.Time) error {
+	switch {
+	case f == Semimonthly && start.Day() != 1 && start.Day() != 16:
+		return fmt.Errorf("a semimonthly series must start on the 1st or the 16th, not %s", start.Format(dateLayout))
+	case f == Monthly && start.Day() > 28:
+		return fmt.Errorf("a monthly series must start on or before the 28th, not %s", start.Format(dateLayout))
+	}
+	return nil
+}
+
+// payDelay is how many days after the end of a period Faker pays it.
+const payDelay = 5
+
+// terms are the pay terms of a fake employee, which stay the same across
+// the stubs of a series.
+type terms struct {
+	employer   Employer
+	employee   Employee
+	state      string
+	salary     Money   // per year; 0 for an hourly employee
+	rate       Money   // per hour
+	retirement float64 // 401(k) contribution rate, 0 for none
+	health     Money   // health insurance per biweekly period, 0 for none
+}
+
+// terms returns random pay terms.
+func (f *Faker) terms() terms {
+	home := places[f.rng.IntN(len(places))]
+	t := terms{employer: f.Employer(), employee: f.employee(home), state: home.state}
+	if f.rng.IntN(3) == 0 {
+		t.salary = f.between(40000, 180000)
+	} else {
+		t.rate = f.between(15, 65)
+	}
+	if f.rng.IntN(2) == 0 {
+		t.retirement = float64(2+f.rng.IntN(7)) / 100
+	}
+	if f.rng.IntN(3) > 0 {
+		t.health = f.between(40, 260)
+	}
+	return t
+}
+
+// Series returns n consecutive paystubs for one random employee paid at
+// freq, the first for the period starting on start. Year-to-date amounts
+// add up the stubs of the series paid so far in the calendar year of each
+// pay date, so every stub agrees with the ones before it.
+func (f *Faker) Series(start time.Time, freq Frequency, n int) ([]Paystub, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("a series needs at least one period, got %d", n)
+	}
+	if err := freq.checkStart(start); err != nil {
+		return nil, err
+	}
+	t := f.terms()
+	perYear := freq.PeriodsPerYear()
+	// Regular hours are 40 a week, spread over the periods of a year.
+	regularHours := math.Round(40*52/float64(perYear)*100) / 100
+
+	var stubs []Paystub
+	var earnings ytdLines
+	var deductions ytdLines
+	year := 0
+	for i := range n {
+		ps, pe := freq.period(start, i)
+		pay := pe.AddDate(0, 0, payDelay)
+		if pay.Year() != year {
+			year = pay.Year()
+			earnings, deductions = ytdLines{}, ytdLines{}
+		}
+		prior := earnings.ytd()
+
+		if t.salary != 0 {
+			earnings.add("Salary", Money(math.Round(float64(t.salary)/float64(perYear))))
+		} else {
+			earnings.add("Regular", Money(math.Round(regularHours*float64(t.rate))))
+			if f.rng.IntN(5) < 2 {
+				ot := float64(1+f.rng.IntN(40)) / 4
+				earnings.add("Overtime", Money(math.Round(ot*float64(t.rate)*1.5)))
+			}
+		}
+		gross := earnings.current()
+		retirement := Money(math.Round(float64(gross) * t.retirement))
+		taxes := f.Rates.Withhold(Wages{Gross: gross, PreTax: retirement, PriorYTD: prior, PeriodsPerYear: perYear, State: t.state})
+		for _, d := range taxes {
+			deductions.add(d.Description, d.Amount)
+		}
+		if retirement != 0 {
+			deductions.add("401(k)", retirement)
+		}
+		if t.health != 0 {
+			deductions.add("Health Insurance", Money(math.Round(float64(t.health)*payPeriodsPerYear/float64(perYear))))
+		}
+
+		s := Paystub{
+			Employer:    t.employer,
+			Employee:    t.employee,
+			PayDate:     Date{pay},
+			PeriodStart: Date{ps},
+			PeriodEnd:   Date{pe},
+		}
+		for _, l := range earnings {
+			e := Earning{Description: l.description, Amount: l.amount, YTD: l.ytd}
+			if l.description == "Regular" && l.amount != 0 {
+				e.Hours, e.Rate = regularHours, t.rate
+			}
+			s.Earnings = append(s.Earnings, e)
+		}
+		for _, l := range deductions {
+			s.Deductions = append(s.Deductions, Deduction{Description: l.description, Amount: l.amount, YTD: l.ytd})
+		}
+		stubs = append(stubs, s)
+		earnings.next()
+		deductions.next()
+	}
+	return stubs, nil
+}
+
+// ytdLine is one line of a stub with its amount this period and its total
+// for the year so far, this period included.
+type ytdLine struct {
+	description string
+	amount, ytd Money
+}
+
+// ytdLines are the lines paid so far in a year, in the order they first
+// appeared. Lines not paid this period stay with a zero amount, so the
+// year-to-date totals of a stub always add up.
+type ytdLines []ytdLine
+
+// add pays m on the line described by desc this period.
+func (ls *ytdLines) add(desc string, m Money) {
+	for i := range *ls {
+		if (*ls)[i].description == desc {
+			(*ls)[i].amount += m
+			(*ls)[i].ytd += m
+			return
+		}
+	}
+	*ls = append(*ls, ytdLine{description: desc, amount: m, ytd: m})
+}
+
+// ytd returns the total paid so far in the year.
+func (ls ytdLines) ytd() Money {
+	var total Money
+	for _, l := range ls {
+		total += l.ytd
+	}
+	return total
+}
+
+// current returns the total paid this period.
+func (ls ytdLines) current() Money {
+	var total Money
+	for _, l := range ls {
+		total += l.amount
+	}
+	return total
+}
+
+// next starts a new period, clearing this period's amounts.
+func (ls ytdLines) next() {
+	for i := range ls {
+		ls[i].amount = 0
+	}
+}
@@ -0,0 +1,373 @@
+package paystub
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// TaxForm is a kind of year-end tax form.
+type TaxForm string
+
+// Tax forms.
+const (
+	FormW2      TaxForm = "w2"       // Form W-2, Wage and Tax Statement
+	Form1099NEC TaxForm = "1099-nec" // Form 1099-NEC, Nonemployee Compensation
+)
+
+var validTaxForms = []string{string(FormW2), string(Form1099NEC)}
+
+// ParseTaxForm returns the TaxForm named s.
+func ParseTaxForm(s string) (TaxForm, error) {
+	for _, f := range validTaxForms {
+		if s == f {
+			return TaxForm(s), nil
+		}
+	}
+	return "", fmt.Errorf("invalid tax form %q (valid: %s)", s, strings.Join(validTaxForms, ", "))
+}
+
+// W2 is the data of a Form W-2: what an employer paid an employee in a
+// calendar year and withheld from it.
+type W2 struct {
+	Year        int      `json:"year"`
+	Employer    Employer `json:"employer"`
+	EmployerEIN string   `json:"employerEIN"`
+	Employee    Employee `json:"employee"`
+	// Wages, box 1, is the wage subject to income tax: gross pay less
+	// pre-tax deductions.
+	Wages               Money  `json:"wages"`
+	FederalTax          Money  `json:"federalTax"`          // box 2
+	SocialSecurityWages Money  `json:"socialSecurityWages"` // box 3, up to the wage base
+	SocialSecurityTax   Money  `json:"socialSecurityTax"`   // box 4
+	MedicareWages       Money  `json:"medicareWages"`       // box 5
+	MedicareTax         Money  `json:"medicareTax"`         // box 6
+	Retirement          Money  `json:"retirement"`          // box 12, code D: 401(k) deferrals
+	State               string `json:"state"`               // box 15; "" for none
+	StateWages          Money  `json:"stateWages"`          // box 16
+	StateTax            Money  `json:"stateTax"`            // box 17
+}
+
+// NEC1099 is the data of a Form 1099-NEC: what a business paid a contractor
+// in a calendar year.
+type NEC1099 struct {
+	Year         int      `json:"year"`
+	Payer        Employer `json:"payer"`
+	PayerTIN     string   `json:"payerTIN"`
+	Recipient    Employee `json:"recipient"`
+	Compensation Money    `json:"compensation"` // box 1
+	FederalTax   Money    `json:"federalTax"`   // box 4
+	StateTax     Money    `json:"stateTax"`     // box 5
+	State        string   `json:"state"`        // box 6; "" for none
+	StateIncome  Money    `json:"stateIncome"`  // box 7
+}
+
+// TaxYears returns the calendar years stubs are paid in, in order.
+func TaxYears(stubs []Paystub) []int {
+	var years []int
+	for _, s := range stubs {
+		if y := s.PayDate.Year(); !s.PayDate.IsZero() && (len(years) == 0 || years[len(years)-1] != y) {
+			years = append(years, y)
+		}
+	}
+	return years
+}
+
+// yearEnd returns the last stub of stubs paid in year, whose year-to-date
+// amounts are the totals of the year.
+func yearEnd(stubs []Paystub, year int) (Paystub, error) {
+	for i := len(stubs) - 1; i >= 0; i-- {
+		if !stubs[i].PayDate.IsZero() && stubs[i].PayDate.Year() == year {
+			return stubs[i], nil
+		}
+	}
+	return Paystub{}, fmt.Errorf("no paystub is paid in %d", year)
+}
+
+// yearTotals are the year-to-date deductions of a stub by kind.
+type yearTotals struct {
+	federal, socialSecurity, medicare, retirement Money
+	state                                         string
+	stateTax                                      Money
+}
+
+// totals sorts the year-to-date deductions of s by the descriptions Withhold
+// and Faker.Series give them.
+func totals(s Paystub) yearTotals {
+	var t yearTotals
+	for _, d := range s.Deductions {
+		switch {
+		case d.Description == "Federal Income Tax":
+			t.federal += d.YTD
+		case d.Description == "Social Security":
+			t.socialSecurity += d.YTD
+		case d.Description == "Medicare":
+			t.medicare += d.YTD
+		case d.Description == "401(k)":
+			t.retirement += d.YTD
+		case strings.HasSuffix(d.Description, " State Income Tax"):
+			t.state = strings.TrimSuffix(d.Description, " State Income Tax")
+			t.stateTax += d.YTD
+		}
+	}
+	return t
+}
+
+// W2For returns the W-2 of the employee of stubs for year, with the
+// year-to-date amounts of the last stub paid in it, so the form reconciles
+// with the series of stubs. Social Security wages stop at rates' wage base.
+func W2For(stubs []Paystub, year int, rates Rates) (W2, error) {
+	s, err := yearEnd(stubs, year)
+	if err != nil {
+		return W2{}, err
+	}
+	t := totals(s)
+	gross := s.GrossYTD()
+	w := W2{
+		Year:                year,
+		Employer:            s.Employer,
+		EmployerEIN:         ein(s.Employer.Name),
+		Employee:            s.Employee,
+		Wages:               gross - t.retirement,
+		FederalTax:          t.federal,
+		SocialSecurityWages: min(gross, rates.SocialSecurityWageBase),
+		SocialSecurityTax:   t.socialSecurity,
+		MedicareWages:       gross,
+		MedicareTax:         t.medicare,
+		Retirement:          t.retirement,
+		State:               t.state,
+		StateTax:            t.stateTax,
+	}
+	if w.State != "" {
+		w.StateWages = w.Wages
+	}
+	return w, nil
+}
+
+// NEC1099For returns the 1099-NEC of the payee of stubs for year, as if they
+// were paid as a contractor: the compensation is the year's gross pay and
+// the income tax withheld that of the last stub paid in it.
+func NEC1099For(stubs []Paystub, year int) (NEC1099, error) {
+	s, err := yearEnd(stubs, year)
+	if err != nil {
+		return NEC1099{}, err
+	}
+	t := totals(s)
+	n := NEC1099{
+		Year:         year,
+		Payer:        s.Employer,
+		PayerTIN:     ein(s.Employer.Name),
+		Recipient:    s.Employee,
+		Compensation: s.GrossYTD(),
+		FederalTax:   t.federal,
+		StateTax:     t.stateTax,
+		State:        t.state,
+	}
+	if n.State != "" {
+		n.StateIncome = n.Compensation
+	}
+	return n, nil
+}
+
+// ein returns a made-up employer identification number for the employer
+// called name, the same every time.
+func ein(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+	return fmt.Sprintf("%02d-%07d", 10+sum%89, sum/89%10000000)
+}
+
+// Values returns the values form f of the employee of stubs shows for year:
+// a W2 or a NEC1099.
+func (f TaxForm) Values(stubs []Paystub, year int, rates Rates) (any, error) {
+	switch f {
+	case FormW2:
+		return W2For(stubs, year, rates)
+	case Form1099NEC:
+		return NEC1099For(stubs, year)
+	}
+	return nil, fmt.Errorf("invalid tax form %q (valid: %s)", f, strings.Join(validTaxForms, ", "))
+}
+
+// Overlays returns the overlays that draw form f of the employee of stubs
+// for year on a blank letter page. Each value's Field names its box, such
+// as "w2.box1" or "1099nec.recipient.name", and the label of box i is
+// "labels[i]".
+func (f TaxForm) Overlays(stubs []Paystub, year int, rates Rates) ([]overlay.OverlayRectText, error) {
+	switch f {
+	case FormW2:
+		w, err := W2For(stubs, year, rates)
+		if err != nil {
+			return nil, err
+		}
+		return w.Overlays(), nil
+	case Form1099NEC:
+		n, err := NEC1099For(stubs, year)
+		if err != nil {
+			return nil, err
+		}
+		return n.Overlays(), nil
+	}
+	return nil, fmt.Errorf("invalid tax form %q (valid: %s)", f, strings.Join(validTaxForms, ", "))
+}
+
+// Generate draws form f of the employee of stubs for year and returns the
+// PDF.
+func (f TaxForm) Generate(stubs []Paystub, year int, rates Rates) ([]byte, error) {
+	overlays, err := f.Overlays(stubs, year, rates)
+	if err != nil {
+		return nil, err
+	}
+	return draw(overlays, formPageWidth, formPageHeight)
+}
+
+// Tax forms are drawn on a letter page as a grid of boxes formColumns wide,
+// each box a whole number of columns and rows of the grid.
+const (
+	formPageWidth  = 612
+	formPageHeight = 792
+	formMargin     = 36
+	formColumns    = 4
+	formRowHeight  = 30
+	formTop        = formPageHeight - formMargin - 40 // below the title
+
+	formFont       = "Helvetica"
+	formBoldFont   = "Helvetica-Bold"
+	formValueFont  = "Courier"
+	formLabelSize  = 6
+	formValueSize  = 9
+	formTitleSize  = 14
+	formPadding    = 3
+	formValueSpace = 11 // between the lines of a value
+)
+
+// formBox is one box of a tax form.
+type formBox struct {
+	label      string
+	lines      []textLine // the value, top to bottom; none for an empty box
+	x, y, w, h int        // in columns and rows of the grid, from its top-left
+	amount     bool       // right-align the value at the bottom of the box
+}
+
+// formOverlays returns the overlays that draw a form titled title, with
+// subtitle below it in small type, and the year on the right, above boxes.
+func formOverlays(title, subtitle string, year int, boxes []formBox) []overlay.OverlayRectText {
+	colWidth := float64(formPageWidth-2*formMargin) / formColumns
+	text := func(field, s, fontName string, size int, x, y float64, right bool) overlay.OverlayRectText {
+		if right {
+			x -= font.TextWidth(s, fontName, size)
+		}
+		return overlay.OverlayRectText{Text: s, Field: field, X: x, Y: y, Scale: 1, FontSize: size, Font: fontName}
+	}
+	top := float64(formPageHeight - formMargin)
+	right := float64(formPageWidth - formMargin)
+	overlays := []overlay.OverlayRectText{
+		text("title", title, formBoldFont, formTitleSize, formMargin, top-formTitleSize, false),
+		text("subtitle", subtitle, formFont, formLabelSize+2, formMargin, top-formTitleSize-14, false),
+		text("year", fmt.Sprint(year), formBoldFont, formTitleSize+6, right, top-formTitleSize-6, true),
+	}
+	for i, b := range boxes {
+		x := formMargin + float64(b.x)*colWidth
+		w := float64(b.w) * colWidth
+		boxTop := formTop - float64(b.y*formRowHeight)
+		bottom := boxTop - float64(b.h*formRowHeight)
+		overlays = append(overlays, overlay.OverlayRectText{
+			Type:        "rect",
+			X:           x,
+			Y:           bottom,
+			Width:       w,
+			Height:      boxTop - bottom,
+			Scale:       1,
+			BorderWidth: 0.5,
+		})
+		overlays = append(overlays, text(fmt.Sprintf("labels[%d]", i), b.label, formFont, formLabelSize, x+formPadding, boxTop-formPadding-formLabelSize, false))
+		for i, l := range b.lines {
+			if b.amount {
+				overlays = append(overlays, text(l.field, l.text, formValueFont, formValueSize, x+w-formPadding, bottom+formPadding+1, true))
+				continue
+			}
+			y := boxTop - formPadding - formLabelSize - float64(i+1)*formValueSpace
+			overlays = append(overlays, text(l.field, l.text, formValueFont, formValueSize, x+formPadding, y, false))
+		}
+	}
+	return overlays
+}
+
+// money returns the lines of a box holding the amount m, or none for zero.
+func money(field string, m Money) []textLine {
+	if m == 0 {
+		return nil
+	}
+	return value(field, m.String())
+}
+
+// value returns the lines of a box holding s, or none for "".
+func value(field, s string) []textLine {
+	if s == "" {
+		return nil
+	}
+	return []textLine{{field, s}}
+}
+
+// Overlays returns the overlays that draw w as a Form W-2 on a blank letter
+// page. Box values have fields such as "w2.box1" and "w2.employee.name".
+func (w W2) Overlays() []overlay.OverlayRectText {
+	var box12a, box13 []textLine
+	if w.Retirement != 0 {
+		box12a = value("w2.box12a", "D "+w.Retirement.String())
+		box13 = value("w2.box13", "X Retirement plan")
+	}
+	return formOverlays("Form W-2 Wage and Tax Statement", "Department of the Treasury - Internal Revenue Service", w.Year, []formBox{
+		{label: "a Employee's social security number", lines: value("w2.employee.ssn", w.Employee.SSN), x: 0, y: 0, w: 2, h: 1},
+		{label: "1 Wages, tips, other compensation", lines: money("w2.box1", w.Wages), x: 2, y: 0, w: 1, h: 1, amount: true},
+		{label: "2 Federal income tax withheld", lines: money("w2.box2", w.FederalTax), x: 3, y: 0, w: 1, h: 1, amount: true},
+		{label: "b Employer identification number (EIN)", lines: value("w2.employer.ein", w.EmployerEIN), x: 0, y: 1, w: 2, h: 1},
+		{label: "3 Social security wages", lines: money("w2.box3", w.SocialSecurityWages), x: 2, y: 1, w: 1, h: 1, amount: true},
+		{label: "4 Social security tax withheld", lines: money("w2.box4", w.SocialSecurityTax), x: 3, y: 1, w: 1, h: 1, amount: true},
+		{label: "c Employer's name, address, and ZIP code", lines: party("w2.employer", w.Employer.Name, w.Employer.Address), x: 0, y: 2, w: 2, h: 3},
+		{label: "5 Medicare wages and tips", lines: money("w2.box5", w.MedicareWages), x: 2, y: 2, w: 1, h: 1, amount: true},
+		{label: "6 Medicare tax withheld", lines: money("w2.box6", w.MedicareTax), x: 3, y: 2, w: 1, h: 1, amount: true},
+		{label: "7 Social security tips", x: 2, y: 3, w: 1, h: 1},
+		{label: "8 Allocated tips", x: 3, y: 3, w: 1, h: 1},
+		{label: "9", x: 2, y: 4, w: 1, h: 1},
+		{label: "10 Dependent care benefits", x: 3, y: 4, w: 1, h: 1},
+		{label: "d Control number", x: 0, y: 5, w: 2, h: 1},
+		{label: "11 Nonqualified plans", x: 2, y: 5, w: 1, h: 1},
+		{label: "12a See instructions for box 12", lines: box12a, x: 3, y: 5, w: 1, h: 1, amount: true},
+		{label: "e/f Employee's name, address, and ZIP code", lines: party("w2.employee", w.Employee.Name, w.Employee.Address), x: 0, y: 6, w: 2, h: 3},
+		{label: "13 Retirement plan", lines: box13, x: 2, y: 6, w: 1, h: 1},
+		{label: "12b", x: 3, y: 6, w: 1, h: 1},
+		{label: "14 Other", x: 2, y: 7, w: 1, h: 2},
+		{label: "12c", x: 3, y: 7, w: 1, h: 1},
+		{label: "12d", x: 3, y: 8, w: 1, h: 1},
+		{label: "15 State", lines: value("w2.box15", w.State), x: 0, y: 9, w: 1, h: 1},
+		{label: "16 State wages, tips, etc.", lines: money("w2.box16", w.StateWages), x: 1, y: 9, w: 1, h: 1, amount: true},
+		{label: "17 State income tax", lines: money("w2.box17", w.StateTax), x: 2, y: 9, w: 1, h: 1, amount: true},
+		{label: "18 Local wages, tips, etc.", x: 3, y: 9, w: 1, h: 1},
+	})
+}
+
+// Overlays returns the overlays that draw n as a Form 1099-NEC on a blank
+// letter page. Box values have fields such as "1099nec.box1" and
+// "1099nec.recipient.name".
+func (n NEC1099) Overlays() []overlay.OverlayRectText {
+	return formOverlays("Form 1099-NEC Nonemployee Compensation", "Department of the Treasury - Internal Revenue Service", n.Year, []formBox{
+		{label: "PAYER'S name, street address, city or town, state or province, country, ZIP", lines: party("1099nec.payer", n.Payer.Name, n.Payer.Address), x: 0, y: 0, w: 2, h: 3},
+		{label: "1 Nonemployee compensation", lines: money("1099nec.box1", n.Compensation), x: 2, y: 0, w: 2, h: 1, amount: true},
+		{label: "2 Payer made direct sales totaling $5,000 or more of consumer products", x: 2, y: 1, w: 2, h: 1},
+		{label: "3 Excess golden parachute payments", x: 2, y: 2, w: 2, h: 1},
+		{label: "PAYER'S TIN", lines: value("1099nec.payer.tin", n.PayerTIN), x: 0, y: 3, w: 1, h: 1},
+		{label: "RECIPIENT'S TIN", lines: value("1099nec.recipient.tin", n.Recipient.SSN), x: 1, y: 3, w: 1, h: 1},
+		{label: "4 Federal income tax withheld", lines: money("1099nec.box4", n.FederalTax), x: 2, y: 3, w: 2, h: 1, amount: true},
+		{label: "RECIPIENT'S name, street address, city or town, state or province, country, ZIP", lines: party("1099nec.recipient", n.Recipient.Name, n.Recipient.Address), x: 0, y: 4, w: 2, h: 3},
+		{label: "5 State tax withheld", lines: money("1099nec.box5", n.StateTax), x: 2, y: 4, w: 1, h: 1, amount: true},
+		{label: "6 State/Payer's state no.", lines: value("1099nec.box6", n.State), x: 3, y: 4, w: 1, h: 1},
+		{label: "7 State income", lines: money("1099nec.box7", n.StateIncome), x: 2, y: 5, w: 2, h: 1, amount: true},
+		{label: "Account number (see instructions)", x: 2, y: 6, w: 2, h: 1},
+	})
+}
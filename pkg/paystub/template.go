@@ -0,0 +1,65 @@
+package paystub
+
+import (
+	"strings"
+
+	"github.com/StCredZero/paystub-test-gen/pkg/overlay"
+)
+
+// TemplateData is what the text templates of overlays see for a paystub:
+// the values a stub usually prints, under flat names such as {{.NetPay}},
+// the whole Paystub as {{.Stub}} for anything else, and the fields added
+// with RegisterField as {{.Fields.name}}.
+type TemplateData struct {
+	EmployerName       string
+	EmployerAddress    string // address lines joined by ", "
+	EmployeeName       string
+	EmployeeAddress    string // address lines joined by ", "
+	EmployeeID         string
+	SSN                string
+	PayDate            Date
+	PeriodStart        Date
+	PeriodEnd          Date
+	GrossPay           Money
+	GrossPayYTD        Money
+	TotalDeductions    Money
+	TotalDeductionsYTD Money
+	NetPay             Money
+	NetPayYTD          Money
+	Stub               Paystub
+	Fields             map[string]string // custom fields, by name
+}
+
+// NewTemplateData returns the template data of s.
+func NewTemplateData(s Paystub) TemplateData {
+	return TemplateData{
+		EmployerName:       s.Employer.Name,
+		EmployerAddress:    strings.Join(s.Employer.Address, ", "),
+		EmployeeName:       s.Employee.Name,
+		EmployeeAddress:    strings.Join(s.Employee.Address, ", "),
+		EmployeeID:         s.Employee.ID,
+		SSN:                s.Employee.SSN,
+		PayDate:            s.PayDate,
+		PeriodStart:        s.PeriodStart,
+		PeriodEnd:          s.PeriodEnd,
+		GrossPay:           s.Gross(),
+		GrossPayYTD:        s.GrossYTD(),
+		TotalDeductions:    s.TotalDeductions(),
+		TotalDeductionsYTD: s.TotalDeductionsYTD(),
+		NetPay:             s.Net(),
+		NetPayYTD:          s.NetYTD(),
+		Stub:               s,
+		Fields:             customFields(s),
+	}
+}
+
+// FillTemplates returns a copy of overlays with the text templates in them
+// filled in from s, formatting amounts and dates as the locale named
+// locale does; "" is DefaultLocale.
+func FillTemplates(overlays []overlay.OverlayRectText, s Paystub, locale string) ([]overlay.OverlayRectText, error) {
+	l, err := LocaleNamed(locale)
+	if err != nil {
+		return nil, err
+	}
+	return overlay.ExpandText(overlays, NewTemplateData(s), l.TemplateFuncs())
+}
@@ -0,0 +1,154 @@
+package paystub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+)
+
+// Bracket is one bracket of a progressive tax: Rate applies to the part of
+// the annual wage above Over, up to the next bracket.
+type Bracket struct {
+	Over Money   `json:"over"`
+	Rate float64 `json:"rate"`
+}
+
+// Rates are the tax rates withholding is worked out with. In JSON, amounts
+// are dollars and rates fractions, such as 0.062 for 6.2%.
+type Rates struct {
+	SocialSecurity         float64 `json:"socialSecurity"`
+	SocialSecurityWageBase Money   `json:"socialSecurityWageBase"` // yearly wages taxed for Social Security
+	Medicare               float64 `json:"medicare"`
+	AdditionalMedicare     float64 `json:"additionalMedicare"`     // on wages past AdditionalMedicareOver
+	AdditionalMedicareOver Money   `json:"additionalMedicareOver"` // in a year
+	// Federal holds the brackets of federal income tax on the annualized
+	// wage, by increasing Over.
+	Federal []Bracket `json:"federal"`
+	// State holds a flat income tax rate per state code; states not listed
+	// have no income tax.
+	State map[string]float64 `json:"state"`
+}
+
+// DefaultRates are 2025's US payroll rates: FICA, the federal percentage
+// method for a single filer with standard withholding, and rough flat
+// state rates.
+var DefaultRates = Rates{
+	SocialSecurity:         0.062,
+	SocialSecurityWageBase: 176100_00,
+	Medicare:               0.0145,
+	AdditionalMedicare:     0.009,
+	AdditionalMedicareOver: 200000_00,
+	Federal: []Bracket{
+		{Over: 0, Rate: 0},
+		{Over: 6400_00, Rate: 0.10},
+		{Over: 18325_00, Rate: 0.12},
+		{Over: 54875_00, Rate: 0.22},
+		{Over: 109750_00, Rate: 0.24},
+		{Over: 203700_00, Rate: 0.32},
+		{Over: 256925_00, Rate: 0.35},
+		{Over: 632750_00, Rate: 0.37},
+	},
+	State: map[string]float64{
+		"IL": 0.0495, "OH": 0.035, "CO": 0.044, "OR": 0.0875, "NC": 0.045,
+		"AZ": 0.025, "WI": 0.053, "VA": 0.0575, "CA": 0.06, "GA": 0.0549,
+		"ID": 0.058, "NY": 0.055, "NE": 0.0584, "NJ": 0.045, "PA": 0.0307, "MI": 0.0425,
+	},
+}
+
+// DecodeRates reads Rates from their JSON in r. Fields the JSON leaves out
+// keep their DefaultRates values; states it lists are added to the default
+// state rates or replace them.
+func DecodeRates(r io.Reader) (Rates, error) {
+	rates := DefaultRates
+	// Decoding fills the slice and map in place, so give it copies.
+	rates.Federal = slices.Clone(DefaultRates.Federal)
+	rates.State = maps.Clone(DefaultRates.State)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rates); err != nil {
+		return rates, err
+	}
+	return rates, rates.validate()
+}
+
+// validate checks that every rate of r is a fraction and its brackets are
+// in order.
+func (r Rates) validate() error {
+	check := func(name string, rate float64) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s rate must be from 0 to 1, got %g", name, rate)
+		}
+		return nil
+	}
+	for name, rate := range map[string]float64{
+		"socialSecurity": r.SocialSecurity, "medicare": r.Medicare, "additionalMedicare": r.AdditionalMedicare,
+	} {
+		if err := check(name, rate); err != nil {
+			return err
+		}
+	}
+	for i, b := range r.Federal {
+		if err := check("federal", b.Rate); err != nil {
+			return err
+		}
+		if i > 0 && b.Over <= r.Federal[i-1].Over {
+			return fmt.Errorf("federal brackets must be in increasing order of over")
+		}
+	}
+	for state, rate := range r.State {
+		if err := check(state+" state", rate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wages are what the taxes of one pay period depend on.
+type Wages struct {
+	Gross Money // paid this period
+	// PreTax is taken out of Gross before income tax, as for a 401(k), but
+	// still pays Social Security and Medicare.
+	PreTax         Money
+	PriorYTD       Money // gross paid earlier in the year
+	PeriodsPerYear int
+	State          string // two-letter code; "" for none
+}
+
+// Withhold returns the taxes withheld from w: federal income tax, Social
+// Security, Medicare and, if w's state has one, state income tax. Income
+// tax annualizes the period's taxable wage; Social Security stops at the
+// wage base and Medicare adds its additional rate past its threshold, both
+// counting PriorYTD.
+func (r Rates) Withhold(w Wages) []Deduction {
+	periods := float64(max(w.PeriodsPerYear, 1))
+	taxable := w.Gross - w.PreTax
+	percent := func(m Money, rate float64) Money { return Money(math.Round(float64(m) * rate)) }
+
+	annual := Money(float64(taxable) * periods)
+	var federal float64
+	for i, b := range r.Federal {
+		if annual <= b.Over {
+			break
+		}
+		top := annual
+		if i+1 < len(r.Federal) {
+			top = min(top, r.Federal[i+1].Over)
+		}
+		federal += float64(top-b.Over) * b.Rate
+	}
+
+	ssWages := max(min(w.Gross, r.SocialSecurityWageBase-w.PriorYTD), 0)
+	extraWages := min(max(w.PriorYTD+w.Gross-r.AdditionalMedicareOver, 0), w.Gross)
+	taxes := []Deduction{
+		{Description: "Federal Income Tax", Amount: Money(math.Round(federal / periods))},
+		{Description: "Social Security", Amount: percent(ssWages, r.SocialSecurity)},
+		{Description: "Medicare", Amount: percent(w.Gross, r.Medicare) + percent(extraWages, r.AdditionalMedicare)},
+	}
+	if rate, ok := r.State[w.State]; ok && rate > 0 {
+		taxes = append(taxes, Deduction{Description: w.State + " State Income Tax", Amount: percent(taxable, rate)})
+	}
+	return taxes
+}